@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"wellness-step-by-step/step-08/models"
+)
+
+type AppointmentEvent struct {
+	Event string             `json:"event"`
+	Data  models.Appointment `json:"data"`
+}
+
+func (c *ClientConsumer) processAppointmentEvent(ctx context.Context, value []byte) {
+	var event AppointmentEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		log.Printf("Failed to unmarshal appointment event: %v", err)
+		return
+	}
+
+	switch event.Event {
+	case "appointment_booked":
+		c.handleAppointmentBooked(ctx, event.Data)
+	case "appointment_cancelled":
+		c.handleAppointmentCancelled(ctx, event.Data)
+	default:
+		log.Printf("Unknown appointment event type: %s", event.Event)
+	}
+}
+
+func (c *ClientConsumer) handleAppointmentBooked(ctx context.Context, appointment models.Appointment) {
+	// Кешируем запись до момента ее окончания
+	ttl := time.Until(appointment.EndsAt)
+	if ttl <= 0 {
+		log.Printf("Skipping cache for past appointment ID %d", appointment.ID)
+		return
+	}
+	c.cacheAppointment(ctx, appointment, ttl)
+
+	log.Printf("Processed appointment_booked event for appointment ID %d (client %d, specialist %d)",
+		appointment.ID, appointment.ClientID, appointment.SpecialistID)
+}
+
+func (c *ClientConsumer) handleAppointmentCancelled(ctx context.Context, appointment models.Appointment) {
+	// Отмененная запись остается в кеше ненадолго, чтобы читатели увидели новый статус
+	c.cacheAppointment(ctx, appointment, time.Hour)
+
+	log.Printf("Processed appointment_cancelled event for appointment ID %d", appointment.ID)
+}
+
+func (c *ClientConsumer) cacheAppointment(ctx context.Context, appointment models.Appointment, ttl time.Duration) {
+	cacheKey := fmt.Sprintf("appointment:%d", appointment.ID)
+	appointmentJSON, err := json.Marshal(appointment)
+	if err != nil {
+		log.Printf("Failed to marshal appointment to JSON: %v", err)
+		return
+	}
+
+	if err := c.cache.SetToCache(ctx, cacheKey, string(appointmentJSON), ttl); err != nil {
+		log.Printf("Failed to cache appointment: %v", err)
+	}
+}
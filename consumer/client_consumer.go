@@ -13,9 +13,69 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// Топики Kafka, которые читает консьюмер
+const (
+	ClientEventsTopic      = "client_events"
+	AppointmentEventsTopic = "appointment_events"
+)
+
+// ClientEvent - событие по клиенту. Для client_merged Data - оставшийся клиент,
+// MergedClientID - удаленный дубликат, чьи данные на него перенесены.
 type ClientEvent struct {
-	Event string        `json:"event"`
-	Data  models.Client `json:"data"`
+	Event          string        `json:"event"`
+	Data           models.Client `json:"data"`
+	MergedClientID uint          `json:"merged_client_id,omitempty"`
+}
+
+// ClientDocument - представление клиента в индексе Elasticsearch
+type ClientDocument struct {
+	ID                 uint                `json:"id"`
+	FullName           string              `json:"full_name"`
+	Email              string              `json:"email"`
+	Phone              string              `json:"phone"`
+	AdvertisingChannel string              `json:"advertising_channel"`
+	SpecialistID       *uint               `json:"specialist_id"`
+	BranchID           *uint               `json:"branch_id"`
+	MeetingPlace       string              `json:"meeting_place"`
+	Occupation         string              `json:"occupation"`
+	Gender             string              `json:"gender"`
+	Age                int                 `json:"age"`
+	ReasonForVisit     string              `json:"reason_for_visit"`
+	SpecialistNotes    string              `json:"specialist_notes"`
+	Tags               []ClientTagDocument `json:"tags"`
+	CreatedAt          time.Time           `json:"created_at"`
+}
+
+// ClientTagDocument - метка клиента в индексе; сегменты фильтруют по полю tags.id
+type ClientTagDocument struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func NewClientDocument(client models.Client) ClientDocument {
+	tags := make([]ClientTagDocument, 0, len(client.Tags))
+	for _, tag := range client.Tags {
+		tags = append(tags, ClientTagDocument{ID: tag.ID, Name: tag.Name, Color: tag.Color})
+	}
+
+	return ClientDocument{
+		ID:                 client.ID,
+		FullName:           client.FullName,
+		Email:              client.Email,
+		Phone:              client.Phone,
+		AdvertisingChannel: client.AdvertisingChannel,
+		SpecialistID:       client.SpecialistID,
+		BranchID:           client.BranchID,
+		MeetingPlace:       client.MeetingPlace,
+		Occupation:         client.Occupation,
+		Gender:             client.Gender,
+		Age:                client.Age,
+		ReasonForVisit:     client.ReasonForVisit,
+		SpecialistNotes:    client.SpecialistNotes,
+		Tags:               tags,
+		CreatedAt:          client.CreatedAt,
+	}
 }
 
 // Добавляем поле es в структуру ClientConsumer
@@ -34,10 +94,10 @@ func NewClientConsumer(repo models.Repository, cache utils.RedisClient, es utils
 		cache: cache,
 		es:    es,
 		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers: []string{os.Getenv("KAFKA_BROKER")},
-			Topic:   "client_events",
-			GroupID: "wellness-group",
-			MaxWait: 10 * time.Second,
+			Brokers:     []string{os.Getenv("KAFKA_BROKER")},
+			GroupTopics: []string{ClientEventsTopic, AppointmentEventsTopic},
+			GroupID:     "wellness-group",
+			MaxWait:     10 * time.Second,
 		}),
 		shutdown: make(chan struct{}),
 	}
@@ -74,7 +134,7 @@ func (c *ClientConsumer) processMessages(ctx context.Context) {
 
 		utils.CaptureError(err, map[string]interface{}{
 			"action":   "kafka_read",
-			"topic":    c.reader.Config().Topic,
+			"topics":   c.reader.Config().GroupTopics,
 			"group_id": c.reader.Config().GroupID,
 		})
 
@@ -83,8 +143,19 @@ func (c *ClientConsumer) processMessages(ctx context.Context) {
 		return
 	}
 
+	switch msg.Topic {
+	case AppointmentEventsTopic:
+		c.processAppointmentEvent(ctx, msg.Value)
+	default:
+		c.processClientEvent(ctx, msg.Value)
+	}
+
+	// В реальном проекте здесь нужно коммитить offset только после успешной обработки
+}
+
+func (c *ClientConsumer) processClientEvent(ctx context.Context, value []byte) {
 	var event ClientEvent
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
+	if err := json.Unmarshal(value, &event); err != nil {
 		log.Printf("Failed to unmarshal Kafka message: %v", err)
 		return
 	}
@@ -96,22 +167,22 @@ func (c *ClientConsumer) processMessages(ctx context.Context) {
 		c.handleClientUpdated(ctx, event.Data)
 	case "client_deleted":
 		c.handleClientDeleted(ctx, event.Data.ID)
+	case "client_merged":
+		c.handleClientUpdated(ctx, event.Data)
+		c.handleClientDeleted(ctx, event.MergedClientID)
 	default:
 		log.Printf("Unknown event type: %s", event.Event)
 	}
-
-	// В реальном проекте здесь нужно коммитить offset только после успешной обработки
 }
 
 // Обновляем обработчики событий
 func (c *ClientConsumer) handleClientCreated(ctx context.Context, client models.Client) {
-	// 1. Сохраняем в PostgreSQL
+	// 1. Сохраняем в PostgreSQL. Клиентов, созданных через API, событие застает уже в базе:
+	// вставку пропускаем, но кэш и индекс обновляем
 	existing, err := c.repo.GetClientByID(client.ID)
 	if err == nil && existing != nil {
-		return // Клиент уже существует
-	}
-
-	if err := c.repo.CreateClient(&client); err != nil {
+		client = *existing
+	} else if err := c.repo.CreateClient(&client); err != nil {
 		log.Printf("Failed to create client from Kafka: %v", err)
 		return
 	}
@@ -130,7 +201,7 @@ func (c *ClientConsumer) handleClientCreated(ctx context.Context, client models.
 
 	// 3. Индексируем в Elasticsearch
 	if c.es != nil {
-		if err := c.es.IndexClient(ctx, "clients", fmt.Sprintf("%d", client.ID), client); err != nil {
+		if err := c.es.IndexClient(ctx, "clients", fmt.Sprintf("%d", client.ID), NewClientDocument(client)); err != nil {
 			log.Printf("Failed to index client in Elasticsearch: %v", err)
 		}
 	}
@@ -159,7 +230,7 @@ func (c *ClientConsumer) handleClientUpdated(ctx context.Context, client models.
 
 	// 3. Обновляем в Elasticsearch
 	if c.es != nil {
-		if err := c.es.IndexClient(ctx, "clients", fmt.Sprintf("%d", client.ID), client); err != nil {
+		if err := c.es.IndexClient(ctx, "clients", fmt.Sprintf("%d", client.ID), NewClientDocument(client)); err != nil {
 			log.Printf("Failed to update client in Elasticsearch: %v", err)
 		}
 	}
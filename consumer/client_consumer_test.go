@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+)
+
+// fakeClients - клиенты в памяти вместо PostgreSQL
+type fakeClients struct {
+	models.Repository
+	clients map[uint]models.Client
+	created int
+}
+
+func (f *fakeClients) GetClientByID(id uint) (*models.Client, error) {
+	client, ok := f.clients[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &client, nil
+}
+
+func (f *fakeClients) CreateClient(client *models.Client) error {
+	f.clients[client.ID] = *client
+	f.created++
+	return nil
+}
+
+type fakeCache struct {
+	utils.RedisClient
+	values map[string]string
+}
+
+func (f *fakeCache) SetToCache(ctx context.Context, key string, value string, expiration time.Duration) error {
+	f.values[key] = value
+	return nil
+}
+
+type fakeIndex struct {
+	utils.ElasticsearchClient
+	documents map[string]interface{}
+}
+
+func (f *fakeIndex) IndexClient(ctx context.Context, index string, id string, document interface{}) error {
+	f.documents[id] = document
+	return nil
+}
+
+func TestHandleClientCreatedIndexesExistingClient(t *testing.T) {
+	client := models.Client{FullName: "Анна Петрова", ReasonForVisit: "Боль в спине"}
+	client.ID = 5
+	repo := &fakeClients{clients: map[uint]models.Client{client.ID: client}}
+	cache := &fakeCache{values: map[string]string{}}
+	index := &fakeIndex{documents: map[string]interface{}{}}
+	c := &ClientConsumer{repo: repo, cache: cache, es: index}
+
+	c.handleClientCreated(context.Background(), client)
+
+	if repo.created != 0 {
+		t.Errorf("client already in PostgreSQL was created again")
+	}
+	if _, ok := cache.values["client:5"]; !ok {
+		t.Error("client was not cached")
+	}
+	doc, ok := index.documents["5"].(ClientDocument)
+	if !ok || doc.ReasonForVisit != client.ReasonForVisit {
+		t.Errorf("indexed document = %+v, want questionnaire of client 5", index.documents["5"])
+	}
+}
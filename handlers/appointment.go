@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AppointmentHandler struct {
+	repo        models.AppointmentRepository
+	clients     models.Repository
+	specialists models.SpecialistRepository
+	packages    models.PackageRepository
+	waitlist    models.WaitlistRepository
+	resources   models.ResourceRepository
+	consents    models.ConsentRepository
+	feedback    models.FeedbackRepository
+	kafka       utils.KafkaProducer
+}
+
+func NewAppointmentHandler(repo models.AppointmentRepository, clients models.Repository, specialists models.SpecialistRepository, packages models.PackageRepository, waitlist models.WaitlistRepository, resources models.ResourceRepository, consents models.ConsentRepository, feedback models.FeedbackRepository, kafka utils.KafkaProducer) *AppointmentHandler {
+	return &AppointmentHandler{
+		repo:        repo,
+		clients:     clients,
+		specialists: specialists,
+		packages:    packages,
+		waitlist:    waitlist,
+		resources:   resources,
+		consents:    consents,
+		feedback:    feedback,
+		kafka:       kafka,
+	}
+}
+
+// AppointmentRequest - запись клиента к специалисту. Если помещение не указано,
+// используется место встречи из анкеты клиента. ResourceIDs - бронируемые помещения и оборудование.
+type AppointmentRequest struct {
+	ClientID     uint      `json:"client_id" binding:"required"`
+	SpecialistID uint      `json:"specialist_id" binding:"required"`
+	Room         string    `json:"room" binding:"max=100"`
+	ResourceIDs  []uint    `json:"resource_ids" binding:"max=10"`
+	StartsAt     time.Time `json:"starts_at" binding:"required"`
+	EndsAt       time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Comment      string    `json:"comment" binding:"max=1000"`
+}
+
+// RescheduleAppointmentRequest - перенос записи на другое время, к другому специалисту или в другое помещение.
+// Если resource_ids не передан, забронированные ресурсы остаются прежними.
+type RescheduleAppointmentRequest struct {
+	SpecialistID uint      `json:"specialist_id" binding:"required"`
+	Room         string    `json:"room" binding:"required,max=100"`
+	ResourceIDs  []uint    `json:"resource_ids" binding:"omitempty,max=10"`
+	StartsAt     time.Time `json:"starts_at" binding:"required"`
+	EndsAt       time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Comment      string    `json:"comment" binding:"max=1000"`
+}
+
+// AppointmentStatusRequest - смена статуса записи. При завершении визита можно указать абонемент
+// для списания; без него выбирается подходящий абонемент клиента автоматически.
+type AppointmentStatusRequest struct {
+	Status    string `json:"status" binding:"required,oneof=booked confirmed completed cancelled no_show"`
+	PackageID *uint  `json:"package_id"`
+}
+
+type AppointmentResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     uint      `json:"client_id"`
+	SpecialistID uint      `json:"specialist_id"`
+	BranchID     uint      `json:"branch_id"`
+	Room         string    `json:"room"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Status       string    `json:"status"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Resources - забронированные помещения и оборудование
+	Resources []AppointmentResourceResponse `json:"resources"`
+	// PackageDebit заполняется при завершении визита, если сессия списана с абонемента
+	PackageDebit *AppointmentDebitResponse `json:"package_debit,omitempty"`
+}
+
+type AppointmentResourceResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+type AppointmentDebitResponse struct {
+	PackageID         uint `json:"package_id"`
+	Sessions          int  `json:"sessions"`
+	RemainingSessions *int `json:"remaining_sessions"`
+}
+
+func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
+	var req AppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.clients.GetClientByID(req.ClientID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	specialist, ok := h.activeSpecialist(c, req.SpecialistID)
+	if !ok {
+		return
+	}
+
+	if !ensureConsents(c, h.consents, client.ID, specialist.Specialization, req.StartsAt) {
+		return
+	}
+
+	resources, ok := h.loadResources(c, req.ResourceIDs)
+	if !ok || !resourcesInBranch(c, resources, specialist.BranchID) {
+		return
+	}
+
+	appointment := &models.Appointment{
+		ClientID:     client.ID,
+		SpecialistID: req.SpecialistID,
+		BranchID:     specialist.BranchID,
+		Room:         req.Room,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		Status:       models.AppointmentStatusBooked,
+		Comment:      req.Comment,
+		Resources:    resources,
+	}
+	if appointment.Room == "" {
+		appointment.Room = client.MeetingPlace
+	}
+
+	if err := h.repo.CreateAppointment(appointment); err != nil {
+		respondAppointmentWriteError(c, err)
+		return
+	}
+
+	if err := h.waitlist.FulfillWaitlistEntries(appointment.ClientID, appointment.SpecialistID); err != nil {
+		log.Printf("Failed to fulfill waitlist for client %d: %v", appointment.ClientID, err)
+	}
+
+	if h.kafka != nil {
+		go publishAppointmentEvent(h.kafka, "appointment_booked", appointment)
+	}
+
+	c.JSON(http.StatusCreated, toAppointmentResponse(appointment))
+}
+
+func (h *AppointmentHandler) GetAppointment(c *gin.Context) {
+	appointment, ok := h.loadAppointment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentResponse(appointment))
+}
+
+type ListAppointmentsQuery struct {
+	PageQuery
+	ClientID     uint      `form:"client_id"`
+	SpecialistID uint      `form:"specialist_id"`
+	BranchID     uint      `form:"branch_id"`
+	Room         string    `form:"room"`
+	ResourceID   uint      `form:"resource_id"`
+	Status       string    `form:"status" binding:"omitempty,oneof=booked confirmed completed cancelled no_show"`
+	From         time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To           time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (h *AppointmentHandler) ListAppointments(c *gin.Context) {
+	var query ListAppointmentsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	appointments, total, err := h.repo.ListAppointments(models.AppointmentFilter{
+		ClientID:     query.ClientID,
+		SpecialistID: query.SpecialistID,
+		BranchID:     query.BranchID,
+		Room:         query.Room,
+		ResourceID:   query.ResourceID,
+		Status:       query.Status,
+		From:         query.From,
+		To:           query.To,
+		Limit:        page.PageSize,
+		Offset:       page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]AppointmentResponse, 0, len(appointments))
+	for i := range appointments {
+		items = append(items, toAppointmentResponse(&appointments[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *AppointmentHandler) RescheduleAppointment(c *gin.Context) {
+	var req RescheduleAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointment, ok := h.loadAppointment(c)
+	if !ok {
+		return
+	}
+
+	if !appointment.IsActive() {
+		c.JSON(http.StatusConflict, gin.H{"error": "only booked or confirmed appointments can be rescheduled"})
+		return
+	}
+
+	// Смена специалиста может означать другую услугу, для которой нужны свои согласия, и другой филиал
+	branchID := appointment.BranchID
+	var specialization string
+	if req.SpecialistID != appointment.SpecialistID {
+		specialist, ok := h.activeSpecialist(c, req.SpecialistID)
+		if !ok {
+			return
+		}
+		specialization = specialist.Specialization
+		branchID = specialist.BranchID
+	} else {
+		specialist, err := h.specialists.GetSpecialistByID(appointment.SpecialistID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		specialization = specialist.Specialization
+	}
+	// Согласия проверяются на новую дату визита и при том же специалисте: к ней срок их действия мог истечь
+	if !ensureConsents(c, h.consents, appointment.ClientID, specialization, req.StartsAt) {
+		return
+	}
+
+	if req.ResourceIDs != nil {
+		resources, ok := h.loadResources(c, req.ResourceIDs)
+		if !ok {
+			return
+		}
+		appointment.Resources = resources
+	}
+	if !resourcesInBranch(c, appointment.Resources, branchID) {
+		return
+	}
+	appointment.BranchID = branchID
+
+	appointment.SpecialistID = req.SpecialistID
+	appointment.Room = req.Room
+	appointment.StartsAt = req.StartsAt
+	appointment.EndsAt = req.EndsAt
+	appointment.Comment = req.Comment
+	// Перенесенную запись нужно подтвердить заново
+	appointment.Status = models.AppointmentStatusBooked
+
+	if err := h.repo.UpdateAppointment(appointment); err != nil {
+		respondAppointmentWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentResponse(appointment))
+}
+
+func (h *AppointmentHandler) UpdateAppointmentStatus(c *gin.Context) {
+	var req AppointmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointment, ok := h.loadAppointment(c)
+	if !ok {
+		return
+	}
+
+	if !models.CanTransitionAppointment(appointment.Status, req.Status) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "cannot change appointment status from " + appointment.Status + " to " + req.Status,
+		})
+		return
+	}
+
+	if req.Status == models.AppointmentStatusCompleted {
+		h.completeAppointment(c, appointment, req.PackageID)
+		return
+	}
+
+	if err := h.repo.ChangeAppointmentStatus(appointment, req.Status); err != nil {
+		respondAppointmentWriteError(c, err)
+		return
+	}
+
+	if h.kafka != nil && appointment.Status == models.AppointmentStatusCancelled {
+		go publishAppointmentEvent(h.kafka, "appointment_cancelled", appointment)
+		go h.notifyWaitlist(*appointment)
+	}
+
+	c.JSON(http.StatusOK, toAppointmentResponse(appointment))
+}
+
+// completeAppointment завершает визит, списывает сессию с абонемента клиента и выдает опрос о визите
+func (h *AppointmentHandler) completeAppointment(c *gin.Context, appointment *models.Appointment, packageID *uint) {
+	pkg, debit, err := h.packages.CompleteAppointment(appointment, packageID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoPackage) {
+			c.JSON(http.StatusConflict, gin.H{"error": "selected package cannot cover this appointment"})
+			return
+		}
+		respondAppointmentWriteError(c, err)
+		return
+	}
+
+	if h.kafka != nil && debit != nil {
+		go publishPackageDebitEvents(h.kafka, *pkg, *debit)
+	}
+	if _, _, err := issueSurvey(h.feedback, h.kafka, appointment); err != nil {
+		log.Printf("Failed to issue survey for appointment %d: %v", appointment.ID, err)
+	}
+
+	resp := toAppointmentResponse(appointment)
+	if debit != nil {
+		resp.PackageDebit = &AppointmentDebitResponse{
+			PackageID:         pkg.ID,
+			Sessions:          debit.Sessions,
+			RemainingSessions: pkg.RemainingSessions,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// notifyWaitlist ищет в листе ожидания клиентов, которым подходит время отмененной записи,
+// и отправляет по каждому событие waitlist_slot_available
+func (h *AppointmentHandler) notifyWaitlist(appointment models.Appointment) {
+	entries, err := h.waitlist.FindWaitlistMatches(&appointment)
+	if err != nil {
+		log.Printf("Failed to match waitlist for appointment %d: %v", appointment.ID, err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for i := range entries {
+		publishWaitlistEvent(h.kafka, "waitlist_slot_available", &entries[i], &appointment)
+		ids = append(ids, entries[i].ID)
+	}
+
+	if err := h.waitlist.MarkWaitlistNotified(ids, time.Now()); err != nil {
+		log.Printf("Failed to mark waitlist entries notified: %v", err)
+	}
+}
+
+// loadAppointment загружает запись по id из пути; при ошибке ответ уже отправлен
+func (h *AppointmentHandler) loadAppointment(c *gin.Context) (*models.Appointment, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment ID format"})
+		return nil, false
+	}
+
+	appointment, err := h.repo.GetAppointmentByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "appointment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return appointment, true
+}
+
+// activeSpecialist загружает специалиста и проверяет, что он принимает клиентов
+func (h *AppointmentHandler) activeSpecialist(c *gin.Context, id uint) (*models.Specialist, bool) {
+	specialist, err := h.specialists.GetSpecialistByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if specialist.Status != models.SpecialistStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "specialist is not active"})
+		return nil, false
+	}
+	return specialist, true
+}
+
+// loadResources загружает бронируемые ресурсы и проверяет, что все они существуют и доступны
+func (h *AppointmentHandler) loadResources(c *gin.Context, ids []uint) ([]models.Resource, bool) {
+	unique := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	resources, err := h.resources.GetResourcesByIDs(unique)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(resources) != len(unique) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource not found"})
+		return nil, false
+	}
+	for _, resource := range resources {
+		if resource.Status != models.ResourceStatusActive {
+			c.JSON(http.StatusConflict, gin.H{"error": "resource " + resource.Name + " is inactive"})
+			return nil, false
+		}
+	}
+	return resources, true
+}
+
+// resourcesInBranch проверяет, что ресурсы находятся в филиале записи; при ошибке ответ уже отправлен
+func resourcesInBranch(c *gin.Context, resources []models.Resource, branchID uint) bool {
+	for _, resource := range resources {
+		if resource.BranchID != branchID {
+			c.JSON(http.StatusConflict, gin.H{"error": "resource " + resource.Name + " belongs to another branch"})
+			return false
+		}
+	}
+	return true
+}
+
+func respondAppointmentWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrOverlap), errors.Is(err, models.ErrResourceBusy), errors.Is(err, models.ErrAppointmentStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client or specialist not found"})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "appointment not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func toAppointmentResponse(appointment *models.Appointment) AppointmentResponse {
+	resources := make([]AppointmentResourceResponse, 0, len(appointment.Resources))
+	for _, resource := range appointment.Resources {
+		resources = append(resources, AppointmentResourceResponse{
+			ID:   resource.ID,
+			Name: resource.Name,
+			Kind: resource.Kind,
+		})
+	}
+	return AppointmentResponse{
+		ID:           appointment.ID,
+		ClientID:     appointment.ClientID,
+		SpecialistID: appointment.SpecialistID,
+		BranchID:     appointment.BranchID,
+		Room:         appointment.Room,
+		StartsAt:     appointment.StartsAt,
+		EndsAt:       appointment.EndsAt,
+		Status:       appointment.Status,
+		Comment:      appointment.Comment,
+		CreatedAt:    appointment.CreatedAt,
+		Resources:    resources,
+	}
+}
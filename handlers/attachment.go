@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+	"unicode/utf8"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAttachmentSize - ограничение размера загружаемого файла
+const maxAttachmentSize = 10 << 20
+
+// allowedAttachmentTypes - допустимые типы файлов; тип определяется по содержимому, а не по расширению
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+type AttachmentHandler struct {
+	repo    models.AttachmentRepository
+	clients models.Repository
+	storage utils.FileStorage
+}
+
+func NewAttachmentHandler(repo models.AttachmentRepository, clients models.Repository, storage utils.FileStorage) *AttachmentHandler {
+	return &AttachmentHandler{
+		repo:    repo,
+		clients: clients,
+		storage: storage,
+	}
+}
+
+// UploadAttachmentRequest - multipart-форма загрузки файла
+type UploadAttachmentRequest struct {
+	File         *multipart.FileHeader `form:"file" binding:"required"`
+	Description  string                `form:"description" binding:"max=1000"`
+	SpecialistID *uint                 `form:"specialist_id"`
+}
+
+type AttachmentResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     uint      `json:"client_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"sha256"`
+	Description  string    `json:"description"`
+	SpecialistID *uint     `json:"specialist_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UploadAttachment принимает файл, проверяет размер и тип по содержимому и сохраняет его в хранилище
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	if !h.storageAvailable(c) {
+		return
+	}
+
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	// Запас сверх лимита файла - на остальные поля формы и границы multipart
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+
+	var req UploadAttachmentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must not exceed %d MB", maxAttachmentSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.File.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must not exceed %d MB", maxAttachmentSize>>20)})
+		return
+	}
+	if req.File.Size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is empty"})
+		return
+	}
+	if req.File.Filename == "" || len(req.File.Filename) > 255 || !utf8.ValidString(req.File.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file name"})
+		return
+	}
+
+	file, err := req.File.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !allowedAttachmentTypes[contentType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "only PDF, JPEG, PNG and WebP files are allowed"})
+		return
+	}
+
+	key, err := newAttachmentKey(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), file), hash)
+	if err := h.storage.Put(c.Request.Context(), key, body, req.File.Size, contentType); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachment := &models.Attachment{
+		ClientID:     clientID,
+		FileName:     req.File.Filename,
+		ContentType:  contentType,
+		Size:         req.File.Size,
+		StorageKey:   key,
+		Checksum:     hex.EncodeToString(hash.Sum(nil)),
+		Description:  req.Description,
+		SpecialistID: req.SpecialistID,
+	}
+	if err := h.repo.CreateAttachment(attachment); err != nil {
+		h.deleteContent(key)
+		if errors.Is(err, models.ErrInvalidReference) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAttachmentResponse(attachment))
+}
+
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.repo.ListAttachments(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]AttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		items = append(items, toAttachmentResponse(&attachments[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	attachment, ok := h.loadAttachment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// DownloadAttachment отдает содержимое файла потоком, не загружая его целиком в память
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	if !h.storageAvailable(c) {
+		return
+	}
+
+	attachment, ok := h.loadAttachment(c)
+	if !ok {
+		return
+	}
+
+	content, err := h.storage.Get(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, utils.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment content not found"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	if !h.storageAvailable(c) {
+		return
+	}
+
+	attachment, ok := h.loadAttachment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteAttachment(attachment.ClientID, attachment.ID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.deleteContent(attachment.StorageKey)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AttachmentHandler) storageAvailable(c *gin.Context) bool {
+	if h.storage == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "file storage is not available"})
+		return false
+	}
+	return true
+}
+
+// deleteContent удаляет содержимое файла; ошибка только логируется, так как метаданные уже не ссылаются на объект
+func (h *AttachmentHandler) deleteContent(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.storage.Delete(ctx, key); err != nil {
+		log.Printf("Failed to delete attachment content %s: %v", key, err)
+	}
+}
+
+func (h *AttachmentHandler) loadAttachment(c *gin.Context) (*models.Attachment, bool) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return nil, false
+	}
+
+	attachmentID, err := parseUint(c.Param("attachment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID format"})
+		return nil, false
+	}
+
+	attachment, err := h.repo.GetAttachment(clientID, attachmentID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return attachment, true
+}
+
+// newAttachmentKey генерирует случайный ключ объекта; имя файла в ключ не попадает
+func newAttachmentKey(clientID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate storage key: %w", err)
+	}
+	return fmt.Sprintf("clients/%d/%s", clientID, hex.EncodeToString(buf)), nil
+}
+
+func toAttachmentResponse(attachment *models.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:           attachment.ID,
+		ClientID:     attachment.ClientID,
+		FileName:     attachment.FileName,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		Checksum:     attachment.Checksum,
+		Description:  attachment.Description,
+		SpecialistID: attachment.SpecialistID,
+		CreatedAt:    attachment.CreatedAt,
+	}
+}
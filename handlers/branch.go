@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BranchHandler struct {
+	repo models.BranchRepository
+}
+
+func NewBranchHandler(repo models.BranchRepository) *BranchHandler {
+	return &BranchHandler{repo: repo}
+}
+
+// BranchRequest - филиал центра. Код неизменяем: по нему филиал указывают внешние системы.
+type BranchRequest struct {
+	Code    string `json:"code" binding:"required,max=50,alphanum,lowercase"`
+	Name    string `json:"name" binding:"required,max=100"`
+	Address string `json:"address" binding:"max=300"`
+	Phone   string `json:"phone" binding:"omitempty,e164"`
+	Active  *bool  `json:"active"`
+}
+
+type BranchResponse struct {
+	ID        uint      `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Phone     string    `json:"phone"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *BranchHandler) CreateBranch(c *gin.Context) {
+	var req BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	branch := &models.Branch{Code: req.Code, Active: true}
+	applyBranchRequest(branch, req)
+
+	if err := h.repo.CreateBranch(branch); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "branch with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toBranchResponse(branch))
+}
+
+// ListBranches возвращает филиалы; active=true оставляет только работающие
+func (h *BranchHandler) ListBranches(c *gin.Context) {
+	branches, err := h.repo.ListBranches(c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]BranchResponse, 0, len(branches))
+	for i := range branches {
+		items = append(items, toBranchResponse(&branches[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *BranchHandler) GetBranch(c *gin.Context) {
+	branch, ok := h.loadBranch(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toBranchResponse(branch))
+}
+
+// UpdateBranch меняет данные филиала; смена кода не допускается. Отключенный филиал
+// остается у существующих записей, но новых специалистов, ресурсов и клиентов к нему не привязать.
+func (h *BranchHandler) UpdateBranch(c *gin.Context) {
+	var req BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	branch, ok := h.loadBranch(c)
+	if !ok {
+		return
+	}
+
+	if req.Code != branch.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "branch code cannot be changed"})
+		return
+	}
+
+	applyBranchRequest(branch, req)
+	if err := h.repo.UpdateBranch(branch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toBranchResponse(branch))
+}
+
+// loadBranch загружает филиал по id из пути; при ошибке ответ уже отправлен
+func (h *BranchHandler) loadBranch(c *gin.Context) (*models.Branch, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid branch ID format"})
+		return nil, false
+	}
+
+	branch, err := h.repo.GetBranch(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "branch not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return branch, true
+}
+
+// resolveBranch возвращает работающий филиал для привязки специалиста, ресурса или клиента;
+// id 0 - основной филиал. При ошибке ответ уже отправлен.
+func resolveBranch(c *gin.Context, branches models.BranchRepository, id uint) (*models.Branch, bool) {
+	var branch *models.Branch
+	var err error
+	if id == 0 {
+		branch, err = branches.GetBranchByCode(models.DefaultBranchCode)
+	} else {
+		branch, err = branches.GetBranch(id)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "branch not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !branch.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "branch is inactive"})
+		return nil, false
+	}
+	return branch, true
+}
+
+// parseBranchQuery разбирает необязательный branch_id из строки запроса; 0 - все филиалы.
+// При ошибке ответ уже отправлен.
+func parseBranchQuery(c *gin.Context) (uint, bool) {
+	value := c.Query("branch_id")
+	if value == "" {
+		return 0, true
+	}
+	branchID, err := parseUint(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid branch ID format"})
+		return 0, false
+	}
+	return branchID, true
+}
+
+func applyBranchRequest(branch *models.Branch, req BranchRequest) {
+	branch.Name = req.Name
+	branch.Address = req.Address
+	branch.Phone = req.Phone
+	if req.Active != nil {
+		branch.Active = *req.Active
+	}
+}
+
+func toBranchResponse(branch *models.Branch) BranchResponse {
+	return BranchResponse{
+		ID:        branch.ID,
+		Code:      branch.Code,
+		Name:      branch.Name,
+		Address:   branch.Address,
+		Phone:     branch.Phone,
+		Active:    branch.Active,
+		CreatedAt: branch.CreatedAt,
+	}
+}
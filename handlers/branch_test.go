@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeBranches - филиалы в памяти для проверки разрешения филиала
+type fakeBranches struct {
+	models.BranchRepository
+	branches []models.Branch
+}
+
+func (f *fakeBranches) GetBranch(id uint) (*models.Branch, error) {
+	for i := range f.branches {
+		if f.branches[i].ID == id {
+			return &f.branches[i], nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (f *fakeBranches) GetBranchByCode(code string) (*models.Branch, error) {
+	for i := range f.branches {
+		if f.branches[i].Code == code {
+			return &f.branches[i], nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func TestResolveBranch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	branches := &fakeBranches{branches: []models.Branch{
+		{ID: 1, Code: models.DefaultBranchCode, Active: true},
+		{ID: 2, Code: "north", Active: true},
+		{ID: 3, Code: "closed", Active: false},
+	}}
+
+	tests := []struct {
+		name       string
+		id         uint
+		wantID     uint
+		wantStatus int
+	}{
+		{"default branch", 0, 1, http.StatusOK},
+		{"explicit branch", 2, 2, http.StatusOK},
+		{"inactive branch", 3, 0, http.StatusBadRequest},
+		{"unknown branch", 9, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		branch, ok := resolveBranch(c, branches, tt.id)
+		if tt.wantID != 0 {
+			if !ok || branch.ID != tt.wantID {
+				t.Errorf("%s: resolveBranch(%d) = %+v, %v, want branch %d", tt.name, tt.id, branch, ok, tt.wantID)
+			}
+			continue
+		}
+		if ok || w.Code != tt.wantStatus {
+			t.Errorf("%s: resolveBranch(%d) ok = %v, status %d, want status %d", tt.name, tt.id, ok, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestResourcesInBranch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resources := []models.Resource{{Name: "Массажный стол", BranchID: 1}, {Name: "Кушетка", BranchID: 2}}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if !resourcesInBranch(c, resources[:1], 1) {
+		t.Error("resources of the branch rejected")
+	}
+	if !resourcesInBranch(c, nil, 1) {
+		t.Error("appointment without resources rejected")
+	}
+
+	w := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	if resourcesInBranch(c, resources, 1) || w.Code != http.StatusConflict {
+		t.Errorf("resource of another branch: status %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestParseBranchQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query  string
+		want   uint
+		wantOK bool
+	}{
+		{"", 0, true},
+		{"?branch_id=4", 4, true},
+		{"?branch_id=north", 0, false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/reports/cash"+tt.query, nil)
+		if got, ok := parseBranchQuery(c); got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseBranchQuery(%q) = %d, %v, want %d, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChannelHandler struct {
+	repo models.ChannelRepository
+}
+
+func NewChannelHandler(repo models.ChannelRepository) *ChannelHandler {
+	return &ChannelHandler{repo: repo}
+}
+
+// ChannelRequest - рекламный канал справочника. Код неизменяем: по нему клиенты ссылаются на канал.
+type ChannelRequest struct {
+	Code   string `json:"code" binding:"required,max=50,alphanum,lowercase"`
+	Name   string `json:"name" binding:"required,max=100"`
+	Active *bool  `json:"active"`
+}
+
+type ChannelResponse struct {
+	ID        uint      `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChannelReportResponse struct {
+	Channel          string       `json:"channel"`
+	Name             string       `json:"name"`
+	NewClients       int64        `json:"new_clients"`
+	ConvertedClients int64        `json:"converted_clients"`
+	ConversionRate   float64      `json:"conversion_rate"`
+	Revenue          models.Money `json:"revenue"`
+}
+
+func (h *ChannelHandler) CreateChannel(c *gin.Context) {
+	var req ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel := &models.AdvertisingChannel{Code: req.Code, Name: req.Name, Active: true}
+	if req.Active != nil {
+		channel.Active = *req.Active
+	}
+
+	if err := h.repo.CreateChannel(channel); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "channel with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toChannelResponse(channel))
+}
+
+// ListChannels возвращает справочник каналов; active=true оставляет только доступные для новых клиентов
+func (h *ChannelHandler) ListChannels(c *gin.Context) {
+	channels, err := h.repo.ListChannels(c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ChannelResponse, 0, len(channels))
+	for i := range channels {
+		items = append(items, toChannelResponse(&channels[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// UpdateChannel меняет название и активность канала; смена кода не допускается
+func (h *ChannelHandler) UpdateChannel(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID format"})
+		return
+	}
+
+	var req ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel, err := h.repo.GetChannel(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Code != channel.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "channel code cannot be changed"})
+		return
+	}
+
+	channel.Name = req.Name
+	if req.Active != nil {
+		channel.Active = *req.Active
+	}
+
+	if err := h.repo.UpdateChannel(channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toChannelResponse(channel))
+}
+
+// GetChannelReport - эффективность каналов за период [from, to]: новые клиенты, конверсия
+// в первый оплаченный визит и выручка от клиентов канала
+func (h *ChannelHandler) GetChannelReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c, true)
+	if !ok {
+		return
+	}
+	branchID, ok := parseBranchQuery(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.repo.ChannelReport(from, to.AddDate(0, 0, 1), branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ChannelReportResponse, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, ChannelReportResponse{
+			Channel:          row.Channel,
+			Name:             row.Name,
+			NewClients:       row.NewClients,
+			ConvertedClients: row.ConvertedClients,
+			ConversionRate:   row.ConversionRate(),
+			Revenue:          row.Revenue,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from.Format(dateLayout),
+		"to":        to.Format(dateLayout),
+		"branch_id": branchID,
+		"channels":  items,
+	})
+}
+
+func toChannelResponse(channel *models.AdvertisingChannel) ChannelResponse {
+	return ChannelResponse{
+		ID:        channel.ID,
+		Code:      channel.Code,
+		Name:      channel.Name,
+		Active:    channel.Active,
+		CreatedAt: channel.CreatedAt,
+	}
+}
@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 	"wellness-step-by-step/step-08/consumer"
 	"wellness-step-by-step/step-08/models"
@@ -15,30 +16,56 @@ import (
 )
 
 type ClientHandler struct {
-	repo  models.Repository
-	kafka utils.KafkaProducer
-	es    utils.ElasticsearchClient // Добавляем поле для Elasticsearch
+	repo     models.Repository
+	channels models.ChannelRepository
+	branches models.BranchRepository
+	kafka    utils.KafkaProducer
+	es       utils.ElasticsearchClient // Добавляем поле для Elasticsearch
 }
 
-func NewClientHandler(repo models.Repository, kafka utils.KafkaProducer, es utils.ElasticsearchClient) *ClientHandler {
+func NewClientHandler(repo models.Repository, channels models.ChannelRepository, branches models.BranchRepository, kafka utils.KafkaProducer, es utils.ElasticsearchClient) *ClientHandler {
 	return &ClientHandler{
-		repo:  repo,
-		kafka: kafka,
-		es:    es, // Инициализируем Elasticsearch клиент
+		repo:     repo,
+		channels: channels,
+		branches: branches,
+		kafka:    kafka,
+		es:       es, // Инициализируем Elasticsearch клиент
 	}
 }
 
+// ClientRequest - анкета клиента, заполняемая администратором при регистрации.
+// AdvertisingChannel - код активного канала из справочника /channels.
 type ClientRequest struct {
-	FullName string `json:"full_name" binding:"required,min=2,max=100"`
-	Email    string `json:"email" binding:"required,email"`
-	Phone    string `json:"phone" binding:"required,e164"`
+	FullName           string `json:"full_name" binding:"required,min=2,max=100"`
+	Email              string `json:"email" binding:"required,email"`
+	Phone              string `json:"phone" binding:"required,e164"`
+	AdvertisingChannel string `json:"advertising_channel" binding:"required,max=50"`
+	SpecialistID       *uint  `json:"specialist_id"`
+	BranchID           *uint  `json:"branch_id" binding:"omitempty,min=1"`
+	MeetingPlace       string `json:"meeting_place" binding:"required,max=200"`
+	Occupation         string `json:"occupation" binding:"required,max=100"`
+	Gender             string `json:"gender" binding:"required,oneof=male female"`
+	Age                int    `json:"age" binding:"required,min=1,max=120"`
+	ReasonForVisit     string `json:"reason_for_visit" binding:"required,max=1000"`
+	SpecialistNotes    string `json:"specialist_notes" binding:"max=5000"`
 }
 
 type ClientResponse struct {
-	ID       uint   `json:"id"`
-	FullName string `json:"full_name"`
-	Email    string `json:"email"`
-	Phone    string `json:"phone"`
+	ID                 uint          `json:"id"`
+	FullName           string        `json:"full_name"`
+	Email              string        `json:"email"`
+	Phone              string        `json:"phone"`
+	AdvertisingChannel string        `json:"advertising_channel"`
+	SpecialistID       *uint         `json:"specialist_id"`
+	BranchID           *uint         `json:"branch_id"`
+	MeetingPlace       string        `json:"meeting_place"`
+	Occupation         string        `json:"occupation"`
+	Gender             string        `json:"gender"`
+	Age                int           `json:"age"`
+	ReasonForVisit     string        `json:"reason_for_visit"`
+	SpecialistNotes    string        `json:"specialist_notes"`
+	Tags               []TagResponse `json:"tags"`
+	CreatedAt          time.Time     `json:"created_at"`
 }
 
 func (h *ClientHandler) CreateClient(c *gin.Context) {
@@ -48,19 +75,25 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 		return
 	}
 
-	client := &models.Client{
-		FullName: req.FullName,
-		Email:    req.Email,
-		Phone:    req.Phone,
+	if !h.checkChannel(c, req.AdvertisingChannel) {
+		return
 	}
+	if req.BranchID != nil {
+		if _, ok := resolveBranch(c, h.branches, *req.BranchID); !ok {
+			return
+		}
+	}
+
+	client := &models.Client{}
+	applyClientRequest(client, req)
 
 	if err := h.repo.CreateClient(client); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondClientWriteError(c, err)
 		return
 	}
 
 	if h.kafka != nil {
-		go h.sendKafkaEvent("client_created", client)
+		go publishClientEvent(h.kafka, "client_created", client)
 	}
 
 	c.JSON(http.StatusCreated, toClientResponse(client))
@@ -129,17 +162,25 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 		return
 	}
 
-	client.FullName = req.FullName
-	client.Email = req.Email
-	client.Phone = req.Phone
+	// Клиент, привлеченный ныне отключенным каналом, сохраняет свою атрибуцию
+	if req.AdvertisingChannel != client.AdvertisingChannel && !h.checkChannel(c, req.AdvertisingChannel) {
+		return
+	}
+	if req.BranchID != nil && (client.BranchID == nil || *req.BranchID != *client.BranchID) {
+		if _, ok := resolveBranch(c, h.branches, *req.BranchID); !ok {
+			return
+		}
+	}
+
+	applyClientRequest(client, req)
 
 	if err := h.repo.UpdateClient(client); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondClientWriteError(c, err)
 		return
 	}
 
 	if h.kafka != nil {
-		go h.sendKafkaEvent("client_updated", client)
+		go publishClientEvent(h.kafka, "client_updated", client)
 	}
 
 	c.JSON(http.StatusOK, toClientResponse(client))
@@ -173,59 +214,173 @@ func (h *ClientHandler) DeleteClient(c *gin.Context) {
 				"event": "client_deleted",
 				"id":    id,
 			}
-			h.sendRawKafkaEvent("client_events", event)
+			publishEvent(h.kafka, consumer.ClientEventsTopic, event)
 		}(id)
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// Вспомогательные методы
+// ReindexClients публикует client_updated для клиентов ids, чтобы консьюмер переписал их документы
+// в поисковом индексе. Используется после миграции, назначившей клиентам филиал.
+func (h *ClientHandler) ReindexClients(ids []uint) {
+	if len(ids) == 0 {
+		return
+	}
+	if h.kafka == nil {
+		log.Printf("WARNING: %d clients were not reindexed: Kafka is unavailable", len(ids))
+		return
+	}
+
+	go func() {
+		for _, id := range ids {
+			client, err := h.repo.GetClientByID(id)
+			if err != nil {
+				log.Printf("Failed to load client %d for reindexing: %v", id, err)
+				continue
+			}
+			publishClientEvent(h.kafka, "client_updated", client)
+		}
+	}()
+}
+
+// ListClientsQuery - фильтры и сортировка списка клиентов
+type ListClientsQuery struct {
+	PageQuery
+	SpecialistID       uint      `form:"specialist_id"`
+	BranchID           uint      `form:"branch_id"`
+	Gender             string    `form:"gender" binding:"omitempty,oneof=male female"`
+	AdvertisingChannel string    `form:"advertising_channel"`
+	AgeMin             int       `form:"age_min" binding:"omitempty,min=1"`
+	AgeMax             int       `form:"age_max" binding:"omitempty,min=1"`
+	CreatedFrom        time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo          time.Time `form:"created_to" time_format:"2006-01-02"`
+	TagID              uint      `form:"tag_id"`
+	Sort               string    `form:"sort"`
+	Order              string    `form:"order" binding:"omitempty,oneof=asc desc"`
+}
 
-func (h *ClientHandler) sendKafkaEvent(eventType string, client *models.Client) {
-	event := consumer.ClientEvent{
-		Event: eventType,
-		Data:  *client,
+func (h *ClientHandler) ListClients(c *gin.Context) {
+	var query ListClientsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	jsonData, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("Failed to marshal Kafka event: %v", err)
+	if query.Sort != "" && !models.IsValidClientSort(query.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported sort key"})
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	page := query.PageQuery.normalize()
+	filter := models.ClientFilter{
+		SpecialistID:       query.SpecialistID,
+		BranchID:           query.BranchID,
+		Gender:             query.Gender,
+		AdvertisingChannel: query.AdvertisingChannel,
+		AgeMin:             query.AgeMin,
+		AgeMax:             query.AgeMax,
+		CreatedFrom:        query.CreatedFrom,
+		TagID:              query.TagID,
+		SortBy:             query.Sort,
+		SortDesc:           query.Order == "desc",
+		Limit:              page.PageSize,
+		Offset:             page.offset(),
+	}
+	// Дата окончания включительная
+	if !query.CreatedTo.IsZero() {
+		filter.CreatedTo = query.CreatedTo.AddDate(0, 0, 1)
+	}
 
-	if err := h.kafka.SendMessage(ctx, "client_events", nil, jsonData); err != nil {
-		log.Printf("Failed to send Kafka message: %v", err)
+	clients, total, err := h.repo.ListClients(filter)
+	if err != nil {
+		utils.CaptureError(err, map[string]interface{}{
+			"endpoint": c.Request.URL.Path,
+			"method":   c.Request.Method,
+			"action":   "list_clients",
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
 	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, toClientResponses(clients), total, page))
 }
 
-func (h *ClientHandler) sendRawKafkaEvent(topic string, event interface{}) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Вспомогательные методы
 
-	jsonData, err := json.Marshal(event)
+// checkChannel проверяет, что рекламный канал есть в справочнике и не отключен
+func (h *ClientHandler) checkChannel(c *gin.Context, code string) bool {
+	channel, err := h.channels.GetChannelByCode(code)
 	if err != nil {
-		log.Printf("Failed to marshal Kafka event: %v", err)
-		return
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown advertising channel"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
 	}
+	if !channel.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "advertising channel is inactive"})
+		return false
+	}
+	return true
+}
 
-	if err := h.kafka.SendMessage(ctx, topic, nil, jsonData); err != nil {
-		log.Printf("Failed to send Kafka message: %v", err)
+// respondClientWriteError отвечает на ошибку сохранения клиента
+func respondClientWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "client with this email already exists"})
+	case errors.Is(err, models.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specialist, branch or advertising channel not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 }
 
+func applyClientRequest(client *models.Client, req ClientRequest) {
+	client.FullName = req.FullName
+	client.Email = req.Email
+	client.Phone = req.Phone
+	client.AdvertisingChannel = req.AdvertisingChannel
+	client.SpecialistID = req.SpecialistID
+	client.BranchID = req.BranchID
+	client.MeetingPlace = req.MeetingPlace
+	client.Occupation = req.Occupation
+	client.Gender = req.Gender
+	client.Age = req.Age
+	client.ReasonForVisit = req.ReasonForVisit
+	client.SpecialistNotes = req.SpecialistNotes
+}
+
 func toClientResponse(client *models.Client) ClientResponse {
 	return ClientResponse{
-		ID:       client.ID,
-		FullName: client.FullName,
-		Email:    client.Email,
-		Phone:    client.Phone,
+		ID:                 client.ID,
+		FullName:           client.FullName,
+		Email:              client.Email,
+		Phone:              client.Phone,
+		AdvertisingChannel: client.AdvertisingChannel,
+		SpecialistID:       client.SpecialistID,
+		BranchID:           client.BranchID,
+		MeetingPlace:       client.MeetingPlace,
+		Occupation:         client.Occupation,
+		Gender:             client.Gender,
+		Age:                client.Age,
+		ReasonForVisit:     client.ReasonForVisit,
+		SpecialistNotes:    client.SpecialistNotes,
+		Tags:               toTagResponses(client.Tags),
+		CreatedAt:          client.CreatedAt,
 	}
 }
 
+func toClientResponses(clients []models.Client) []ClientResponse {
+	items := make([]ClientResponse, 0, len(clients))
+	for i := range clients {
+		items = append(items, toClientResponse(&clients[i]))
+	}
+	return items
+}
+
 func parseUint(s string) (uint, error) {
 	var id uint
 	_, err := fmt.Sscanf(s, "%d", &id)
@@ -247,11 +402,52 @@ func (h *ClientHandler) SearchClients(c *gin.Context) {
 		return
 	}
 
+	// Фильтры по полям анкеты применяются как точные совпадения
+	var filters []map[string]interface{}
+	for _, field := range []string{"gender", "advertising_channel", "specialist_id", "branch_id"} {
+		if value := c.Query(field); value != "" {
+			filters = append(filters, map[string]interface{}{
+				"term": map[string]interface{}{field: value},
+			})
+		}
+	}
+
+	ageRange := map[string]interface{}{}
+	if v := c.Query("age_min"); v != "" {
+		age, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid age_min"})
+			return
+		}
+		ageRange["gte"] = age
+	}
+	if v := c.Query("age_max"); v != "" {
+		age, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid age_max"})
+			return
+		}
+		ageRange["lte"] = age
+	}
+	if len(ageRange) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"age": ageRange},
+		})
+	}
+
 	searchQuery := map[string]interface{}{
 		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  query,
-				"fields": []string{"full_name", "email", "phone"},
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query": query,
+						"fields": []string{
+							"full_name", "email", "phone", "occupation",
+							"reason_for_visit", "meeting_place", "specialist_notes",
+						},
+					},
+				},
+				"filter": filters,
 			},
 		},
 	}
@@ -262,42 +458,25 @@ func (h *ClientHandler) SearchClients(c *gin.Context) {
 		return
 	}
 
-	// Преобразуем результаты в ClientResponse
-	// В методе SearchClients после получения results
-	var clients []ClientResponse
-	for _, hit := range results {
-		id, ok := hit["id"].(float64)
-		if !ok {
-			log.Printf("Invalid id type in search result: %v", hit["id"])
-			continue
-		}
-
-		fullName, ok := hit["full_name"].(string)
-		if !ok {
-			log.Printf("Invalid full_name type in search result: %v", hit["full_name"])
-			continue
-		}
+	c.JSON(http.StatusOK, decodeClientHits(results))
+}
 
-		email, ok := hit["email"].(string)
-		if !ok {
-			log.Printf("Invalid email type in search result: %v", hit["email"])
+// decodeClientHits переводит найденные документы в ответ; документы в индексе имеют ту же структуру, что и ClientResponse
+func decodeClientHits(results []map[string]interface{}) []ClientResponse {
+	clients := make([]ClientResponse, 0, len(results))
+	for _, hit := range results {
+		raw, err := json.Marshal(hit)
+		if err != nil {
+			log.Printf("Failed to marshal search hit: %v", err)
 			continue
 		}
 
-		phone, ok := hit["phone"].(string)
-		if !ok {
-			log.Printf("Invalid phone type in search result: %v", hit["phone"])
+		var client ClientResponse
+		if err := json.Unmarshal(raw, &client); err != nil {
+			log.Printf("Invalid client document in search result: %v", err)
 			continue
 		}
-
-		client := ClientResponse{
-			ID:       uint(id),
-			FullName: fullName,
-			Email:    email,
-			Phone:    phone,
-		}
 		clients = append(clients, client)
 	}
-
-	c.JSON(http.StatusOK, clients)
+	return clients
 }
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSignatureImageSize - ограничение размера изображения подписи после декодирования
+const maxSignatureImageSize = 512 << 10
+
+var (
+	errInvalidSignatureImage  = errors.New("signature_image must be a base64 data URL of a PNG or JPEG image")
+	errSignatureImageTooLarge = errors.New("signature image is too large")
+)
+
+type ConsentHandler struct {
+	repo    models.ConsentRepository
+	clients models.Repository
+}
+
+func NewConsentHandler(repo models.ConsentRepository, clients models.Repository) *ConsentHandler {
+	return &ConsentHandler{
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+// ConsentTemplateRequest - шаблон согласия. ValidDays - срок действия подписи в днях, 0 - бессрочно.
+type ConsentTemplateRequest struct {
+	Code      string   `json:"code" binding:"required,max=50"`
+	Title     string   `json:"title" binding:"required,max=200"`
+	Body      string   `json:"body" binding:"required,max=50000"`
+	Services  []string `json:"services" binding:"max=50,dive,max=100"`
+	ValidDays int      `json:"valid_days" binding:"min=0,max=3650"`
+}
+
+// SignConsentRequest - подпись согласия. DocumentHash - SHA-256 документа, который видел клиент;
+// если текст успел измениться, подпись отклоняется. SignatureImage - data URL изображения (PNG или JPEG).
+type SignConsentRequest struct {
+	TemplateID     uint   `json:"template_id" binding:"required"`
+	SignerName     string `json:"signer_name" binding:"required,max=200"`
+	DocumentHash   string `json:"document_hash" binding:"required,len=64,hexadecimal"`
+	SignatureImage string `json:"signature_image"`
+}
+
+type ConsentTemplateResponse struct {
+	ID        uint      `json:"id"`
+	Code      string    `json:"code"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Services  []string  `json:"services"`
+	ValidDays int       `json:"valid_days"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ConsentSignatureResponse struct {
+	ID              uint       `json:"id"`
+	ClientID        uint       `json:"client_id"`
+	TemplateID      uint       `json:"template_id"`
+	Code            string     `json:"code"`
+	Title           string     `json:"title"`
+	TemplateVersion int        `json:"template_version"`
+	SignerName      string     `json:"signer_name"`
+	SignedAt        time.Time  `json:"signed_at"`
+	IPAddress       string     `json:"ip_address"`
+	DocumentHash    string     `json:"document_hash"`
+	HasSignature    bool       `json:"has_signature_image"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	Valid           bool       `json:"valid"`
+	Document        string     `json:"document,omitempty"`
+}
+
+type MissingConsentResponse struct {
+	ID    uint   `json:"id"`
+	Code  string `json:"code"`
+	Title string `json:"title"`
+}
+
+func (h *ConsentHandler) CreateTemplate(c *gin.Context) {
+	var req ConsentTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := &models.ConsentTemplate{}
+	applyConsentTemplateRequest(template, req)
+
+	if err := h.repo.CreateConsentTemplate(template); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "consent with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toConsentTemplateResponse(template))
+}
+
+func (h *ConsentHandler) ListTemplates(c *gin.Context) {
+	var page PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page = page.normalize()
+
+	templates, total, err := h.repo.ListConsentTemplates(page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ConsentTemplateResponse, 0, len(templates))
+	for i := range templates {
+		items = append(items, toConsentTemplateResponse(&templates[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *ConsentHandler) GetTemplate(c *gin.Context) {
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toConsentTemplateResponse(template))
+}
+
+func (h *ConsentHandler) UpdateTemplate(c *gin.Context) {
+	var req ConsentTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	applyConsentTemplateRequest(template, req)
+
+	if err := h.repo.UpdateConsentTemplate(template); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "consent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toConsentTemplateResponse(template))
+}
+
+// RenderDocument возвращает текст согласия для клиента и его хэш, который нужно передать при подписании
+func (h *ConsentHandler) RenderDocument(c *gin.Context) {
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	clientID, err := parseUint(c.Query("client_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+	client, err := h.clients.GetClientByID(clientID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	document := template.Render(client, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"template_id":      template.ID,
+		"template_version": template.Version,
+		"document":         document,
+		"document_hash":    models.DocumentHash(document),
+	})
+}
+
+// SignConsent сохраняет подпись клиента с IP-адресом, временем и хэшем подписанного документа
+func (h *ConsentHandler) SignConsent(c *gin.Context) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+
+	var req SignConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.clients.GetClientByID(clientID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.repo.GetConsentTemplate(req.TemplateID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "consent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var image []byte
+	var mimeType string
+	if req.SignatureImage != "" {
+		image, mimeType, err = decodeSignatureImage(req.SignatureImage)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	document := template.Render(client, now)
+	hash := models.DocumentHash(document)
+	if !strings.EqualFold(hash, req.DocumentHash) {
+		c.JSON(http.StatusConflict, gin.H{"error": "consent document has changed, render it again before signing"})
+		return
+	}
+
+	signature := &models.ConsentSignature{
+		ClientID:          clientID,
+		TemplateID:        template.ID,
+		TemplateVersion:   template.Version,
+		SignerName:        req.SignerName,
+		SignedAt:          now,
+		IPAddress:         c.ClientIP(),
+		UserAgent:         c.Request.UserAgent(),
+		Document:          document,
+		DocumentHash:      hash,
+		SignatureImage:    image,
+		SignatureMimeType: mimeType,
+		Template:          template,
+	}
+	if template.ValidDays > 0 {
+		expiresAt := now.AddDate(0, 0, template.ValidDays)
+		signature.ExpiresAt = &expiresAt
+	}
+
+	if err := h.repo.CreateConsentSignature(signature); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toConsentSignatureResponse(signature, now))
+}
+
+func (h *ConsentHandler) ListSignatures(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	signatures, err := h.repo.ListConsentSignatures(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	items := make([]ConsentSignatureResponse, 0, len(signatures))
+	for i := range signatures {
+		items = append(items, toConsentSignatureResponse(&signatures[i], now))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// GetSignature возвращает подпись вместе с текстом подписанного документа
+func (h *ConsentHandler) GetSignature(c *gin.Context) {
+	signature, ok := h.loadSignature(c)
+	if !ok {
+		return
+	}
+
+	resp := toConsentSignatureResponse(signature, time.Now())
+	resp.Document = signature.Document
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetSignatureImage отдает изображение подписи
+func (h *ConsentHandler) GetSignatureImage(c *gin.Context) {
+	signature, ok := h.loadSignature(c)
+	if !ok {
+		return
+	}
+
+	if len(signature.SignatureImage) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "signature image not found"})
+		return
+	}
+
+	c.Data(http.StatusOK, signature.SignatureMimeType, signature.SignatureImage)
+}
+
+// RevokeSignature отзывает согласие клиента; запись на услуги, требующие его, снова блокируется
+func (h *ConsentHandler) RevokeSignature(c *gin.Context) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+	signatureID, err := parseUint(c.Param("signature_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature ID format"})
+		return
+	}
+
+	now := time.Now()
+	signature, err := h.repo.RevokeConsentSignature(clientID, signatureID, now)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "consent signature not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toConsentSignatureResponse(signature, now))
+}
+
+func (h *ConsentHandler) loadTemplate(c *gin.Context) (*models.ConsentTemplate, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid consent ID format"})
+		return nil, false
+	}
+
+	template, err := h.repo.GetConsentTemplate(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "consent not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return template, true
+}
+
+func (h *ConsentHandler) loadSignature(c *gin.Context) (*models.ConsentSignature, bool) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return nil, false
+	}
+	signatureID, err := parseUint(c.Param("signature_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature ID format"})
+		return nil, false
+	}
+
+	signature, err := h.repo.GetConsentSignature(clientID, signatureID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "consent signature not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return signature, true
+}
+
+// ensureConsents блокирует запись на услугу, если клиент не подписал обязательные для нее согласия,
+// действующие на момент визита at. Ответ с перечнем недостающих согласий уже отправлен при false.
+func ensureConsents(c *gin.Context, consents models.ConsentRepository, clientID uint, service string, at time.Time) bool {
+	missing, err := consents.MissingConsents(clientID, service, at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	items := make([]MissingConsentResponse, 0, len(missing))
+	for _, template := range missing {
+		items = append(items, MissingConsentResponse{ID: template.ID, Code: template.Code, Title: template.Title})
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":            models.ErrConsentRequired.Error(),
+		"missing_consents": items,
+	})
+	return false
+}
+
+// decodeSignatureImage разбирает data URL вида data:image/png;base64,...
+func decodeSignatureImage(dataURL string) ([]byte, string, error) {
+	header, payload, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return nil, "", errInvalidSignatureImage
+	}
+
+	mimeType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	if mimeType != "image/png" && mimeType != "image/jpeg" {
+		return nil, "", errInvalidSignatureImage
+	}
+
+	image, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(image) == 0 {
+		return nil, "", errInvalidSignatureImage
+	}
+	if len(image) > maxSignatureImageSize {
+		return nil, "", errSignatureImageTooLarge
+	}
+	if http.DetectContentType(image) != mimeType {
+		return nil, "", errInvalidSignatureImage
+	}
+	return image, mimeType, nil
+}
+
+func applyConsentTemplateRequest(template *models.ConsentTemplate, req ConsentTemplateRequest) {
+	template.Code = req.Code
+	template.Title = req.Title
+	template.Body = req.Body
+	template.Services = models.NormalizeServices(req.Services)
+	template.ValidDays = req.ValidDays
+}
+
+func toConsentTemplateResponse(template *models.ConsentTemplate) ConsentTemplateResponse {
+	services := []string(template.Services)
+	if services == nil {
+		services = []string{}
+	}
+	return ConsentTemplateResponse{
+		ID:        template.ID,
+		Code:      template.Code,
+		Title:     template.Title,
+		Body:      template.Body,
+		Services:  services,
+		ValidDays: template.ValidDays,
+		Version:   template.Version,
+		UpdatedAt: template.UpdatedAt,
+	}
+}
+
+func toConsentSignatureResponse(signature *models.ConsentSignature, now time.Time) ConsentSignatureResponse {
+	resp := ConsentSignatureResponse{
+		ID:              signature.ID,
+		ClientID:        signature.ClientID,
+		TemplateID:      signature.TemplateID,
+		TemplateVersion: signature.TemplateVersion,
+		SignerName:      signature.SignerName,
+		SignedAt:        signature.SignedAt,
+		IPAddress:       signature.IPAddress,
+		DocumentHash:    signature.DocumentHash,
+		HasSignature:    signature.SignatureMimeType != "",
+		ExpiresAt:       signature.ExpiresAt,
+		RevokedAt:       signature.RevokedAt,
+	}
+	if signature.Template != nil {
+		resp.Code = signature.Template.Code
+		resp.Title = signature.Template.Title
+		resp.Valid = signature.IsValid(signature.Template.Version, now)
+	}
+	return resp
+}
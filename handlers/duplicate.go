@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DuplicateHandler struct {
+	repo    models.DuplicateRepository
+	clients models.Repository
+	kafka   utils.KafkaProducer
+	es      utils.ElasticsearchClient
+}
+
+func NewDuplicateHandler(repo models.DuplicateRepository, clients models.Repository, kafka utils.KafkaProducer, es utils.ElasticsearchClient) *DuplicateHandler {
+	return &DuplicateHandler{
+		repo:    repo,
+		clients: clients,
+		kafka:   kafka,
+		es:      es,
+	}
+}
+
+// MergeClientsRequest - слияние: клиент из пути остается, duplicate_id удаляется
+type MergeClientsRequest struct {
+	DuplicateID uint `json:"duplicate_id" binding:"required"`
+}
+
+type DuplicateCandidateResponse struct {
+	Client  ClientResponse `json:"client"`
+	Score   float64        `json:"score"`
+	Reasons []string       `json:"reasons"`
+}
+
+type MergeClientsResponse struct {
+	Client         ClientResponse   `json:"client"`
+	MergedClientID uint             `json:"merged_client_id"`
+	Moved          map[string]int64 `json:"moved"`
+}
+
+// FindDuplicates возвращает возможных дубликатов клиента по убыванию оценки. Похожие имена
+// ищутся нечетким запросом в Elasticsearch; без него совпадение имени проверяется только точное.
+func (h *DuplicateHandler) FindDuplicates(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+
+	client, err := h.clients.GetClientByID(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var nameMatchIDs []uint
+	if h.es != nil {
+		searchQuery := map[string]interface{}{
+			"size": 20,
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must": map[string]interface{}{
+						"match": map[string]interface{}{
+							"full_name": map[string]interface{}{
+								"query":     client.FullName,
+								"fuzziness": "AUTO",
+								"operator":  "and",
+							},
+						},
+					},
+					"must_not": map[string]interface{}{
+						"term": map[string]interface{}{"id": client.ID},
+					},
+				},
+			},
+		}
+		results, err := h.es.SearchClients(c.Request.Context(), "clients", searchQuery)
+		if err != nil {
+			log.Printf("Failed to search similar names for client %d: %v", client.ID, err)
+		}
+		for _, hit := range decodeClientHits(results) {
+			nameMatchIDs = append(nameMatchIDs, hit.ID)
+		}
+	}
+
+	candidates, err := h.repo.FindDuplicateCandidates(client, nameMatchIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]DuplicateCandidateResponse, 0, len(candidates))
+	for i := range candidates {
+		items = append(items, DuplicateCandidateResponse{
+			Client:  toClientResponse(&candidates[i].Client),
+			Score:   candidates[i].Score,
+			Reasons: candidates[i].Reasons,
+		})
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// MergeClients переносит визиты, заметки, счета и остальные данные дубликата на клиента из пути
+func (h *DuplicateHandler) MergeClients(c *gin.Context) {
+	survivorID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+
+	var req MergeClientsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DuplicateID == survivorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a client cannot be merged with itself"})
+		return
+	}
+
+	result, err := h.repo.MergeClients(survivorID, req.DuplicateID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+		case errors.Is(err, models.ErrMergeConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if h.kafka != nil {
+		go publishClientMergedEvent(h.kafka, result.Survivor, result.MergedID)
+	}
+
+	c.JSON(http.StatusOK, MergeClientsResponse{
+		Client:         toClientResponse(result.Survivor),
+		MergedClientID: result.MergedID,
+		Moved:          result.Moved,
+	})
+}
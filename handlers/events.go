@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"wellness-step-by-step/step-08/consumer"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+)
+
+// publishClientEvent отправляет событие об изменении клиента в топик client_events
+func publishClientEvent(producer utils.KafkaProducer, eventType string, client *models.Client) {
+	event := consumer.ClientEvent{
+		Event: eventType,
+		Data:  *client,
+	}
+	publishEvent(producer, consumer.ClientEventsTopic, event)
+}
+
+// publishClientMergedEvent сообщает о слиянии дубликата mergedID с клиентом survivor
+func publishClientMergedEvent(producer utils.KafkaProducer, survivor *models.Client, mergedID uint) {
+	event := consumer.ClientEvent{
+		Event:          "client_merged",
+		Data:           *survivor,
+		MergedClientID: mergedID,
+	}
+	publishEvent(producer, consumer.ClientEventsTopic, event)
+}
+
+// publishAppointmentEvent отправляет событие по записи на прием в топик appointment_events
+func publishAppointmentEvent(producer utils.KafkaProducer, eventType string, appointment *models.Appointment) {
+	event := consumer.AppointmentEvent{
+		Event: eventType,
+		Data:  *appointment,
+	}
+	publishEvent(producer, consumer.AppointmentEventsTopic, event)
+}
+
+// publishEvent сериализует событие в JSON и отправляет его в указанный топик
+func publishEvent(producer utils.KafkaProducer, topic string, event interface{}) {
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal Kafka event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := producer.SendMessage(ctx, topic, nil, jsonData); err != nil {
+		log.Printf("Failed to send Kafka message: %v", err)
+	}
+}
+
+const packageEventsTopic = "package_events"
+
+// PackageEvent - событие по абонементу клиента для внешних сервисов (уведомления, аналитика).
+// При списании указывается визит или запись на групповое занятие, за которые списана сессия.
+type PackageEvent struct {
+	Event             string         `json:"event"`
+	Data              models.Package `json:"data"`
+	AppointmentID     *uint          `json:"appointment_id,omitempty"`
+	ClassEnrollmentID *uint          `json:"class_enrollment_id,omitempty"`
+}
+
+// publishPackageEvent отправляет событие по абонементу в топик package_events
+func publishPackageEvent(producer utils.KafkaProducer, eventType string, pkg *models.Package, debit *models.PackageDebit) {
+	event := PackageEvent{
+		Event: eventType,
+		Data:  *pkg,
+	}
+	if debit != nil {
+		event.AppointmentID = debit.AppointmentID
+		event.ClassEnrollmentID = debit.ClassEnrollmentID
+	}
+	publishEvent(producer, packageEventsTopic, event)
+}
+
+// publishPackageDebitEvents сообщает о списании сессии и, если сессии закончились, об исчерпании абонемента
+func publishPackageDebitEvents(producer utils.KafkaProducer, pkg models.Package, debit models.PackageDebit) {
+	publishPackageEvent(producer, "package_debited", &pkg, &debit)
+	if pkg.EffectiveStatus(time.Now()) == models.PackageStatusExhausted {
+		publishPackageEvent(producer, "package_exhausted", &pkg, &debit)
+	}
+}
+
+const waitlistEventsTopic = "waitlist_events"
+
+// WaitlistSlot - освободившееся время, предлагаемое клиенту из листа ожидания
+type WaitlistSlot struct {
+	AppointmentID uint      `json:"appointment_id"`
+	SpecialistID  uint      `json:"specialist_id"`
+	BranchID      uint      `json:"branch_id"`
+	Room          string    `json:"room"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+// WaitlistEvent - событие для сервиса уведомлений о том, что подходящее клиенту время освободилось
+type WaitlistEvent struct {
+	Event string               `json:"event"`
+	Data  models.WaitlistEntry `json:"data"`
+	Slot  WaitlistSlot         `json:"slot"`
+}
+
+// publishWaitlistEvent отправляет событие листа ожидания в топик waitlist_events
+func publishWaitlistEvent(producer utils.KafkaProducer, eventType string, entry *models.WaitlistEntry, appointment *models.Appointment) {
+	event := WaitlistEvent{
+		Event: eventType,
+		Data:  *entry,
+		Slot: WaitlistSlot{
+			AppointmentID: appointment.ID,
+			SpecialistID:  appointment.SpecialistID,
+			BranchID:      appointment.BranchID,
+			Room:          appointment.Room,
+			StartsAt:      appointment.StartsAt,
+			EndsAt:        appointment.EndsAt,
+		},
+	}
+	publishEvent(producer, waitlistEventsTopic, event)
+}
+
+const classEventsTopic = "class_events"
+
+// ClassEvent - событие по записи клиента на групповое занятие
+type ClassEvent struct {
+	Event string                 `json:"event"`
+	Data  models.ClassEnrollment `json:"data"`
+	Class models.GroupClass      `json:"class"`
+}
+
+// publishClassEvents отправляет в топик class_events по событию на каждую запись
+func publishClassEvents(producer utils.KafkaProducer, eventType string, class models.GroupClass, enrollments []models.ClassEnrollment) {
+	class.Enrollments = nil
+	for _, enrollment := range enrollments {
+		publishEvent(producer, classEventsTopic, ClassEvent{
+			Event: eventType,
+			Data:  enrollment,
+			Class: class,
+		})
+	}
+}
+
+const loyaltyEventsTopic = "loyalty_events"
+
+// loyaltyEventTypes - тип события по виду записи журнала баллов
+var loyaltyEventTypes = map[string]string{
+	models.LoyaltyEntryAccrual:    "loyalty_points_accrued",
+	models.LoyaltyEntryRedemption: "loyalty_points_redeemed",
+	models.LoyaltyEntryRefund:     "loyalty_points_returned",
+	models.LoyaltyEntryReversal:   "loyalty_points_reversed",
+	models.LoyaltyEntryExpiry:     "loyalty_points_expired",
+	models.LoyaltyEntryAdjustment: "loyalty_points_adjusted",
+}
+
+// LoyaltyEvent - новая запись журнала баллов клиента; Balance - доступные баллы после нее
+type LoyaltyEvent struct {
+	Event   string              `json:"event"`
+	Data    models.LoyaltyEntry `json:"data"`
+	Balance int64               `json:"balance"`
+}
+
+// publishLoyaltyEvents отправляет в топик loyalty_events по событию на каждую запись журнала.
+// Записи уже проведены, поэтому баланс после каждой из них восстанавливается от текущего баланса
+// клиента вычитанием более поздних записей пакета.
+func publishLoyaltyEvents(producer utils.KafkaProducer, loyalty models.LoyaltyRepository, entries []models.LoyaltyEntry) {
+	balances := map[uint]int64{}
+	for _, entry := range entries {
+		if _, ok := balances[entry.ClientID]; ok {
+			continue
+		}
+		current, err := loyalty.GetLoyaltyBalance(entry.ClientID, time.Now())
+		if err != nil {
+			log.Printf("Failed to get loyalty balance of client %d: %v", entry.ClientID, err)
+			continue
+		}
+		balances[entry.ClientID] = current.Points
+	}
+
+	after := make([]int64, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		balance, ok := balances[entries[i].ClientID]
+		if !ok {
+			continue
+		}
+		after[i] = balance
+		balances[entries[i].ClientID] = balance - entries[i].Points
+	}
+
+	for i, entry := range entries {
+		if _, ok := balances[entry.ClientID]; !ok {
+			continue
+		}
+		publishEvent(producer, loyaltyEventsTopic, LoyaltyEvent{
+			Event:   loyaltyEventTypes[entry.Kind],
+			Data:    entry,
+			Balance: after[i],
+		})
+	}
+}
+
+const feedbackEventsTopic = "feedback_events"
+
+// SurveyEvent - событие опроса: survey_issued для рассылки ссылки клиенту
+// и feedback_received с ответом клиента. BranchID - филиал, где был визит.
+type SurveyEvent struct {
+	Event    string        `json:"event"`
+	Data     models.Survey `json:"data"`
+	BranchID uint          `json:"branch_id"`
+}
+
+// publishSurveyEvent отправляет событие опроса в топик feedback_events
+func publishSurveyEvent(producer utils.KafkaProducer, eventType string, survey *models.Survey, branchID uint) {
+	event := SurveyEvent{Event: eventType, Data: *survey, BranchID: branchID}
+	event.Data.Appointment = nil
+	publishEvent(producer, feedbackEventsTopic, event)
+}
+
+const stockEventsTopic = "stock_events"
+
+// StockEvent - оповещение stock_low: движение Data опустило остаток товара в филиале до порога закупки
+type StockEvent struct {
+	Event   string               `json:"event"`
+	Data    models.StockMovement `json:"data"`
+	Product models.Product       `json:"product"`
+}
+
+// publishLowStockEvents отправляет в топик stock_events оповещения по движениям, после которых
+// остаток товара впервые опустился до его порога; products - товары движений по id
+func publishLowStockEvents(producer utils.KafkaProducer, products map[uint]models.Product, movements []models.StockMovement) {
+	for _, movement := range movements {
+		product, ok := products[movement.ProductID]
+		if !ok || !product.Active || !movement.ReachedLowStock(product.LowStockThreshold) {
+			continue
+		}
+		publishEvent(producer, stockEventsTopic, StockEvent{Event: "stock_low", Data: movement, Product: product})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+)
+
+// fakeProducer запоминает отправленные сообщения
+type fakeProducer struct {
+	utils.KafkaProducer
+	messages [][]byte
+}
+
+func (f *fakeProducer) SendMessage(ctx context.Context, topic string, key, value []byte) error {
+	f.messages = append(f.messages, value)
+	return nil
+}
+
+// fakeLoyalty отдает текущий баланс клиента
+type fakeLoyalty struct {
+	models.LoyaltyRepository
+	points int64
+}
+
+func (f *fakeLoyalty) GetLoyaltyBalance(clientID uint, now time.Time) (*models.LoyaltyBalance, error) {
+	return &models.LoyaltyBalance{Points: f.points}, nil
+}
+
+func TestPublishLoyaltyEventsRunningBalance(t *testing.T) {
+	producer := &fakeProducer{}
+	// Возврат баллов, затем отмена начисленных: текущий баланс уже учитывает обе записи
+	entries := []models.LoyaltyEntry{
+		{ClientID: 1, Kind: models.LoyaltyEntryRefund, Points: 300},
+		{ClientID: 1, Kind: models.LoyaltyEntryReversal, Points: -50},
+	}
+
+	publishLoyaltyEvents(producer, &fakeLoyalty{points: 1250}, entries)
+
+	want := []int64{1300, 1250}
+	if len(producer.messages) != len(want) {
+		t.Fatalf("got %d events, want %d", len(producer.messages), len(want))
+	}
+	for i, message := range producer.messages {
+		var event LoyaltyEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if event.Balance != want[i] {
+			t.Errorf("event %d balance = %d, want %d", i, event.Balance, want[i])
+		}
+	}
+}
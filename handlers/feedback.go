@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedbackHandler struct {
+	repo         models.FeedbackRepository
+	appointments models.AppointmentRepository
+	kafka        utils.KafkaProducer
+}
+
+func NewFeedbackHandler(repo models.FeedbackRepository, appointments models.AppointmentRepository, kafka utils.KafkaProducer) *FeedbackHandler {
+	return &FeedbackHandler{
+		repo:         repo,
+		appointments: appointments,
+		kafka:        kafka,
+	}
+}
+
+// FeedbackRequest - ответ клиента по публичной ссылке. Recommend - готовность рекомендовать центр
+// от 0 до 10; указатель, потому что 0 - допустимый ответ.
+type FeedbackRequest struct {
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+	Recommend *int   `json:"recommend" binding:"required,min=0,max=10"`
+	Comment   string `json:"comment" binding:"max=2000"`
+}
+
+type ListFeedbackQuery struct {
+	PageQuery
+	ClientID     uint `form:"client_id"`
+	SpecialistID uint `form:"specialist_id"`
+	BranchID     uint `form:"branch_id"`
+}
+
+type SurveyResponse struct {
+	ID            uint              `json:"id"`
+	AppointmentID uint              `json:"appointment_id"`
+	ClientID      uint              `json:"client_id"`
+	SpecialistID  uint              `json:"specialist_id"`
+	Token         string            `json:"token"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Response      *FeedbackResponse `json:"response"`
+}
+
+// PublicSurveyResponse - то, что видит клиент по ссылке; внутренних идентификаторов в нем нет
+type PublicSurveyResponse struct {
+	SpecialistName string    `json:"specialist_name"`
+	VisitedAt      time.Time `json:"visited_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Expired        bool      `json:"expired"`
+	Submitted      bool      `json:"submitted"`
+}
+
+type FeedbackResponse struct {
+	ID           uint      `json:"id"`
+	SurveyID     uint      `json:"survey_id"`
+	ClientID     uint      `json:"client_id"`
+	SpecialistID uint      `json:"specialist_id"`
+	Rating       int       `json:"rating"`
+	Recommend    int       `json:"recommend"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type SpecialistRatingResponse struct {
+	SpecialistID  uint    `json:"specialist_id"`
+	FullName      string  `json:"full_name"`
+	Responses     int64   `json:"responses"`
+	AverageRating float64 `json:"average_rating"`
+}
+
+// IssueSurvey выдает опрос по завершенному визиту. Если опрос уже есть, возвращается он, а ссылка
+// отправляется клиенту повторно, пока на опрос не ответили и срок его не истек.
+func (h *FeedbackHandler) IssueSurvey(c *gin.Context) {
+	appointmentID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment ID format"})
+		return
+	}
+
+	appointment, err := h.appointments.GetAppointmentByID(appointmentID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "appointment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if appointment.Status != models.AppointmentStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "survey can be issued only for a completed appointment"})
+		return
+	}
+
+	survey, created, err := issueSurvey(h.repo, h.kafka, appointment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	} else if h.kafka != nil && survey.Response == nil && !survey.Expired(time.Now()) {
+		go publishSurveyEvent(h.kafka, "survey_issued", survey, appointment.BranchID)
+	}
+	c.JSON(status, toSurveyResponse(survey))
+}
+
+func (h *FeedbackHandler) GetAppointmentSurvey(c *gin.Context) {
+	appointmentID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment ID format"})
+		return
+	}
+
+	survey, err := h.repo.GetSurveyByAppointment(appointmentID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "survey not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSurveyResponse(survey))
+}
+
+// GetPublicSurvey показывает клиенту опрос по ссылке без авторизации
+func (h *FeedbackHandler) GetPublicSurvey(c *gin.Context) {
+	survey, ok := h.loadSurveyByToken(c)
+	if !ok {
+		return
+	}
+
+	resp := PublicSurveyResponse{
+		ExpiresAt: survey.ExpiresAt,
+		Expired:   survey.Expired(time.Now()),
+		Submitted: survey.Response != nil,
+	}
+	if survey.Specialist != nil {
+		resp.SpecialistName = survey.Specialist.FullName
+	}
+	if survey.Appointment != nil {
+		resp.VisitedAt = survey.Appointment.StartsAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SubmitFeedback принимает ответ клиента по ссылке; ответить можно один раз, пока ссылка действует
+func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) {
+	var req FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	survey, ok := h.loadSurveyByToken(c)
+	if !ok {
+		return
+	}
+	if survey.Response != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "feedback has already been submitted"})
+		return
+	}
+
+	response := &models.FeedbackResponse{
+		Rating:    req.Rating,
+		Recommend: *req.Recommend,
+		Comment:   req.Comment,
+	}
+	if err := h.repo.SubmitFeedback(survey, response, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, models.ErrSurveyExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "survey link has expired"})
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "feedback has already been submitted"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if h.kafka != nil {
+		go publishSurveyEvent(h.kafka, "feedback_received", survey, survey.Appointment.BranchID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "thank you for your feedback"})
+}
+
+func (h *FeedbackHandler) ListFeedback(c *gin.Context) {
+	var query ListFeedbackQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page := query.PageQuery.normalize()
+	responses, total, err := h.repo.ListFeedback(models.FeedbackFilter{
+		ClientID:     query.ClientID,
+		SpecialistID: query.SpecialistID,
+		BranchID:     query.BranchID,
+		From:         from,
+		To:           to,
+		Limit:        page.PageSize,
+		Offset:       page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]FeedbackResponse, 0, len(responses))
+	for i := range responses {
+		items = append(items, toFeedbackResponse(&responses[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *FeedbackHandler) GetSpecialistRatings(c *gin.Context) {
+	from, to, ok := parseDateRange(c, true)
+	if !ok {
+		return
+	}
+	branchID, ok := parseBranchQuery(c)
+	if !ok {
+		return
+	}
+
+	ratings, err := h.repo.SpecialistRatings(from, to.AddDate(0, 0, 1), branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]SpecialistRatingResponse, 0, len(ratings))
+	for _, rating := range ratings {
+		items = append(items, SpecialistRatingResponse(rating))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format(dateLayout),
+		"to":          to.Format(dateLayout),
+		"specialists": items,
+	})
+}
+
+// GetNPSReport строит NPS за период по всему центру, по специалисту (specialist_id) или филиалу (branch_id)
+func (h *FeedbackHandler) GetNPSReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c, true)
+	if !ok {
+		return
+	}
+
+	var specialistID uint
+	if value := c.Query("specialist_id"); value != "" {
+		id, err := parseUint(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist_id"})
+			return
+		}
+		specialistID = id
+	}
+	branchID, ok := parseBranchQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.repo.NPSReport(from, to.AddDate(0, 0, 1), specialistID, branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format(dateLayout),
+		"to":            to.Format(dateLayout),
+		"specialist_id": specialistID,
+		"branch_id":     branchID,
+		"responses":     report.Responses,
+		"promoters":     report.Promoters,
+		"passives":      report.Passives,
+		"detractors":    report.Detractors,
+		"nps":           report.Score(),
+	})
+}
+
+// loadSurveyByToken загружает опрос по токену из пути; при ошибке ответ уже отправлен
+func (h *FeedbackHandler) loadSurveyByToken(c *gin.Context) (*models.Survey, bool) {
+	survey, err := h.repo.GetSurveyByToken(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "survey not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return survey, true
+}
+
+// issueSurvey выдает опрос по завершенному визиту и отправляет событие survey_issued,
+// по которому сервис уведомлений рассылает клиенту ссылку
+func issueSurvey(repo models.FeedbackRepository, kafka utils.KafkaProducer, appointment *models.Appointment) (*models.Survey, bool, error) {
+	token, err := newSurveyToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	survey, created, err := repo.IssueSurvey(&models.Survey{
+		AppointmentID: appointment.ID,
+		ClientID:      appointment.ClientID,
+		SpecialistID:  appointment.SpecialistID,
+		Token:         token,
+		ExpiresAt:     now.Add(models.SurveyTTL),
+		CreatedAt:     now,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if kafka != nil && created {
+		go publishSurveyEvent(kafka, "survey_issued", survey, appointment.BranchID)
+	}
+	return survey, created, nil
+}
+
+// newSurveyToken генерирует случайный токен публичной ссылки, пригодный для URL
+func newSurveyToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate survey token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func toSurveyResponse(survey *models.Survey) SurveyResponse {
+	resp := SurveyResponse{
+		ID:            survey.ID,
+		AppointmentID: survey.AppointmentID,
+		ClientID:      survey.ClientID,
+		SpecialistID:  survey.SpecialistID,
+		Token:         survey.Token,
+		ExpiresAt:     survey.ExpiresAt,
+		CreatedAt:     survey.CreatedAt,
+	}
+	if survey.Response != nil {
+		response := toFeedbackResponse(survey.Response)
+		resp.Response = &response
+	}
+	return resp
+}
+
+func toFeedbackResponse(response *models.FeedbackResponse) FeedbackResponse {
+	return FeedbackResponse{
+		ID:           response.ID,
+		SurveyID:     response.SurveyID,
+		ClientID:     response.ClientID,
+		SpecialistID: response.SpecialistID,
+		Rating:       response.Rating,
+		Recommend:    response.Recommend,
+		Comment:      response.Comment,
+		CreatedAt:    response.CreatedAt,
+	}
+}
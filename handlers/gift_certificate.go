@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GiftCertificateHandler struct {
+	repo    models.GiftCertificateRepository
+	clients models.Repository
+}
+
+func NewGiftCertificateHandler(repo models.GiftCertificateRepository, clients models.Repository) *GiftCertificateHandler {
+	return &GiftCertificateHandler{
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+// GiftCertificateRequest - выпуск сертификата на сумму (amount) или число сессий (sessions).
+// Покупатель и получатель задаются клиентом центра или именем. Без code код генерируется.
+type GiftCertificateRequest struct {
+	Code              string       `json:"code" binding:"omitempty,min=6,max=50,alphanum"`
+	Kind              string       `json:"kind" binding:"required,oneof=amount sessions"`
+	Amount            models.Money `json:"amount" binding:"min=0"`
+	Sessions          *int         `json:"sessions" binding:"omitempty,min=1,max=100"`
+	PurchaserClientID *uint        `json:"purchaser_client_id"`
+	PurchaserName     string       `json:"purchaser_name" binding:"max=200"`
+	RecipientClientID *uint        `json:"recipient_client_id"`
+	RecipientName     string       `json:"recipient_name" binding:"max=200"`
+	ExpiresAt         string       `json:"expires_at" binding:"required,datetime=2006-01-02"`
+	Comment           string       `json:"comment" binding:"max=500"`
+}
+
+type ListGiftCertificatesQuery struct {
+	PageQuery
+	Code     string `form:"code"`
+	ClientID uint   `form:"client_id"`
+}
+
+type GiftCertificateUsageResponse struct {
+	InvoiceID uint         `json:"invoice_id"`
+	PaymentID uint         `json:"payment_id"`
+	Amount    models.Money `json:"amount"`
+	Sessions  int          `json:"sessions"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type GiftCertificateResponse struct {
+	ID                uint                           `json:"id"`
+	Code              string                         `json:"code"`
+	Kind              string                         `json:"kind"`
+	Amount            models.Money                   `json:"amount"`
+	Balance           models.Money                   `json:"balance"`
+	Sessions          *int                           `json:"sessions"`
+	RemainingSessions *int                           `json:"remaining_sessions"`
+	PurchaserClientID *uint                          `json:"purchaser_client_id"`
+	PurchaserName     string                         `json:"purchaser_name"`
+	RecipientClientID *uint                          `json:"recipient_client_id"`
+	RecipientName     string                         `json:"recipient_name"`
+	ExpiresAt         string                         `json:"expires_at"`
+	Status            string                         `json:"status"`
+	Comment           string                         `json:"comment"`
+	Usages            []GiftCertificateUsageResponse `json:"usages,omitempty"`
+	CreatedAt         time.Time                      `json:"created_at"`
+}
+
+func (h *GiftCertificateHandler) CreateGiftCertificate(c *gin.Context) {
+	var req GiftCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case req.Kind == models.GiftCertificateKindAmount && (req.Amount <= 0 || req.Sessions != nil):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount certificate requires amount only"})
+		return
+	case req.Kind == models.GiftCertificateKindSessions && (req.Sessions == nil || req.Amount != 0):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sessions certificate requires sessions only"})
+		return
+	case req.PurchaserClientID == nil && req.PurchaserName == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purchaser_client_id or purchaser_name is required"})
+		return
+	}
+
+	expiresAt, _ := time.ParseInLocation(dateLayout, req.ExpiresAt, time.Local)
+	if expiresAt.Format(dateLayout) < time.Now().Format(dateLayout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must not be in the past"})
+		return
+	}
+
+	for _, id := range []*uint{req.PurchaserClientID, req.RecipientClientID} {
+		if id == nil {
+			continue
+		}
+		if _, err := h.clients.GetClientByID(*id); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	code := models.NormalizeCode(req.Code)
+	if code == "" {
+		generated, err := newGiftCertificateCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		code = generated
+	}
+
+	certificate := &models.GiftCertificate{
+		Code:              code,
+		Kind:              req.Kind,
+		Amount:            req.Amount,
+		Balance:           req.Amount,
+		PurchaserClientID: req.PurchaserClientID,
+		PurchaserName:     req.PurchaserName,
+		RecipientClientID: req.RecipientClientID,
+		RecipientName:     req.RecipientName,
+		ExpiresAt:         expiresAt,
+		Status:            models.GiftCertificateStatusActive,
+		Comment:           req.Comment,
+	}
+	if req.Sessions != nil {
+		total, remaining := *req.Sessions, *req.Sessions
+		certificate.Sessions = &total
+		certificate.RemainingSessions = &remaining
+	}
+
+	if err := h.repo.CreateGiftCertificate(certificate); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "gift certificate with this code already exists"})
+		case errors.Is(err, models.ErrInvalidReference):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toGiftCertificateResponse(certificate))
+}
+
+func (h *GiftCertificateHandler) ListGiftCertificates(c *gin.Context) {
+	var query ListGiftCertificatesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	certificates, total, err := h.repo.ListGiftCertificates(models.GiftCertificateFilter{
+		Code:     models.NormalizeCode(query.Code),
+		ClientID: query.ClientID,
+		Limit:    page.PageSize,
+		Offset:   page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]GiftCertificateResponse, 0, len(certificates))
+	for i := range certificates {
+		items = append(items, toGiftCertificateResponse(&certificates[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *GiftCertificateHandler) GetGiftCertificate(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid gift certificate ID format"})
+		return
+	}
+
+	certificate, err := h.repo.GetGiftCertificate(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "gift certificate not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toGiftCertificateResponse(certificate))
+}
+
+func (h *GiftCertificateHandler) CancelGiftCertificate(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid gift certificate ID format"})
+		return
+	}
+
+	certificate, err := h.repo.CancelGiftCertificate(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "gift certificate not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toGiftCertificateResponse(certificate))
+}
+
+// newGiftCertificateCode генерирует случайный код вида GC-XXXXXXXX
+func newGiftCertificateCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate gift certificate code: %w", err)
+	}
+	return "GC-" + base32.StdEncoding.EncodeToString(buf), nil
+}
+
+func toGiftCertificateResponse(certificate *models.GiftCertificate) GiftCertificateResponse {
+	resp := GiftCertificateResponse{
+		ID:                certificate.ID,
+		Code:              certificate.Code,
+		Kind:              certificate.Kind,
+		Amount:            certificate.Amount,
+		Balance:           certificate.Balance,
+		Sessions:          certificate.Sessions,
+		RemainingSessions: certificate.RemainingSessions,
+		PurchaserClientID: certificate.PurchaserClientID,
+		PurchaserName:     certificate.PurchaserName,
+		RecipientClientID: certificate.RecipientClientID,
+		RecipientName:     certificate.RecipientName,
+		ExpiresAt:         certificate.ExpiresAt.Format(dateLayout),
+		Status:            certificate.EffectiveStatus(time.Now()),
+		Comment:           certificate.Comment,
+		CreatedAt:         certificate.CreatedAt,
+	}
+	for _, usage := range certificate.Usages {
+		resp.Usages = append(resp.Usages, GiftCertificateUsageResponse{
+			InvoiceID: usage.InvoiceID,
+			PaymentID: usage.PaymentID,
+			Amount:    usage.Amount,
+			Sessions:  usage.Sessions,
+			CreatedAt: usage.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupClassHandler struct {
+	repo        models.GroupClassRepository
+	clients     models.Repository
+	specialists models.SpecialistRepository
+	consents    models.ConsentRepository
+	kafka       utils.KafkaProducer
+}
+
+func NewGroupClassHandler(repo models.GroupClassRepository, clients models.Repository, specialists models.SpecialistRepository, consents models.ConsentRepository, kafka utils.KafkaProducer) *GroupClassHandler {
+	return &GroupClassHandler{
+		repo:        repo,
+		clients:     clients,
+		specialists: specialists,
+		consents:    consents,
+		kafka:       kafka,
+	}
+}
+
+// GroupClassRequest - групповое занятие. Service - услуга для списания абонемента,
+// по умолчанию специализация инструктора.
+type GroupClassRequest struct {
+	Title        string    `json:"title" binding:"required,max=200"`
+	Service      string    `json:"service" binding:"max=100"`
+	SpecialistID uint      `json:"specialist_id" binding:"required"`
+	Room         string    `json:"room" binding:"max=100"`
+	StartsAt     time.Time `json:"starts_at" binding:"required"`
+	EndsAt       time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Capacity     int       `json:"capacity" binding:"required,min=1,max=500"`
+	Description  string    `json:"description" binding:"max=1000"`
+}
+
+type EnrollmentRequest struct {
+	ClientID uint `json:"client_id" binding:"required"`
+}
+
+// AttendanceRequest - отметка посещения. За посещение списывается сессия с абонемента:
+// указанного или подходящего автоматически.
+type AttendanceRequest struct {
+	Status    string `json:"status" binding:"required,oneof=attended no_show"`
+	PackageID *uint  `json:"package_id"`
+}
+
+type EnrollmentResponse struct {
+	ID        uint      `json:"id"`
+	ClientID  uint      `json:"client_id"`
+	Status    string    `json:"status"`
+	QueuedAt  time.Time `json:"queued_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GroupClassResponse struct {
+	ID            uint                 `json:"id"`
+	Title         string               `json:"title"`
+	Service       string               `json:"service"`
+	SpecialistID  uint                 `json:"specialist_id"`
+	BranchID      uint                 `json:"branch_id"`
+	Room          string               `json:"room"`
+	StartsAt      time.Time            `json:"starts_at"`
+	EndsAt        time.Time            `json:"ends_at"`
+	Capacity      int                  `json:"capacity"`
+	EnrolledCount int                  `json:"enrolled_count"`
+	FreeSeats     int                  `json:"free_seats"`
+	Status        string               `json:"status"`
+	Description   string               `json:"description"`
+	Enrollments   []EnrollmentResponse `json:"enrollments,omitempty"`
+}
+
+func (h *GroupClassHandler) CreateClass(c *gin.Context) {
+	var req GroupClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	instructor, ok := h.activeInstructor(c, req.SpecialistID)
+	if !ok {
+		return
+	}
+
+	class := &models.GroupClass{Status: models.GroupClassStatusScheduled, BranchID: instructor.BranchID}
+	applyGroupClassRequest(class, req)
+
+	if err := h.repo.CreateGroupClass(class); err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toGroupClassResponse(class))
+}
+
+func (h *GroupClassHandler) GetClass(c *gin.Context) {
+	class, ok := h.loadClass(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toGroupClassResponse(class))
+}
+
+type ListGroupClassesQuery struct {
+	PageQuery
+	SpecialistID uint   `form:"specialist_id"`
+	BranchID     uint   `form:"branch_id"`
+	Status       string `form:"status" binding:"omitempty,oneof=scheduled cancelled"`
+}
+
+func (h *GroupClassHandler) ListClasses(c *gin.Context) {
+	var query ListGroupClassesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page := query.PageQuery.normalize()
+	classes, total, err := h.repo.ListGroupClasses(models.GroupClassFilter{
+		SpecialistID: query.SpecialistID,
+		BranchID:     query.BranchID,
+		Status:       query.Status,
+		From:         from,
+		To:           to,
+		Limit:        page.PageSize,
+		Offset:       page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]GroupClassResponse, 0, len(classes))
+	for i := range classes {
+		items = append(items, toGroupClassResponse(&classes[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *GroupClassHandler) UpdateClass(c *gin.Context) {
+	var req GroupClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	class, ok := h.loadClass(c)
+	if !ok {
+		return
+	}
+
+	if class.Status != models.GroupClassStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": "cancelled class cannot be changed"})
+		return
+	}
+	if req.SpecialistID != class.SpecialistID {
+		instructor, ok := h.activeInstructor(c, req.SpecialistID)
+		if !ok {
+			return
+		}
+		class.BranchID = instructor.BranchID
+	}
+
+	applyGroupClassRequest(class, req)
+
+	promoted, err := h.repo.UpdateGroupClass(class)
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	if h.kafka != nil && len(promoted) > 0 {
+		go publishClassEvents(h.kafka, "class_waitlist_promoted", *class, promoted)
+	}
+
+	h.respondWithClass(c, class.ID)
+}
+
+// CancelClass отменяет занятие; записанные клиенты и лист ожидания получают уведомление
+func (h *GroupClassHandler) CancelClass(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid class ID format"})
+		return
+	}
+
+	class, cancelled, err := h.repo.CancelGroupClass(id)
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	if h.kafka != nil && len(cancelled) > 0 {
+		go publishClassEvents(h.kafka, "class_cancelled", *class, cancelled)
+	}
+
+	h.respondWithClass(c, class.ID)
+}
+
+// Enroll записывает клиента на занятие или, если мест нет, в лист ожидания
+func (h *GroupClassHandler) Enroll(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid class ID format"})
+		return
+	}
+
+	var req EnrollmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.clients.GetClientByID(req.ClientID); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	class, err := h.repo.GetGroupClass(id)
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+	service := class.Service
+	if service == "" {
+		specialist, err := h.specialists.GetSpecialistByID(class.SpecialistID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		service = specialist.Specialization
+	}
+	if !ensureConsents(c, h.consents, req.ClientID, service, class.StartsAt) {
+		return
+	}
+
+	enrollment, err := h.repo.EnrollClient(id, req.ClientID, time.Now())
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toEnrollmentResponse(enrollment))
+}
+
+// CancelEnrollment отменяет запись клиента; освободившееся место получает первый из листа ожидания
+func (h *GroupClassHandler) CancelEnrollment(c *gin.Context) {
+	id, clientID, ok := parseEnrollmentPath(c)
+	if !ok {
+		return
+	}
+
+	enrollment, promoted, err := h.repo.CancelEnrollment(id, clientID, time.Now())
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	if h.kafka != nil && len(promoted) > 0 {
+		if class, err := h.repo.GetGroupClass(id); err == nil {
+			go publishClassEvents(h.kafka, "class_waitlist_promoted", *class, promoted)
+		}
+	}
+
+	promotedResp := make([]EnrollmentResponse, 0, len(promoted))
+	for i := range promoted {
+		promotedResp = append(promotedResp, toEnrollmentResponse(&promoted[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enrollment": toEnrollmentResponse(enrollment),
+		"promoted":   promotedResp,
+	})
+}
+
+// MarkAttendance отмечает посещение или неявку клиента
+func (h *GroupClassHandler) MarkAttendance(c *gin.Context) {
+	id, clientID, ok := parseEnrollmentPath(c)
+	if !ok {
+		return
+	}
+
+	var req AttendanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, pkg, debit, err := h.repo.MarkAttendance(id, clientID, req.Status, req.PackageID, time.Now())
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+
+	if h.kafka != nil && debit != nil {
+		go publishPackageDebitEvents(h.kafka, *pkg, *debit)
+	}
+
+	resp := gin.H{"enrollment": toEnrollmentResponse(enrollment), "package_debit": nil}
+	if debit != nil {
+		resp["package_debit"] = AppointmentDebitResponse{
+			PackageID:         pkg.ID,
+			Sessions:          debit.Sessions,
+			RemainingSessions: pkg.RemainingSessions,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *GroupClassHandler) respondWithClass(c *gin.Context, id uint) {
+	class, err := h.repo.GetGroupClass(id)
+	if err != nil {
+		respondGroupClassError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toGroupClassResponse(class))
+}
+
+func (h *GroupClassHandler) loadClass(c *gin.Context) (*models.GroupClass, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid class ID format"})
+		return nil, false
+	}
+
+	class, err := h.repo.GetGroupClass(id)
+	if err != nil {
+		respondGroupClassError(c, err)
+		return nil, false
+	}
+	return class, true
+}
+
+// activeInstructor загружает инструктора и проверяет, что он принимает клиентов
+func (h *GroupClassHandler) activeInstructor(c *gin.Context, id uint) (*models.Specialist, bool) {
+	specialist, err := h.specialists.GetSpecialistByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if specialist.Status != models.SpecialistStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "specialist is not active"})
+		return nil, false
+	}
+	return specialist, true
+}
+
+func parseEnrollmentPath(c *gin.Context) (uint, uint, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid class ID format"})
+		return 0, 0, false
+	}
+	clientID, err := parseUint(c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return 0, 0, false
+	}
+	return id, clientID, true
+}
+
+func respondGroupClassError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "group class not found"})
+	case errors.Is(err, models.ErrNotEnrolled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrOverlap),
+		errors.Is(err, models.ErrClassClosed),
+		errors.Is(err, models.ErrClassNotStarted),
+		errors.Is(err, models.ErrAlreadyEnrolled),
+		errors.Is(err, models.ErrCapacityTooLow):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNoPackage):
+		c.JSON(http.StatusConflict, gin.H{"error": "selected package cannot cover this class"})
+	case errors.Is(err, models.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client or specialist not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func applyGroupClassRequest(class *models.GroupClass, req GroupClassRequest) {
+	class.Title = req.Title
+	class.Service = req.Service
+	class.SpecialistID = req.SpecialistID
+	class.Room = req.Room
+	class.StartsAt = req.StartsAt
+	class.EndsAt = req.EndsAt
+	class.Capacity = req.Capacity
+	class.Description = req.Description
+}
+
+func toEnrollmentResponse(enrollment *models.ClassEnrollment) EnrollmentResponse {
+	return EnrollmentResponse{
+		ID:        enrollment.ID,
+		ClientID:  enrollment.ClientID,
+		Status:    enrollment.Status,
+		QueuedAt:  enrollment.QueuedAt,
+		UpdatedAt: enrollment.UpdatedAt,
+	}
+}
+
+func toGroupClassResponse(class *models.GroupClass) GroupClassResponse {
+	resp := GroupClassResponse{
+		ID:            class.ID,
+		Title:         class.Title,
+		Service:       class.Service,
+		SpecialistID:  class.SpecialistID,
+		BranchID:      class.BranchID,
+		Room:          class.Room,
+		StartsAt:      class.StartsAt,
+		EndsAt:        class.EndsAt,
+		Capacity:      class.Capacity,
+		EnrolledCount: class.EnrolledCount,
+		FreeSeats:     class.FreeSeats(),
+		Status:        class.Status,
+		Description:   class.Description,
+	}
+	for i := range class.Enrollments {
+		resp.Enrollments = append(resp.Enrollments, toEnrollmentResponse(&class.Enrollments[i]))
+	}
+	return resp
+}
@@ -0,0 +1,647 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandler struct {
+	repo          models.InvoiceRepository
+	clients       models.Repository
+	appointments  models.AppointmentRepository
+	packages      models.PackageRepository
+	organizations models.OrganizationRepository
+	referrals     models.ReferralRepository
+	loyalty       models.LoyaltyRepository
+	products      models.ProductRepository
+	branches      models.BranchRepository
+	kafka         utils.KafkaProducer
+}
+
+func NewInvoiceHandler(repo models.InvoiceRepository, clients models.Repository, appointments models.AppointmentRepository, packages models.PackageRepository, organizations models.OrganizationRepository, referrals models.ReferralRepository, loyalty models.LoyaltyRepository, products models.ProductRepository, branches models.BranchRepository, kafka utils.KafkaProducer) *InvoiceHandler {
+	return &InvoiceHandler{
+		repo:          repo,
+		clients:       clients,
+		appointments:  appointments,
+		packages:      packages,
+		organizations: organizations,
+		referrals:     referrals,
+		loyalty:       loyalty,
+		products:      products,
+		branches:      branches,
+		kafka:         kafka,
+	}
+}
+
+// InvoiceLineRequest - строка счета; может ссылаться на визит или абонемент клиента либо на товар.
+// Для товара без description и unit_price берутся название и цена из каталога.
+type InvoiceLineRequest struct {
+	Description     string       `json:"description" binding:"required_without=ProductID,max=300"`
+	Quantity        int          `json:"quantity" binding:"required,min=1,max=1000"`
+	UnitPrice       models.Money `json:"unit_price" binding:"min=0"`
+	DiscountPercent int          `json:"discount_percent" binding:"min=0,max=100"`
+	AppointmentID   *uint        `json:"appointment_id"`
+	PackageID       *uint        `json:"package_id"`
+	ProductID       *uint        `json:"product_id"`
+}
+
+// InvoiceRequest - новый счет. Плательщиком, отличным от клиента, может быть связанный с ним
+// опекун или родственник (payer_client_id) либо организация-спонсор (payer_organization_id).
+// promo_code дает скидку по правилам кода, gift_certificate_code сразу оплачивает счет сертификатом.
+// Товары списываются со склада филиала branch_id, по умолчанию - основного.
+type InvoiceRequest struct {
+	ClientID            uint                 `json:"client_id" binding:"required"`
+	PayerClientID       *uint                `json:"payer_client_id"`
+	PayerOrganizationID *uint                `json:"payer_organization_id"`
+	Lines               []InvoiceLineRequest `json:"lines" binding:"required,min=1,dive"`
+	BranchID            uint                 `json:"branch_id"`
+	Discount            models.Money         `json:"discount" binding:"min=0"`
+	PromoCode           string               `json:"promo_code" binding:"max=50"`
+	GiftCertificateCode string               `json:"gift_certificate_code" binding:"max=50"`
+	Comment             string               `json:"comment" binding:"max=1000"`
+}
+
+// PaymentRequest - оплата или возврат по счету. Способы loyalty и gift_certificate допустимы только
+// для возврата: он возвращает баллы клиенту или остаток на сертификат, которыми был оплачен счет.
+type PaymentRequest struct {
+	Method  string       `json:"method" binding:"required,oneof=cash card transfer loyalty gift_certificate"`
+	Amount  models.Money `json:"amount" binding:"required,gt=0"`
+	PaidAt  *time.Time   `json:"paid_at"`
+	Comment string       `json:"comment" binding:"max=500"`
+}
+
+type InvoiceLineResponse struct {
+	ID              uint         `json:"id"`
+	Description     string       `json:"description"`
+	Quantity        int          `json:"quantity"`
+	UnitPrice       models.Money `json:"unit_price"`
+	DiscountPercent int          `json:"discount_percent"`
+	Total           models.Money `json:"total"`
+	AppointmentID   *uint        `json:"appointment_id"`
+	PackageID       *uint        `json:"package_id"`
+	ProductID       *uint        `json:"product_id"`
+	BranchID        *uint        `json:"branch_id"`
+}
+
+type PaymentResponse struct {
+	ID      uint         `json:"id"`
+	Kind    string       `json:"kind"`
+	Method  string       `json:"method"`
+	Amount  models.Money `json:"amount"`
+	PaidAt  time.Time    `json:"paid_at"`
+	Comment string       `json:"comment,omitempty"`
+}
+
+type InvoiceResponse struct {
+	ID                  uint                  `json:"id"`
+	Number              string                `json:"number"`
+	ClientID            uint                  `json:"client_id"`
+	PayerClientID       *uint                 `json:"payer_client_id"`
+	PayerOrganizationID *uint                 `json:"payer_organization_id"`
+	Status              string                `json:"status"`
+	Subtotal            models.Money          `json:"subtotal"`
+	Discount            models.Money          `json:"discount"`
+	PromoCodeID         *uint                 `json:"promo_code_id"`
+	PromoDiscount       models.Money          `json:"promo_discount"`
+	Total               models.Money          `json:"total"`
+	PaidTotal           models.Money          `json:"paid_total"`
+	Outstanding         models.Money          `json:"outstanding"`
+	IssuedAt            time.Time             `json:"issued_at"`
+	Comment             string                `json:"comment"`
+	Lines               []InvoiceLineResponse `json:"lines,omitempty"`
+	Payments            []PaymentResponse     `json:"payments,omitempty"`
+}
+
+func (h *InvoiceHandler) CreateInvoice(c *gin.Context) {
+	var req InvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.clients.GetClientByID(req.ClientID); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validatePayer(c, req) {
+		return
+	}
+
+	invoice := &models.Invoice{
+		ClientID:            req.ClientID,
+		PayerClientID:       req.PayerClientID,
+		PayerOrganizationID: req.PayerOrganizationID,
+		Discount:            req.Discount,
+		Comment:             req.Comment,
+	}
+	products := map[uint]models.Product{}
+	var branch *models.Branch
+	for _, line := range req.Lines {
+		if !h.validateLineReference(c, req.ClientID, line) {
+			return
+		}
+		invoiceLine := models.InvoiceLine{
+			AppointmentID:   line.AppointmentID,
+			PackageID:       line.PackageID,
+			Description:     line.Description,
+			Quantity:        line.Quantity,
+			UnitPrice:       line.UnitPrice,
+			DiscountPercent: line.DiscountPercent,
+		}
+		if line.ProductID != nil {
+			product, ok := h.saleProduct(c, *line.ProductID)
+			if !ok {
+				return
+			}
+			if branch == nil {
+				if branch, ok = resolveBranch(c, h.branches, req.BranchID); !ok {
+					return
+				}
+			}
+			products[product.ID] = *product
+			invoiceLine.ProductID = &product.ID
+			invoiceLine.BranchID = &branch.ID
+			if invoiceLine.Description == "" {
+				invoiceLine.Description = product.Name
+			}
+			if invoiceLine.UnitPrice == 0 {
+				invoiceLine.UnitPrice = product.Price
+			}
+		}
+		invoice.Lines = append(invoice.Lines, invoiceLine)
+	}
+
+	codes := models.InvoiceCodes{
+		PromoCode:           models.NormalizeCode(req.PromoCode),
+		GiftCertificateCode: models.NormalizeCode(req.GiftCertificateCode),
+	}
+	if err := h.repo.CreateInvoice(invoice, codes); err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	invoicePaid(h.referrals, h.loyalty, h.kafka, invoice, invoice.IssuedAt)
+	if h.kafka != nil && len(products) > 0 {
+		go h.publishLowStock(invoice.ID, products)
+	}
+
+	c.JSON(http.StatusCreated, toInvoiceResponse(invoice))
+}
+
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice ID format"})
+		return
+	}
+
+	invoice, err := h.repo.GetInvoice(id)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toInvoiceResponse(invoice))
+}
+
+type ListInvoicesQuery struct {
+	PageQuery
+	ClientID            uint   `form:"client_id"`
+	PayerClientID       uint   `form:"payer_client_id"`
+	PayerOrganizationID uint   `form:"payer_organization_id"`
+	Status              string `form:"status" binding:"omitempty,oneof=issued partially_paid paid cancelled"`
+	BranchID            uint   `form:"branch_id"`
+}
+
+func (h *InvoiceHandler) ListInvoices(c *gin.Context) {
+	var query ListInvoicesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page := query.PageQuery.normalize()
+	invoices, total, err := h.repo.ListInvoices(models.InvoiceFilter{
+		ClientID:            query.ClientID,
+		PayerClientID:       query.PayerClientID,
+		PayerOrganizationID: query.PayerOrganizationID,
+		Status:              query.Status,
+		BranchID:            query.BranchID,
+		From:                from,
+		To:                  to,
+		Limit:               page.PageSize,
+		Offset:              page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]InvoiceResponse, 0, len(invoices))
+	for i := range invoices {
+		items = append(items, toInvoiceResponse(&invoices[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *InvoiceHandler) CancelInvoice(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice ID format"})
+		return
+	}
+
+	invoice, err := h.repo.CancelInvoice(id)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toInvoiceResponse(invoice))
+}
+
+func (h *InvoiceHandler) AddPayment(c *gin.Context) {
+	h.addPayment(c, models.PaymentKindPayment)
+}
+
+// AddRefund возвращает оплату тем способом, которым она была внесена: деньги - деньгами, баллы - баллами,
+// оплату сертификатом - на сертификат
+func (h *InvoiceHandler) AddRefund(c *gin.Context) {
+	h.addPayment(c, models.PaymentKindRefund)
+}
+
+func (h *InvoiceHandler) addPayment(c *gin.Context, kind string) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice ID format"})
+		return
+	}
+
+	var req PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if kind == models.PaymentKindPayment && (req.Method == models.PaymentMethodLoyalty || req.Method == models.PaymentMethodGiftCertificate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "loyalty points and gift certificates cannot be used as a payment method here"})
+		return
+	}
+
+	payment := &models.Payment{
+		Kind:    kind,
+		Method:  req.Method,
+		Amount:  req.Amount,
+		Comment: req.Comment,
+	}
+	if req.PaidAt != nil {
+		payment.PaidAt = *req.PaidAt
+	}
+
+	var (
+		invoice *models.Invoice
+		entries []models.LoyaltyEntry
+	)
+	if kind == models.PaymentKindRefund {
+		invoice, entries, err = h.repo.AddRefund(id, payment)
+	} else {
+		invoice, err = h.repo.AddPayment(id, payment)
+	}
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	if kind == models.PaymentKindPayment {
+		invoicePaid(h.referrals, h.loyalty, h.kafka, invoice, payment.PaidAt)
+	}
+	if h.kafka != nil && len(entries) > 0 {
+		go publishLoyaltyEvents(h.kafka, h.loyalty, entries)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"payment": toPaymentResponse(payment),
+		"invoice": toInvoiceResponse(invoice),
+	})
+}
+
+// GetClientBalance возвращает сумму счетов, которые оплачивает клиент (свои и подопечных), оплат и задолженность
+func (h *InvoiceHandler) GetClientBalance(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	balance, err := h.repo.GetClientBalance(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":   clientID,
+		"invoiced":    balance.Invoiced,
+		"paid":        balance.Paid,
+		"outstanding": balance.Outstanding,
+	})
+}
+
+// GetCashReport - кассовый отчет за день: поступления и возвраты по способам оплаты, по всему центру или филиалу
+func (h *InvoiceHandler) GetCashReport(c *gin.Context) {
+	branchID, ok := parseBranchQuery(c)
+	if !ok {
+		return
+	}
+
+	day := time.Now()
+	if v := c.Query("date"); v != "" {
+		parsed, err := time.ParseInLocation(dateLayout, v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+
+	rows, err := h.repo.GetCashReport(from, from.AddDate(0, 0, 1), branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type methodTotals struct {
+		Payments models.Money `json:"payments"`
+		Refunds  models.Money `json:"refunds"`
+		Net      models.Money `json:"net"`
+		Count    int64        `json:"count"`
+	}
+
+	var total methodTotals
+	byMethod := map[string]*methodTotals{}
+	for _, method := range []string{models.PaymentMethodCash, models.PaymentMethodCard, models.PaymentMethodTransfer} {
+		byMethod[method] = &methodTotals{}
+	}
+
+	for _, row := range rows {
+		totals, ok := byMethod[row.Method]
+		if !ok {
+			totals = &methodTotals{}
+			byMethod[row.Method] = totals
+		}
+		for _, t := range []*methodTotals{totals, &total} {
+			if row.Kind == models.PaymentKindRefund {
+				t.Refunds += row.Amount
+				t.Net -= row.Amount
+			} else {
+				t.Payments += row.Amount
+				t.Net += row.Amount
+			}
+			t.Count += row.Count
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":      from.Format(dateLayout),
+		"branch_id": branchID,
+		"by_method": byMethod,
+		"total":     total,
+	})
+}
+
+// GetOrganizationBalance возвращает сумму счетов, выставленных организации за сотрудников, оплат и задолженность
+func (h *InvoiceHandler) GetOrganizationBalance(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID format"})
+		return
+	}
+
+	if _, err := h.organizations.GetOrganization(id); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	balance, err := h.repo.GetOrganizationBalance(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization_id": id,
+		"invoiced":        balance.Invoiced,
+		"paid":            balance.Paid,
+		"outstanding":     balance.Outstanding,
+	})
+}
+
+// validatePayer проверяет, что сторонний плательщик связан с клиентом
+func (h *InvoiceHandler) validatePayer(c *gin.Context, req InvoiceRequest) bool {
+	if req.PayerClientID == nil && req.PayerOrganizationID == nil {
+		return true
+	}
+	if req.PayerClientID != nil && req.PayerOrganizationID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invoice can have either a payer client or a payer organization"})
+		return false
+	}
+	if req.PayerClientID != nil && *req.PayerClientID == req.ClientID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payer_client_id must differ from client_id"})
+		return false
+	}
+
+	allowed, err := h.organizations.CanBill(req.ClientID, req.PayerClientID, req.PayerOrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payer is not linked to the client as guardian, family member or corporate sponsor"})
+		return false
+	}
+	return true
+}
+
+// validateLineReference проверяет, что визит или абонемент из строки счета принадлежит клиенту
+func (h *InvoiceHandler) validateLineReference(c *gin.Context, clientID uint, line InvoiceLineRequest) bool {
+	references := 0
+	for _, id := range []*uint{line.AppointmentID, line.PackageID, line.ProductID} {
+		if id != nil {
+			references++
+		}
+	}
+	if references > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invoice line can reference only one of an appointment, a package or a product"})
+		return false
+	}
+
+	if line.AppointmentID != nil {
+		appointment, err := h.appointments.GetAppointmentByID(*line.AppointmentID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "appointment not found"})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		if appointment.ClientID != clientID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "appointment belongs to another client"})
+			return false
+		}
+		if !appointment.IsBillable() {
+			c.JSON(http.StatusConflict, gin.H{"error": appointment.Status + " appointment cannot be invoiced"})
+			return false
+		}
+	}
+
+	if line.PackageID != nil {
+		if _, err := h.packages.GetPackage(clientID, *line.PackageID); err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "package not found"})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// saleProduct загружает товар для строки счета; снятый с продажи товар не продается. При ошибке ответ уже отправлен.
+func (h *InvoiceHandler) saleProduct(c *gin.Context, id uint) (*models.Product, bool) {
+	product, err := h.products.GetProduct(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !product.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product is not for sale"})
+		return nil, false
+	}
+	return product, true
+}
+
+// publishLowStock оповещает о товарах, остаток которых опустился до порога после продажи по счету
+func (h *InvoiceHandler) publishLowStock(invoiceID uint, products map[uint]models.Product) {
+	sales, _, err := h.products.ListStockMovements(models.StockMovementFilter{InvoiceID: invoiceID, Kind: models.StockMovementSale, Limit: -1})
+	if err != nil {
+		log.Printf("Failed to load stock movements of invoice %d: %v", invoiceID, err)
+		return
+	}
+	publishLowStockEvents(h.kafka, products, sales)
+}
+
+// invoicePaid выполняет действия после полной оплаты счета: первый оплаченный счет приглашенного
+// клиента дает право на реферальную награду, а за оплату начисляются баллы лояльности.
+// Через него проходит любой переход счета в paid - деньгами, баллами или при создании.
+func invoicePaid(referrals models.ReferralRepository, loyalty models.LoyaltyRepository, kafka utils.KafkaProducer, invoice *models.Invoice, at time.Time) {
+	// Счет с нулевой суммой (полная скидка) закрыт сразу, но ничего не оплачено, и наград за него нет
+	if invoice.Status != models.InvoiceStatusPaid || invoice.Total <= 0 {
+		return
+	}
+	if _, err := referrals.QualifyReferral(invoice.ClientID, invoice.ID, at); err != nil {
+		log.Printf("Failed to qualify referral for client %d: %v", invoice.ClientID, err)
+	}
+	accrueLoyaltyPoints(loyalty, kafka, invoice, at)
+}
+
+func respondInvoiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invoice not found"})
+	case errors.Is(err, models.ErrAlreadyInvoiced),
+		errors.Is(err, models.ErrInvoiceCancelled),
+		errors.Is(err, models.ErrInvoiceHasPayments),
+		errors.Is(err, models.ErrOverpayment),
+		errors.Is(err, models.ErrRefundExceedsPaid),
+		errors.Is(err, models.ErrInsufficientPoints),
+		errors.Is(err, models.ErrPromoNotApplicable),
+		errors.Is(err, models.ErrPromoExhausted),
+		errors.Is(err, models.ErrCertificateNotApplicable),
+		errors.Is(err, models.ErrInsufficientStock),
+		errors.Is(err, models.ErrAppointmentStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUnknownCode), errors.Is(err, models.ErrFractionalPoints):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "referenced client, appointment, package, product or branch not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func toInvoiceResponse(invoice *models.Invoice) InvoiceResponse {
+	resp := InvoiceResponse{
+		ID:                  invoice.ID,
+		Number:              invoice.Number,
+		ClientID:            invoice.ClientID,
+		PayerClientID:       invoice.PayerClientID,
+		PayerOrganizationID: invoice.PayerOrganizationID,
+		Status:              invoice.Status,
+		Subtotal:            invoice.Subtotal,
+		Discount:            invoice.Discount,
+		PromoCodeID:         invoice.PromoCodeID,
+		PromoDiscount:       invoice.PromoDiscount,
+		Total:               invoice.Total,
+		PaidTotal:           invoice.PaidTotal,
+		Outstanding:         invoice.Outstanding(),
+		IssuedAt:            invoice.IssuedAt,
+		Comment:             invoice.Comment,
+	}
+	for _, line := range invoice.Lines {
+		resp.Lines = append(resp.Lines, InvoiceLineResponse{
+			ID:              line.ID,
+			Description:     line.Description,
+			Quantity:        line.Quantity,
+			UnitPrice:       line.UnitPrice,
+			DiscountPercent: line.DiscountPercent,
+			Total:           line.Total,
+			AppointmentID:   line.AppointmentID,
+			PackageID:       line.PackageID,
+			ProductID:       line.ProductID,
+			BranchID:        line.BranchID,
+		})
+	}
+	for i := range invoice.Payments {
+		resp.Payments = append(resp.Payments, toPaymentResponse(&invoice.Payments[i]))
+	}
+	return resp
+}
+
+func toPaymentResponse(payment *models.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:      payment.ID,
+		Kind:    payment.Kind,
+		Method:  payment.Method,
+		Amount:  payment.Amount,
+		PaidAt:  payment.PaidAt,
+		Comment: payment.Comment,
+	}
+}
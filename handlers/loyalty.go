@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	repo      models.LoyaltyRepository
+	clients   models.Repository
+	referrals models.ReferralRepository
+	kafka     utils.KafkaProducer
+}
+
+func NewLoyaltyHandler(repo models.LoyaltyRepository, clients models.Repository, referrals models.ReferralRepository, kafka utils.KafkaProducer) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		repo:      repo,
+		clients:   clients,
+		referrals: referrals,
+		kafka:     kafka,
+	}
+}
+
+// LoyaltyRuleRequest - правило начисления: percent процентов от оплаченной суммы строк счета
+// (applies_to: all, appointments, packages или products) баллами, срок жизни баллов expiry_days (0 - бессрочно)
+type LoyaltyRuleRequest struct {
+	Name            string       `json:"name" binding:"required,max=200"`
+	AppliesTo       string       `json:"applies_to" binding:"omitempty,oneof=all appointments packages products"`
+	Percent         int          `json:"percent" binding:"required,min=1,max=100"`
+	MinInvoiceTotal models.Money `json:"min_invoice_total" binding:"min=0"`
+	ExpiryDays      int          `json:"expiry_days" binding:"min=0,max=3650"`
+	Active          *bool        `json:"active"`
+}
+
+// LoyaltyAdjustmentRequest - ручное начисление (points > 0) или списание (points < 0) баллов с причиной
+type LoyaltyAdjustmentRequest struct {
+	Points  int64  `json:"points" binding:"required"`
+	Comment string `json:"comment" binding:"required,max=500"`
+}
+
+type LoyaltyRedemptionRequest struct {
+	Points int64 `json:"points" binding:"required,min=1"`
+}
+
+type LoyaltyRuleResponse struct {
+	ID              uint         `json:"id"`
+	Name            string       `json:"name"`
+	AppliesTo       string       `json:"applies_to"`
+	Percent         int          `json:"percent"`
+	MinInvoiceTotal models.Money `json:"min_invoice_total"`
+	ExpiryDays      int          `json:"expiry_days"`
+	Active          bool         `json:"active"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+type LoyaltyEntryResponse struct {
+	ID            uint       `json:"id"`
+	ClientID      uint       `json:"client_id"`
+	Kind          string     `json:"kind"`
+	Points        int64      `json:"points"`
+	InvoiceID     *uint      `json:"invoice_id"`
+	RuleID        *uint      `json:"rule_id"`
+	SourceEntryID *uint      `json:"source_entry_id"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	Comment       string     `json:"comment,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type LoyaltyLotResponse struct {
+	EntryID   uint       `json:"entry_id"`
+	Points    int64      `json:"points"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *LoyaltyHandler) CreateRule(c *gin.Context) {
+	var req LoyaltyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &models.LoyaltyRule{Active: true}
+	applyLoyaltyRuleRequest(rule, req)
+
+	if err := h.repo.CreateLoyaltyRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toLoyaltyRuleResponse(rule))
+}
+
+func (h *LoyaltyHandler) ListRules(c *gin.Context) {
+	rules, err := h.repo.ListLoyaltyRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]LoyaltyRuleResponse, 0, len(rules))
+	for i := range rules {
+		items = append(items, toLoyaltyRuleResponse(&rules[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// UpdateRule меняет правило; уже начисленные по нему баллы не пересчитываются
+func (h *LoyaltyHandler) UpdateRule(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid loyalty rule ID format"})
+		return
+	}
+
+	var req LoyaltyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.repo.GetLoyaltyRule(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "loyalty rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	applyLoyaltyRuleRequest(rule, req)
+	if err := h.repo.UpdateLoyaltyRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toLoyaltyRuleResponse(rule))
+}
+
+// GetBalance возвращает доступные баллы клиента с разбивкой по срокам сгорания
+func (h *LoyaltyHandler) GetBalance(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	balance, err := h.repo.GetLoyaltyBalance(clientID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	lots := make([]LoyaltyLotResponse, 0, len(balance.Lots))
+	for _, lot := range balance.Lots {
+		lots = append(lots, LoyaltyLotResponse{EntryID: lot.EntryID, Points: lot.Remaining, ExpiresAt: lot.ExpiresAt})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":   clientID,
+		"points":      balance.Points,
+		"point_value": models.LoyaltyPointValue,
+		"lots":        lots,
+	})
+}
+
+// ListEntries - история начислений и списаний баллов клиента, новые записи первыми
+func (h *LoyaltyHandler) ListEntries(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.normalize()
+	entries, total, err := h.repo.ListLoyaltyEntries(clientID, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]LoyaltyEntryResponse, 0, len(entries))
+	for i := range entries {
+		items = append(items, toLoyaltyEntryResponse(&entries[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *LoyaltyHandler) AdjustPoints(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req LoyaltyAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := &models.LoyaltyEntry{ClientID: clientID, Points: req.Points, Comment: req.Comment}
+	if err := h.repo.AdjustLoyaltyPoints(entry); err != nil {
+		if errors.Is(err, models.ErrInsufficientPoints) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.kafka != nil {
+		go publishLoyaltyEvents(h.kafka, h.repo, []models.LoyaltyEntry{*entry})
+	}
+
+	c.JSON(http.StatusCreated, toLoyaltyEntryResponse(entry))
+}
+
+// RedeemPoints оплачивает счет баллами клиента, на которого выставлен счет
+func (h *LoyaltyHandler) RedeemPoints(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice ID format"})
+		return
+	}
+
+	var req LoyaltyRedemptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, invoice, err := h.repo.RedeemLoyaltyPoints(id, req.Points, time.Now())
+	if err != nil {
+		if errors.Is(err, models.ErrInsufficientPoints) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		respondInvoiceError(c, err)
+		return
+	}
+
+	if h.kafka != nil {
+		go publishLoyaltyEvents(h.kafka, h.repo, []models.LoyaltyEntry{*entry})
+	}
+	invoicePaid(h.referrals, h.repo, h.kafka, invoice, entry.CreatedAt)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"entry":   toLoyaltyEntryResponse(entry),
+		"invoice": toInvoiceResponse(invoice),
+	})
+}
+
+// ExpirePoints записывает сгорание просроченных баллов всех клиентов; вызывается планировщиком
+func (h *LoyaltyHandler) ExpirePoints(c *gin.Context) {
+	entries, err := h.repo.ExpireLoyaltyPoints(0, time.Now())
+	if h.kafka != nil && len(entries) > 0 {
+		go publishLoyaltyEvents(h.kafka, h.repo, entries)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var points int64
+	for _, entry := range entries {
+		points -= entry.Points
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries": len(entries),
+		"points":  points,
+	})
+}
+
+// accrueLoyaltyPoints начисляет баллы за полностью оплаченный счет; вызывается из invoicePaid. Ошибка начисления
+// не отменяет проведенную оплату и только логируется.
+func accrueLoyaltyPoints(loyalty models.LoyaltyRepository, kafka utils.KafkaProducer, invoice *models.Invoice, at time.Time) {
+	if invoice.Status != models.InvoiceStatusPaid {
+		return
+	}
+
+	entries, err := loyalty.AccrueLoyaltyPoints(invoice.ID, at)
+	if err != nil {
+		log.Printf("Failed to accrue loyalty points for invoice %d: %v", invoice.ID, err)
+		return
+	}
+	if kafka != nil && len(entries) > 0 {
+		go publishLoyaltyEvents(kafka, loyalty, entries)
+	}
+}
+
+func applyLoyaltyRuleRequest(rule *models.LoyaltyRule, req LoyaltyRuleRequest) {
+	rule.Name = req.Name
+	rule.AppliesTo = req.AppliesTo
+	if rule.AppliesTo == "" {
+		rule.AppliesTo = models.LoyaltyAppliesToAll
+	}
+	rule.Percent = req.Percent
+	rule.MinInvoiceTotal = req.MinInvoiceTotal
+	rule.ExpiryDays = req.ExpiryDays
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+}
+
+func toLoyaltyRuleResponse(rule *models.LoyaltyRule) LoyaltyRuleResponse {
+	return LoyaltyRuleResponse{
+		ID:              rule.ID,
+		Name:            rule.Name,
+		AppliesTo:       rule.AppliesTo,
+		Percent:         rule.Percent,
+		MinInvoiceTotal: rule.MinInvoiceTotal,
+		ExpiryDays:      rule.ExpiryDays,
+		Active:          rule.Active,
+		CreatedAt:       rule.CreatedAt,
+	}
+}
+
+func toLoyaltyEntryResponse(entry *models.LoyaltyEntry) LoyaltyEntryResponse {
+	return LoyaltyEntryResponse{
+		ID:            entry.ID,
+		ClientID:      entry.ClientID,
+		Kind:          entry.Kind,
+		Points:        entry.Points,
+		InvoiceID:     entry.InvoiceID,
+		RuleID:        entry.RuleID,
+		SourceEntryID: entry.SourceEntryID,
+		ExpiresAt:     entry.ExpiresAt,
+		Comment:       entry.Comment,
+		CreatedAt:     entry.CreatedAt,
+	}
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	repo    models.OrganizationRepository
+	clients models.Repository
+}
+
+func NewOrganizationHandler(repo models.OrganizationRepository, clients models.Repository) *OrganizationHandler {
+	return &OrganizationHandler{
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+type OrganizationRequest struct {
+	Name        string `json:"name" binding:"required,max=200"`
+	TaxID       string `json:"tax_id" binding:"omitempty,numeric,min=10,max=12"`
+	ContactName string `json:"contact_name" binding:"max=200"`
+	Phone       string `json:"phone" binding:"omitempty,e164"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	Address     string `json:"address" binding:"max=500"`
+}
+
+// RelationshipRequest - связь клиента из пути. Для guardian related_client_id - опекун клиента,
+// для family_member - родственник, для corporate_sponsor указывается organization_id.
+type RelationshipRequest struct {
+	Kind            string `json:"kind" binding:"required,oneof=guardian family_member corporate_sponsor"`
+	RelatedClientID *uint  `json:"related_client_id"`
+	OrganizationID  *uint  `json:"organization_id"`
+}
+
+type OrganizationResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	TaxID       string    `json:"tax_id"`
+	ContactName string    `json:"contact_name"`
+	Phone       string    `json:"phone"`
+	Email       string    `json:"email"`
+	Address     string    `json:"address"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RelatedPartyResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type RelationshipResponse struct {
+	ID            uint                  `json:"id"`
+	Kind          string                `json:"kind"`
+	Client        RelatedPartyResponse  `json:"client"`
+	RelatedClient *RelatedPartyResponse `json:"related_client,omitempty"`
+	Organization  *RelatedPartyResponse `json:"organization,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organization := &models.Organization{}
+	applyOrganizationRequest(organization, req)
+
+	if err := h.repo.CreateOrganization(organization); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toOrganizationResponse(organization))
+}
+
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.normalize()
+	organizations, total, err := h.repo.ListOrganizations(page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]OrganizationResponse, 0, len(organizations))
+	for i := range organizations {
+		items = append(items, toOrganizationResponse(&organizations[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	organization, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toOrganizationResponse(organization))
+}
+
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organization, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	applyOrganizationRequest(organization, req)
+	if err := h.repo.UpdateOrganization(organization); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toOrganizationResponse(organization))
+}
+
+// ListOrganizationClients возвращает сотрудников, за которых платит организация
+func (h *OrganizationHandler) ListOrganizationClients(c *gin.Context) {
+	organization, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	clients, err := h.repo.ListOrganizationClients(organization.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toClientResponses(clients))
+}
+
+func (h *OrganizationHandler) CreateRelationship(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req RelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	relationship := &models.ClientRelationship{ClientID: clientID, Kind: req.Kind}
+	if req.Kind == models.RelationshipCorporateSponsor {
+		if req.OrganizationID == nil || req.RelatedClientID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "corporate_sponsor requires organization_id only"})
+			return
+		}
+		relationship.OrganizationID = req.OrganizationID
+	} else {
+		if req.RelatedClientID == nil || req.OrganizationID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": req.Kind + " requires related_client_id only"})
+			return
+		}
+		if *req.RelatedClientID == clientID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a client cannot be related to itself"})
+			return
+		}
+		relationship.RelatedClientID = req.RelatedClientID
+	}
+
+	if err := h.repo.CreateRelationship(relationship); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "relationship already exists"})
+		case errors.Is(err, models.ErrInvalidReference):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "related client or organization not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toRelationshipResponse(relationship))
+}
+
+// ListRelationships возвращает связи клиента с обеих сторон: его опекунов и подопечных, родственников и спонсоров
+func (h *OrganizationHandler) ListRelationships(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	relationships, err := h.repo.ListRelationships(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]RelationshipResponse, 0, len(relationships))
+	for i := range relationships {
+		items = append(items, toRelationshipResponse(&relationships[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *OrganizationHandler) DeleteRelationship(c *gin.Context) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+	relationshipID, err := parseUint(c.Param("relationship_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid relationship ID format"})
+		return
+	}
+
+	if err := h.repo.DeleteRelationship(clientID, relationshipID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "relationship not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDependents возвращает подопечных клиента-опекуна
+func (h *OrganizationHandler) ListDependents(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	clients, err := h.repo.ListDependents(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toClientResponses(clients))
+}
+
+func (h *OrganizationHandler) loadOrganization(c *gin.Context) (*models.Organization, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID format"})
+		return nil, false
+	}
+
+	organization, err := h.repo.GetOrganization(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return organization, true
+}
+
+func applyOrganizationRequest(organization *models.Organization, req OrganizationRequest) {
+	organization.Name = strings.TrimSpace(req.Name)
+	organization.TaxID = req.TaxID
+	organization.ContactName = req.ContactName
+	organization.Phone = req.Phone
+	organization.Email = req.Email
+	organization.Address = req.Address
+}
+
+func toOrganizationResponse(organization *models.Organization) OrganizationResponse {
+	return OrganizationResponse{
+		ID:          organization.ID,
+		Name:        organization.Name,
+		TaxID:       organization.TaxID,
+		ContactName: organization.ContactName,
+		Phone:       organization.Phone,
+		Email:       organization.Email,
+		Address:     organization.Address,
+		CreatedAt:   organization.CreatedAt,
+	}
+}
+
+func toRelationshipResponse(relationship *models.ClientRelationship) RelationshipResponse {
+	resp := RelationshipResponse{
+		ID:        relationship.ID,
+		Kind:      relationship.Kind,
+		Client:    RelatedPartyResponse{ID: relationship.ClientID},
+		CreatedAt: relationship.CreatedAt,
+	}
+	if relationship.Client != nil {
+		resp.Client.Name = relationship.Client.FullName
+	}
+	if relationship.RelatedClientID != nil {
+		resp.RelatedClient = &RelatedPartyResponse{ID: *relationship.RelatedClientID}
+		if relationship.RelatedClient != nil {
+			resp.RelatedClient.Name = relationship.RelatedClient.FullName
+		}
+	}
+	if relationship.OrganizationID != nil {
+		resp.Organization = &RelatedPartyResponse{ID: *relationship.OrganizationID}
+		if relationship.Organization != nil {
+			resp.Organization.Name = relationship.Organization.Name
+		}
+	}
+	return resp
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PackageHandler struct {
+	repo    models.PackageRepository
+	clients models.Repository
+	kafka   utils.KafkaProducer
+}
+
+func NewPackageHandler(repo models.PackageRepository, clients models.Repository, kafka utils.KafkaProducer) *PackageHandler {
+	return &PackageHandler{
+		repo:    repo,
+		clients: clients,
+		kafka:   kafka,
+	}
+}
+
+// PackageRequest - продажа абонемента. Для пакета сессий total_sessions обязателен,
+// для членства без него число посещений не ограничено.
+type PackageRequest struct {
+	Kind          string `json:"kind" binding:"required,oneof=sessions membership"`
+	Title         string `json:"title" binding:"required,max=200"`
+	Service       string `json:"service" binding:"max=100"`
+	TotalSessions *int   `json:"total_sessions" binding:"omitempty,min=1,max=1000"`
+	ValidFrom     string `json:"valid_from" binding:"required,datetime=2006-01-02"`
+	ValidUntil    string `json:"valid_until" binding:"required,datetime=2006-01-02"`
+}
+
+type PackageDebitResponse struct {
+	AppointmentID     *uint     `json:"appointment_id"`
+	ClassEnrollmentID *uint     `json:"class_enrollment_id"`
+	Sessions          int       `json:"sessions"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type PackageResponse struct {
+	ID                uint                   `json:"id"`
+	ClientID          uint                   `json:"client_id"`
+	Kind              string                 `json:"kind"`
+	Title             string                 `json:"title"`
+	Service           string                 `json:"service"`
+	TotalSessions     *int                   `json:"total_sessions"`
+	RemainingSessions *int                   `json:"remaining_sessions"`
+	ValidFrom         string                 `json:"valid_from"`
+	ValidUntil        string                 `json:"valid_until"`
+	Status            string                 `json:"status"`
+	FrozenAt          *time.Time             `json:"frozen_at"`
+	FrozenDays        int                    `json:"frozen_days"`
+	Debits            []PackageDebitResponse `json:"debits,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+}
+
+func (h *PackageHandler) CreatePackage(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req PackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind == models.PackageKindSessions && req.TotalSessions == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "total_sessions is required for a session package"})
+		return
+	}
+
+	validFrom, _ := time.ParseInLocation(dateLayout, req.ValidFrom, time.Local)
+	validUntil, _ := time.ParseInLocation(dateLayout, req.ValidUntil, time.Local)
+	if validUntil.Before(validFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until must not be before valid_from"})
+		return
+	}
+
+	pkg := &models.Package{
+		ClientID:   clientID,
+		Kind:       req.Kind,
+		Title:      req.Title,
+		Service:    req.Service,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+		Status:     models.PackageStatusActive,
+	}
+	if req.TotalSessions != nil {
+		total, remaining := *req.TotalSessions, *req.TotalSessions
+		pkg.TotalSessions = &total
+		pkg.RemainingSessions = &remaining
+	}
+
+	if err := h.repo.CreatePackage(pkg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.kafka != nil {
+		go publishPackageEvent(h.kafka, "package_purchased", pkg, nil)
+	}
+
+	c.JSON(http.StatusCreated, toPackageResponse(pkg))
+}
+
+func (h *PackageHandler) ListPackages(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	packages, err := h.repo.ListPackages(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]PackageResponse, 0, len(packages))
+	for i := range packages {
+		items = append(items, toPackageResponse(&packages[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *PackageHandler) GetPackage(c *gin.Context) {
+	pkg, ok := h.loadPackage(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toPackageResponse(pkg))
+}
+
+// FreezePackage приостанавливает абонемент: списания невозможны, срок действия будет продлен при разморозке
+func (h *PackageHandler) FreezePackage(c *gin.Context) {
+	pkg, ok := h.loadPackage(c)
+	if !ok {
+		return
+	}
+
+	if pkg.EffectiveStatus(time.Now()) != models.PackageStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "only an active package can be frozen"})
+		return
+	}
+
+	now := time.Now()
+	pkg.Status = models.PackageStatusFrozen
+	pkg.FrozenAt = &now
+
+	h.savePackage(c, pkg, models.PackageStatusActive, "package_frozen")
+}
+
+func (h *PackageHandler) UnfreezePackage(c *gin.Context) {
+	pkg, ok := h.loadPackage(c)
+	if !ok {
+		return
+	}
+
+	if pkg.Status != models.PackageStatusFrozen {
+		c.JSON(http.StatusConflict, gin.H{"error": "package is not frozen"})
+		return
+	}
+
+	pkg.Unfreeze(time.Now())
+
+	h.savePackage(c, pkg, models.PackageStatusFrozen, "package_unfrozen")
+}
+
+func (h *PackageHandler) CancelPackage(c *gin.Context) {
+	pkg, ok := h.loadPackage(c)
+	if !ok {
+		return
+	}
+
+	if pkg.Status == models.PackageStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "package is already cancelled"})
+		return
+	}
+
+	from := pkg.Status
+	pkg.Status = models.PackageStatusCancelled
+	pkg.FrozenAt = nil
+
+	h.savePackage(c, pkg, from, "package_cancelled")
+}
+
+// savePackage сохраняет переход абонемента из статуса from; если статус тем временем изменили, отвечает 409
+func (h *PackageHandler) savePackage(c *gin.Context, pkg *models.Package, from string, eventType string) {
+	if err := h.repo.UpdatePackage(pkg, from); err != nil {
+		if errors.Is(err, models.ErrPackageChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.kafka != nil {
+		go publishPackageEvent(h.kafka, eventType, pkg, nil)
+	}
+
+	c.JSON(http.StatusOK, toPackageResponse(pkg))
+}
+
+func (h *PackageHandler) loadPackage(c *gin.Context) (*models.Package, bool) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return nil, false
+	}
+
+	packageID, err := parseUint(c.Param("package_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid package ID format"})
+		return nil, false
+	}
+
+	pkg, err := h.repo.GetPackage(clientID, packageID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "package not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return pkg, true
+}
+
+func toPackageResponse(pkg *models.Package) PackageResponse {
+	resp := PackageResponse{
+		ID:                pkg.ID,
+		ClientID:          pkg.ClientID,
+		Kind:              pkg.Kind,
+		Title:             pkg.Title,
+		Service:           pkg.Service,
+		TotalSessions:     pkg.TotalSessions,
+		RemainingSessions: pkg.RemainingSessions,
+		ValidFrom:         pkg.ValidFrom.Format(dateLayout),
+		ValidUntil:        pkg.ValidUntil.Format(dateLayout),
+		Status:            pkg.EffectiveStatus(time.Now()),
+		FrozenAt:          pkg.FrozenAt,
+		FrozenDays:        pkg.FrozenDays,
+		CreatedAt:         pkg.CreatedAt,
+	}
+	for _, debit := range pkg.Debits {
+		resp.Debits = append(resp.Debits, PackageDebitResponse{
+			AppointmentID:     debit.AppointmentID,
+			ClassEnrollmentID: debit.ClassEnrollmentID,
+			Sessions:          debit.Sessions,
+			CreatedAt:         debit.CreatedAt,
+		})
+	}
+	return resp
+}
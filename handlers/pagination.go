@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// PageQuery - параметры постраничной выдачи
+type PageQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+func (p PageQuery) normalize() PageQuery {
+	if p.Page == 0 {
+		p.Page = 1
+	}
+	if p.PageSize == 0 {
+		p.PageSize = defaultPageSize
+	}
+	if p.PageSize > maxPageSize {
+		p.PageSize = maxPageSize
+	}
+	return p
+}
+
+func (p PageQuery) offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// PageResponse - общая обертка для списков
+type PageResponse struct {
+	Items    interface{} `json:"items"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Next     string      `json:"next,omitempty"`
+}
+
+// newPageResponse формирует ответ и ссылку на следующую страницу, сохраняя остальные параметры запроса
+func newPageResponse(c *gin.Context, items interface{}, total int64, page PageQuery) PageResponse {
+	resp := PageResponse{
+		Items:    items,
+		Total:    total,
+		Page:     page.Page,
+		PageSize: page.PageSize,
+	}
+
+	if int64(page.Page*page.PageSize) < total {
+		values := c.Request.URL.Query()
+		values.Set("page", strconv.Itoa(page.Page+1))
+		values.Set("page_size", strconv.Itoa(page.PageSize))
+		resp.Next = c.Request.URL.Path + "?" + values.Encode()
+	}
+
+	return resp
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewPageResponseNextLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/clients?gender=female&page=1", nil)
+
+	page := PageQuery{Page: 1, PageSize: 10}.normalize()
+	resp := newPageResponse(c, []int{}, 25, page)
+
+	want := "/api/v1/clients?gender=female&page=2&page_size=10"
+	if resp.Next != want {
+		t.Errorf("Next = %q, want %q", resp.Next, want)
+	}
+
+	page.Page = 3
+	resp = newPageResponse(c, []int{}, 25, page)
+	if resp.Next != "" {
+		t.Errorf("Next on last page = %q, want empty", resp.Next)
+	}
+}
+
+func TestPageQueryNormalize(t *testing.T) {
+	page := PageQuery{}.normalize()
+	if page.Page != 1 || page.PageSize != defaultPageSize {
+		t.Errorf("normalize() = %+v, want page 1 and default size", page)
+	}
+	if got := (PageQuery{Page: 3, PageSize: 20}).offset(); got != 40 {
+		t.Errorf("offset() = %d, want 40", got)
+	}
+}
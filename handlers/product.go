@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductHandler struct {
+	repo     models.ProductRepository
+	branches models.BranchRepository
+	kafka    utils.KafkaProducer
+}
+
+func NewProductHandler(repo models.ProductRepository, branches models.BranchRepository, kafka utils.KafkaProducer) *ProductHandler {
+	return &ProductHandler{
+		repo:     repo,
+		branches: branches,
+		kafka:    kafka,
+	}
+}
+
+// ProductRequest - товар каталога. LowStockThreshold - остаток в филиале, при котором
+// отправляется оповещение о необходимости закупки.
+type ProductRequest struct {
+	SKU               string       `json:"sku" binding:"required,max=50"`
+	Name              string       `json:"name" binding:"required,max=200"`
+	Category          string       `json:"category" binding:"required,oneof=supplements cosmetics other"`
+	Description       string       `json:"description" binding:"max=2000"`
+	Price             models.Money `json:"price" binding:"min=0"`
+	LowStockThreshold int          `json:"low_stock_threshold" binding:"min=0,max=100000"`
+	Active            *bool        `json:"active"`
+}
+
+type ProductResponse struct {
+	ID                uint         `json:"id"`
+	SKU               string       `json:"sku"`
+	Name              string       `json:"name"`
+	Category          string       `json:"category"`
+	Description       string       `json:"description"`
+	Price             models.Money `json:"price"`
+	LowStockThreshold int          `json:"low_stock_threshold"`
+	Active            bool         `json:"active"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+type ListProductsQuery struct {
+	PageQuery
+	Category string `form:"category" binding:"omitempty,oneof=supplements cosmetics other"`
+	Query    string `form:"q"`
+	Active   bool   `form:"active"`
+}
+
+// StockMovementRequest - приход товара в филиал или списание (брак, истек срок); продажи проводятся счетами.
+// Без branch_id движение относится к основному филиалу.
+type StockMovementRequest struct {
+	ProductID uint   `json:"product_id" binding:"required"`
+	BranchID  uint   `json:"branch_id"`
+	Kind      string `json:"kind" binding:"required,oneof=receipt write_off"`
+	Quantity  int    `json:"quantity" binding:"required,min=1,max=100000"`
+	Comment   string `json:"comment" binding:"required_if=Kind write_off,max=500"`
+}
+
+type StockMovementResponse struct {
+	ID            uint      `json:"id"`
+	ProductID     uint      `json:"product_id"`
+	BranchID      uint      `json:"branch_id"`
+	Kind          string    `json:"kind"`
+	Quantity      int       `json:"quantity"`
+	QuantityAfter int       `json:"quantity_after"`
+	InvoiceID     *uint     `json:"invoice_id"`
+	Comment       string    `json:"comment"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ListStockMovementsQuery struct {
+	PageQuery
+	ProductID uint   `form:"product_id"`
+	BranchID  uint   `form:"branch_id"`
+	Kind      string `form:"kind" binding:"omitempty,oneof=receipt sale write_off return"`
+	InvoiceID uint   `form:"invoice_id"`
+}
+
+type StockResponse struct {
+	ProductID         uint      `json:"product_id"`
+	SKU               string    `json:"sku"`
+	Name              string    `json:"name"`
+	BranchID          uint      `json:"branch_id"`
+	Quantity          int       `json:"quantity"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
+	Low               bool      `json:"low"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type ListStockQuery struct {
+	PageQuery
+	ProductID uint `form:"product_id"`
+	BranchID  uint `form:"branch_id"`
+	Low       bool `form:"low"`
+}
+
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	var req ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product := &models.Product{Active: true}
+	applyProductRequest(product, req)
+
+	if err := h.repo.CreateProduct(product); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "product with this SKU already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toProductResponse(product))
+}
+
+// ListProducts возвращает каталог; q ищет по названию и артикулу, active=true оставляет товары в продаже
+func (h *ProductHandler) ListProducts(c *gin.Context) {
+	var query ListProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	products, total, err := h.repo.ListProducts(models.ProductFilter{
+		Category:   query.Category,
+		Query:      query.Query,
+		ActiveOnly: query.Active,
+		Limit:      page.PageSize,
+		Offset:     page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ProductResponse, 0, len(products))
+	for i := range products {
+		items = append(items, toProductResponse(&products[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *ProductHandler) GetProduct(c *gin.Context) {
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toProductResponse(product))
+}
+
+// UpdateProduct меняет карточку товара; снятый с продажи товар нельзя добавить в новый счет
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	var req ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	applyProductRequest(product, req)
+	if err := h.repo.UpdateProduct(product); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "product with this SKU already exists"})
+		case errors.Is(err, models.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toProductResponse(product))
+}
+
+// ListStock возвращает остатки по филиалам; low=true оставляет товары в продаже с остатком не выше порога
+func (h *ProductHandler) ListStock(c *gin.Context) {
+	var query ListStockQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	stock, total, err := h.repo.ListStock(models.StockFilter{
+		ProductID: query.ProductID,
+		BranchID:  query.BranchID,
+		LowOnly:   query.Low,
+		Limit:     page.PageSize,
+		Offset:    page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]StockResponse, 0, len(stock))
+	for i := range stock {
+		items = append(items, toStockResponse(&stock[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *ProductHandler) CreateStockMovement(c *gin.Context) {
+	var req StockMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.repo.GetProduct(req.ProductID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	branch, ok := resolveBranch(c, h.branches, req.BranchID)
+	if !ok {
+		return
+	}
+
+	movement := &models.StockMovement{
+		ProductID: product.ID,
+		BranchID:  branch.ID,
+		Kind:      req.Kind,
+		Quantity:  req.Quantity,
+		Comment:   req.Comment,
+	}
+	if err := h.repo.RecordStockMovement(movement); err != nil {
+		if errors.Is(err, models.ErrInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.kafka != nil {
+		go publishLowStockEvents(h.kafka, map[uint]models.Product{product.ID: *product}, []models.StockMovement{*movement})
+	}
+
+	c.JSON(http.StatusCreated, toStockMovementResponse(movement))
+}
+
+func (h *ProductHandler) ListStockMovements(c *gin.Context) {
+	var query ListStockMovementsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page := query.PageQuery.normalize()
+	movements, total, err := h.repo.ListStockMovements(models.StockMovementFilter{
+		ProductID: query.ProductID,
+		BranchID:  query.BranchID,
+		Kind:      query.Kind,
+		InvoiceID: query.InvoiceID,
+		From:      from,
+		To:        to,
+		Limit:     page.PageSize,
+		Offset:    page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]StockMovementResponse, 0, len(movements))
+	for i := range movements {
+		items = append(items, toStockMovementResponse(&movements[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// loadProduct загружает товар по id из пути; при ошибке ответ уже отправлен
+func (h *ProductHandler) loadProduct(c *gin.Context) (*models.Product, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return nil, false
+	}
+
+	product, err := h.repo.GetProduct(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return product, true
+}
+
+func applyProductRequest(product *models.Product, req ProductRequest) {
+	product.SKU = req.SKU
+	product.Name = req.Name
+	product.Category = req.Category
+	product.Description = req.Description
+	product.Price = req.Price
+	product.LowStockThreshold = req.LowStockThreshold
+	if req.Active != nil {
+		product.Active = *req.Active
+	}
+}
+
+func toProductResponse(product *models.Product) ProductResponse {
+	return ProductResponse{
+		ID:                product.ID,
+		SKU:               product.SKU,
+		Name:              product.Name,
+		Category:          product.Category,
+		Description:       product.Description,
+		Price:             product.Price,
+		LowStockThreshold: product.LowStockThreshold,
+		Active:            product.Active,
+		CreatedAt:         product.CreatedAt,
+	}
+}
+
+func toStockResponse(stock *models.ProductStock) StockResponse {
+	resp := StockResponse{
+		ProductID: stock.ProductID,
+		BranchID:  stock.BranchID,
+		Quantity:  stock.Quantity,
+		UpdatedAt: stock.UpdatedAt,
+	}
+	if stock.Product != nil {
+		resp.SKU = stock.Product.SKU
+		resp.Name = stock.Product.Name
+		resp.LowStockThreshold = stock.Product.LowStockThreshold
+		resp.Low = stock.Quantity <= stock.Product.LowStockThreshold
+	}
+	return resp
+}
+
+func toStockMovementResponse(movement *models.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		ID:            movement.ID,
+		ProductID:     movement.ProductID,
+		BranchID:      movement.BranchID,
+		Kind:          movement.Kind,
+		Quantity:      movement.Quantity,
+		QuantityAfter: movement.QuantityAfter,
+		InvoiceID:     movement.InvoiceID,
+		Comment:       movement.Comment,
+		CreatedAt:     movement.CreatedAt,
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromoCodeHandler struct {
+	repo     models.PromoCodeRepository
+	channels models.ChannelRepository
+}
+
+func NewPromoCodeHandler(repo models.PromoCodeRepository, channels models.ChannelRepository) *PromoCodeHandler {
+	return &PromoCodeHandler{
+		repo:     repo,
+		channels: channels,
+	}
+}
+
+// PromoCodeRequest - условия промокода. Для kind=percent задается percent, для kind=fixed - amount.
+// channels ограничивает код клиентами из указанных рекламных каналов, пустой список - без ограничения.
+type PromoCodeRequest struct {
+	Code           string       `json:"code" binding:"required,min=3,max=50,alphanum"`
+	Kind           string       `json:"kind" binding:"required,oneof=percent fixed"`
+	Percent        int          `json:"percent" binding:"min=0,max=100"`
+	Amount         models.Money `json:"amount" binding:"min=0"`
+	FirstVisitOnly bool         `json:"first_visit_only"`
+	Channels       []string     `json:"channels" binding:"max=20"`
+	UsageLimit     *int         `json:"usage_limit" binding:"omitempty,min=1"`
+	PerClientLimit *int         `json:"per_client_limit" binding:"omitempty,min=1"`
+	ValidFrom      string       `json:"valid_from" binding:"omitempty,datetime=2006-01-02"`
+	ValidUntil     string       `json:"valid_until" binding:"omitempty,datetime=2006-01-02"`
+	Active         *bool        `json:"active"`
+	Comment        string       `json:"comment" binding:"max=500"`
+}
+
+type ListPromoCodesQuery struct {
+	PageQuery
+	Active bool `form:"active"`
+}
+
+type PromoCodeResponse struct {
+	ID             uint         `json:"id"`
+	Code           string       `json:"code"`
+	Kind           string       `json:"kind"`
+	Percent        int          `json:"percent"`
+	Amount         models.Money `json:"amount"`
+	FirstVisitOnly bool         `json:"first_visit_only"`
+	Channels       []string     `json:"channels"`
+	UsageLimit     *int         `json:"usage_limit"`
+	PerClientLimit *int         `json:"per_client_limit"`
+	UsedCount      int          `json:"used_count"`
+	ValidFrom      *string      `json:"valid_from"`
+	ValidUntil     *string      `json:"valid_until"`
+	Active         bool         `json:"active"`
+	Comment        string       `json:"comment"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+func (h *PromoCodeHandler) CreatePromoCode(c *gin.Context) {
+	var req PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo := &models.PromoCode{Code: models.NormalizeCode(req.Code), Active: true}
+	if !h.applyRequest(c, promo, req) {
+		return
+	}
+
+	if err := h.repo.CreatePromoCode(promo); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "promo code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toPromoCodeResponse(promo))
+}
+
+func (h *PromoCodeHandler) ListPromoCodes(c *gin.Context) {
+	var query ListPromoCodesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	promos, total, err := h.repo.ListPromoCodes(query.Active, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]PromoCodeResponse, 0, len(promos))
+	for i := range promos {
+		items = append(items, toPromoCodeResponse(&promos[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *PromoCodeHandler) GetPromoCode(c *gin.Context) {
+	promo, ok := h.loadPromoCode(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toPromoCodeResponse(promo))
+}
+
+// UpdatePromoCode меняет условия кода; сам код и счетчик использований не меняются
+func (h *PromoCodeHandler) UpdatePromoCode(c *gin.Context) {
+	var req PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo, ok := h.loadPromoCode(c)
+	if !ok {
+		return
+	}
+	if models.NormalizeCode(req.Code) != promo.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "promo code cannot be changed"})
+		return
+	}
+	if !h.applyRequest(c, promo, req) {
+		return
+	}
+
+	if err := h.repo.UpdatePromoCode(promo); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "promo code not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toPromoCodeResponse(promo))
+}
+
+// applyRequest проверяет условия кода и переносит их в promo; при ошибке ответ уже отправлен
+func (h *PromoCodeHandler) applyRequest(c *gin.Context, promo *models.PromoCode, req PromoCodeRequest) bool {
+	if req.Kind == models.PromoKindPercent && req.Percent == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "percent is required for a percent promo code"})
+		return false
+	}
+	if req.Kind == models.PromoKindFixed && req.Amount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount is required for a fixed promo code"})
+		return false
+	}
+
+	var validFrom, validUntil *time.Time
+	if req.ValidFrom != "" {
+		parsed, _ := time.ParseInLocation(dateLayout, req.ValidFrom, time.Local)
+		validFrom = &parsed
+	}
+	if req.ValidUntil != "" {
+		parsed, _ := time.ParseInLocation(dateLayout, req.ValidUntil, time.Local)
+		validUntil = &parsed
+	}
+	if validFrom != nil && validUntil != nil && validUntil.Before(*validFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until must not be before valid_from"})
+		return false
+	}
+
+	channels := models.StringList{}
+	for _, code := range req.Channels {
+		if _, err := h.channels.GetChannelByCode(code); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown advertising channel " + code})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		channels = append(channels, code)
+	}
+
+	promo.Kind = req.Kind
+	promo.Percent = 0
+	promo.Amount = 0
+	if req.Kind == models.PromoKindPercent {
+		promo.Percent = req.Percent
+	} else {
+		promo.Amount = req.Amount
+	}
+	promo.FirstVisitOnly = req.FirstVisitOnly
+	promo.Channels = channels
+	promo.UsageLimit = req.UsageLimit
+	promo.PerClientLimit = req.PerClientLimit
+	promo.ValidFrom = validFrom
+	promo.ValidUntil = validUntil
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+	promo.Comment = req.Comment
+	return true
+}
+
+func (h *PromoCodeHandler) loadPromoCode(c *gin.Context) (*models.PromoCode, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code ID format"})
+		return nil, false
+	}
+
+	promo, err := h.repo.GetPromoCode(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "promo code not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return promo, true
+}
+
+func toPromoCodeResponse(promo *models.PromoCode) PromoCodeResponse {
+	resp := PromoCodeResponse{
+		ID:             promo.ID,
+		Code:           promo.Code,
+		Kind:           promo.Kind,
+		Percent:        promo.Percent,
+		Amount:         promo.Amount,
+		FirstVisitOnly: promo.FirstVisitOnly,
+		Channels:       promo.Channels,
+		UsageLimit:     promo.UsageLimit,
+		PerClientLimit: promo.PerClientLimit,
+		UsedCount:      promo.UsedCount,
+		Active:         promo.Active,
+		Comment:        promo.Comment,
+		CreatedAt:      promo.CreatedAt,
+	}
+	if resp.Channels == nil {
+		resp.Channels = []string{}
+	}
+	if promo.ValidFrom != nil {
+		validFrom := promo.ValidFrom.Format(dateLayout)
+		resp.ValidFrom = &validFrom
+	}
+	if promo.ValidUntil != nil {
+		validUntil := promo.ValidUntil.Format(dateLayout)
+		resp.ValidUntil = &validUntil
+	}
+	return resp
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QuestionnaireHandler struct {
+	repo    models.QuestionnaireRepository
+	clients models.Repository
+}
+
+func NewQuestionnaireHandler(repo models.QuestionnaireRepository, clients models.Repository) *QuestionnaireHandler {
+	return &QuestionnaireHandler{
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+type QuestionRequest struct {
+	Key      string   `json:"key" binding:"required,max=50"`
+	Label    string   `json:"label" binding:"required,max=500"`
+	Type     string   `json:"type" binding:"required,oneof=text choice scale date"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" binding:"max=50,dive,required,max=200"`
+	Multiple bool     `json:"multiple"`
+	Min      int      `json:"min"`
+	Max      int      `json:"max"`
+}
+
+type QuestionnaireTemplateRequest struct {
+	Code        string            `json:"code" binding:"required,max=50"`
+	Title       string            `json:"title" binding:"required,max=200"`
+	Description string            `json:"description" binding:"max=2000"`
+	Questions   []QuestionRequest `json:"questions" binding:"required,min=1,max=200,dive"`
+}
+
+// UpdateQuestionnaireTemplateRequest - изменение шаблона. Если вопросы переданы и отличаются
+// от текущих, создается новая версия; ранее заполненные анкеты остаются привязаны к своей версии.
+type UpdateQuestionnaireTemplateRequest struct {
+	Title       string            `json:"title" binding:"required,max=200"`
+	Description string            `json:"description" binding:"max=2000"`
+	Questions   []QuestionRequest `json:"questions" binding:"omitempty,min=1,max=200,dive"`
+}
+
+// QuestionnaireAnswersRequest - ответы клиента по ключам вопросов текущей версии шаблона
+type QuestionnaireAnswersRequest struct {
+	TemplateID uint                   `json:"template_id" binding:"required"`
+	Answers    map[string]interface{} `json:"answers" binding:"required"`
+}
+
+type QuestionnaireTemplateResponse struct {
+	ID          uint              `json:"id"`
+	Code        string            `json:"code"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Version     int               `json:"version"`
+	Questions   []models.Question `json:"questions,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// AnsweredQuestion - вопрос версии шаблона вместе с ответом клиента
+type AnsweredQuestion struct {
+	models.Question
+	Answer interface{} `json:"answer"`
+}
+
+type QuestionnaireResponseResponse struct {
+	ID         uint               `json:"id"`
+	ClientID   uint               `json:"client_id"`
+	TemplateID uint               `json:"template_id"`
+	Code       string             `json:"code"`
+	Title      string             `json:"title"`
+	Version    int                `json:"version"`
+	FilledAt   time.Time          `json:"filled_at"`
+	Questions  []AnsweredQuestion `json:"questions"`
+}
+
+func (h *QuestionnaireHandler) CreateTemplate(c *gin.Context) {
+	var req QuestionnaireTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	questions := toQuestions(req.Questions)
+	if err := questions.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := &models.QuestionnaireTemplate{
+		Code:        req.Code,
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if err := h.repo.CreateQuestionnaireTemplate(template, questions); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "questionnaire with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toQuestionnaireTemplateResponse(template))
+}
+
+func (h *QuestionnaireHandler) ListTemplates(c *gin.Context) {
+	var page PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page = page.normalize()
+
+	templates, total, err := h.repo.ListQuestionnaireTemplates(page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]QuestionnaireTemplateResponse, 0, len(templates))
+	for i := range templates {
+		items = append(items, toQuestionnaireTemplateResponse(&templates[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// GetTemplate возвращает шаблон с текущими вопросами; ?version=N - с вопросами указанной версии
+func (h *QuestionnaireHandler) GetTemplate(c *gin.Context) {
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	if v := c.Query("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+			return
+		}
+		content, err := h.repo.GetQuestionnaireVersion(template.ID, version)
+		if err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "questionnaire version not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		template.Latest = content
+	}
+
+	c.JSON(http.StatusOK, toQuestionnaireTemplateResponse(template))
+}
+
+func (h *QuestionnaireHandler) UpdateTemplate(c *gin.Context) {
+	var req UpdateQuestionnaireTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var questions models.Questions
+	if req.Questions != nil {
+		questions = toQuestions(req.Questions)
+		if err := questions.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	template, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	template.Title = req.Title
+	template.Description = req.Description
+	if err := h.repo.UpdateQuestionnaireTemplate(template, questions); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "questionnaire not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toQuestionnaireTemplateResponse(template))
+}
+
+// FillQuestionnaire сохраняет ответы клиента по текущей версии шаблона
+func (h *QuestionnaireHandler) FillQuestionnaire(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req QuestionnaireAnswersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.repo.GetQuestionnaireTemplate(req.TemplateID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "questionnaire not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	answers, err := template.Latest.ValidateAnswers(req.Answers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := &models.QuestionnaireResponse{
+		ClientID:   clientID,
+		TemplateID: template.ID,
+		VersionID:  template.Latest.ID,
+		Answers:    answers,
+		Template:   template,
+		Version:    template.Latest,
+	}
+	if err := h.repo.CreateQuestionnaireResponse(response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toQuestionnaireResponseResponse(response))
+}
+
+func (h *QuestionnaireHandler) ListResponses(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var query struct {
+		PageQuery
+		TemplateID uint `form:"template_id"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := query.PageQuery.normalize()
+
+	responses, total, err := h.repo.ListQuestionnaireResponses(clientID, query.TemplateID, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]QuestionnaireResponseResponse, 0, len(responses))
+	for i := range responses {
+		items = append(items, toQuestionnaireResponseResponse(&responses[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// GetResponse возвращает заполненную анкету: вопросы той версии, по которой она заполнялась, с ответами
+func (h *QuestionnaireHandler) GetResponse(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	responseID, err := parseUint(c.Param("response_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid response ID format"})
+		return
+	}
+
+	response, err := h.repo.GetQuestionnaireResponse(clientID, responseID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "questionnaire response not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toQuestionnaireResponseResponse(response))
+}
+
+func (h *QuestionnaireHandler) loadTemplate(c *gin.Context) (*models.QuestionnaireTemplate, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid questionnaire ID format"})
+		return nil, false
+	}
+
+	template, err := h.repo.GetQuestionnaireTemplate(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "questionnaire not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return template, true
+}
+
+func toQuestions(reqs []QuestionRequest) models.Questions {
+	questions := make(models.Questions, 0, len(reqs))
+	for _, q := range reqs {
+		questions = append(questions, models.Question{
+			Key:      q.Key,
+			Label:    q.Label,
+			Type:     q.Type,
+			Required: q.Required,
+			Options:  q.Options,
+			Multiple: q.Multiple,
+			Min:      q.Min,
+			Max:      q.Max,
+		})
+	}
+	return questions
+}
+
+func toQuestionnaireTemplateResponse(template *models.QuestionnaireTemplate) QuestionnaireTemplateResponse {
+	resp := QuestionnaireTemplateResponse{
+		ID:          template.ID,
+		Code:        template.Code,
+		Title:       template.Title,
+		Description: template.Description,
+		Version:     template.CurrentVersion,
+		UpdatedAt:   template.UpdatedAt,
+	}
+	if template.Latest != nil {
+		resp.Version = template.Latest.Version
+		resp.Questions = template.Latest.Questions
+	}
+	return resp
+}
+
+func toQuestionnaireResponseResponse(response *models.QuestionnaireResponse) QuestionnaireResponseResponse {
+	resp := QuestionnaireResponseResponse{
+		ID:         response.ID,
+		ClientID:   response.ClientID,
+		TemplateID: response.TemplateID,
+		FilledAt:   response.CreatedAt,
+		Questions:  []AnsweredQuestion{},
+	}
+	if response.Template != nil {
+		resp.Code = response.Template.Code
+		resp.Title = response.Template.Title
+	}
+	if response.Version != nil {
+		resp.Version = response.Version.Version
+		for _, question := range response.Version.Questions {
+			resp.Questions = append(resp.Questions, AnsweredQuestion{
+				Question: question,
+				Answer:   response.Answers[question.Key],
+			})
+		}
+	}
+	return resp
+}
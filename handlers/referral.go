@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReferralHandler struct {
+	repo    models.ReferralRepository
+	clients models.Repository
+}
+
+func NewReferralHandler(repo models.ReferralRepository, clients models.Repository) *ReferralHandler {
+	return &ReferralHandler{
+		repo:    repo,
+		clients: clients,
+	}
+}
+
+// ReferralRequest - клиент из пути привел клиента referred_client_id; reward_amount - причитающаяся ему награда
+type ReferralRequest struct {
+	ReferredClientID uint         `json:"referred_client_id" binding:"required"`
+	RewardAmount     models.Money `json:"reward_amount" binding:"min=0"`
+	Comment          string       `json:"comment" binding:"max=500"`
+}
+
+type ListReferralsQuery struct {
+	PageQuery
+	Status   string `form:"status" binding:"omitempty,oneof=pending qualified rewarded cancelled"`
+	BranchID uint   `form:"branch_id"`
+}
+
+type ReferralResponse struct {
+	ID                  uint                 `json:"id"`
+	Referrer            RelatedPartyResponse `json:"referrer"`
+	Referred            RelatedPartyResponse `json:"referred"`
+	Status              string               `json:"status"`
+	RewardAmount        models.Money         `json:"reward_amount"`
+	QualifyingInvoiceID *uint                `json:"qualifying_invoice_id"`
+	QualifiedAt         *time.Time           `json:"qualified_at"`
+	RewardedAt          *time.Time           `json:"rewarded_at"`
+	Comment             string               `json:"comment"`
+	CreatedAt           time.Time            `json:"created_at"`
+}
+
+func (h *ReferralHandler) CreateReferral(c *gin.Context) {
+	referrerID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req ReferralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ReferredClientID == referrerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a client cannot refer itself"})
+		return
+	}
+
+	referral := &models.Referral{
+		ReferrerID:   referrerID,
+		ReferredID:   req.ReferredClientID,
+		RewardAmount: req.RewardAmount,
+		Comment:      req.Comment,
+	}
+
+	if err := h.repo.CreateReferral(referral); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "referred client already has a referrer"})
+		case errors.Is(err, models.ErrReferralCycle):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrInvalidReference):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "referred client not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toReferralResponse(referral))
+}
+
+// ListClientReferrals возвращает клиентов, приведенных клиентом из пути
+func (h *ReferralHandler) ListClientReferrals(c *gin.Context) {
+	referrerID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+	h.listReferrals(c, referrerID)
+}
+
+// ListReferrals - все рефералы центра, например status=qualified для выдачи наград
+func (h *ReferralHandler) ListReferrals(c *gin.Context) {
+	h.listReferrals(c, 0)
+}
+
+func (h *ReferralHandler) listReferrals(c *gin.Context, referrerID uint) {
+	var query ListReferralsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	referrals, total, err := h.repo.ListReferrals(models.ReferralFilter{
+		ReferrerID: referrerID,
+		Status:     query.Status,
+		BranchID:   query.BranchID,
+		Limit:      page.PageSize,
+		Offset:     page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ReferralResponse, 0, len(referrals))
+	for i := range referrals {
+		items = append(items, toReferralResponse(&referrals[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// RewardReferral отмечает выдачу награды пригласившему; доступно после оплаты приглашенным первого счета
+func (h *ReferralHandler) RewardReferral(c *gin.Context) {
+	h.changeReferral(c, func(id uint) (*models.Referral, error) {
+		return h.repo.RewardReferral(id, time.Now())
+	})
+}
+
+func (h *ReferralHandler) CancelReferral(c *gin.Context) {
+	h.changeReferral(c, h.repo.CancelReferral)
+}
+
+func (h *ReferralHandler) changeReferral(c *gin.Context, change func(id uint) (*models.Referral, error)) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid referral ID format"})
+		return
+	}
+
+	referral, err := change(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "referral not found"})
+		case errors.Is(err, models.ErrReferralNotQualified), errors.Is(err, models.ErrReferralClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toReferralResponse(referral))
+}
+
+func toReferralResponse(referral *models.Referral) ReferralResponse {
+	resp := ReferralResponse{
+		ID:                  referral.ID,
+		Referrer:            RelatedPartyResponse{ID: referral.ReferrerID},
+		Referred:            RelatedPartyResponse{ID: referral.ReferredID},
+		Status:              referral.Status,
+		RewardAmount:        referral.RewardAmount,
+		QualifyingInvoiceID: referral.QualifyingInvoiceID,
+		QualifiedAt:         referral.QualifiedAt,
+		RewardedAt:          referral.RewardedAt,
+		Comment:             referral.Comment,
+		CreatedAt:           referral.CreatedAt,
+	}
+	if referral.Referrer != nil {
+		resp.Referrer.Name = referral.Referrer.FullName
+	}
+	if referral.Referred != nil {
+		resp.Referred.Name = referral.Referred.FullName
+	}
+	return resp
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ResourceHandler struct {
+	repo     models.ResourceRepository
+	branches models.BranchRepository
+}
+
+func NewResourceHandler(repo models.ResourceRepository, branches models.BranchRepository) *ResourceHandler {
+	return &ResourceHandler{
+		repo:     repo,
+		branches: branches,
+	}
+}
+
+type ResourceRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Kind        string `json:"kind" binding:"required,oneof=room equipment"`
+	Capacity    int    `json:"capacity" binding:"required,min=1,max=1000"`
+	Status      string `json:"status" binding:"omitempty,oneof=active inactive"`
+	Description string `json:"description" binding:"max=500"`
+	// BranchID - филиал ресурса; при создании без него - основной, при изменении без него - прежний
+	BranchID uint `json:"branch_id"`
+}
+
+type ResourceResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Capacity    int       `json:"capacity"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	BranchID    uint      `json:"branch_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ResourceBookingResponse struct {
+	AppointmentID uint      `json:"appointment_id"`
+	ClientID      uint      `json:"client_id"`
+	SpecialistID  uint      `json:"specialist_id"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+// ResourceOccupancyResponse - занятость ресурса за день
+type ResourceOccupancyResponse struct {
+	ResourceResponse
+	BookedMinutes int                       `json:"booked_minutes"`
+	PeakUsage     int                       `json:"peak_usage"`
+	Bookings      []ResourceBookingResponse `json:"bookings"`
+}
+
+func (h *ResourceHandler) CreateResource(c *gin.Context) {
+	var req ResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	branch, ok := resolveBranch(c, h.branches, req.BranchID)
+	if !ok {
+		return
+	}
+
+	resource := &models.Resource{BranchID: branch.ID}
+	applyResourceRequest(resource, req)
+
+	if err := h.repo.CreateResource(resource); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toResourceResponse(resource))
+}
+
+func (h *ResourceHandler) GetResource(c *gin.Context) {
+	resource, ok := h.loadResource(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toResourceResponse(resource))
+}
+
+type ListResourcesQuery struct {
+	PageQuery
+	Kind     string `form:"kind" binding:"omitempty,oneof=room equipment"`
+	Status   string `form:"status" binding:"omitempty,oneof=active inactive"`
+	BranchID uint   `form:"branch_id"`
+}
+
+func (h *ResourceHandler) ListResources(c *gin.Context) {
+	var query ListResourcesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	resources, total, err := h.repo.ListResources(models.ResourceFilter{
+		Kind:     query.Kind,
+		Status:   query.Status,
+		BranchID: query.BranchID,
+		Limit:    page.PageSize,
+		Offset:   page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ResourceResponse, 0, len(resources))
+	for i := range resources {
+		items = append(items, toResourceResponse(&resources[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *ResourceHandler) UpdateResource(c *gin.Context) {
+	var req ResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resource, ok := h.loadResource(c)
+	if !ok {
+		return
+	}
+
+	if req.BranchID != 0 && req.BranchID != resource.BranchID {
+		// Забронированный ресурс нельзя перевезти в другой филиал, пока не перенесены записи
+		count, err := h.repo.CountUpcomingResourceBookings(resource.ID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "resource has upcoming bookings"})
+			return
+		}
+
+		branch, ok := resolveBranch(c, h.branches, req.BranchID)
+		if !ok {
+			return
+		}
+		resource.BranchID = branch.ID
+	}
+	applyResourceRequest(resource, req)
+
+	if err := h.repo.UpdateResource(resource); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toResourceResponse(resource))
+}
+
+func (h *ResourceHandler) DeleteResource(c *gin.Context) {
+	resource, ok := h.loadResource(c)
+	if !ok {
+		return
+	}
+
+	// Ресурс с предстоящими бронированиями удалять нельзя: записи нужно перенести или отменить
+	count, err := h.repo.CountUpcomingResourceBookings(resource.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "resource has upcoming bookings"})
+		return
+	}
+
+	if err := h.repo.DeleteResource(resource.ID); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetOccupancy возвращает занятость ресурсов за день: записи, суммарное время и пиковую загрузку
+func (h *ResourceHandler) GetOccupancy(c *gin.Context) {
+	var query struct {
+		Kind     string `form:"kind" binding:"omitempty,oneof=room equipment"`
+		Date     string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+		BranchID uint   `form:"branch_id"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	day := time.Now()
+	if query.Date != "" {
+		day, _ = time.ParseInLocation(dateLayout, query.Date, time.Local)
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 1)
+
+	resources, _, err := h.repo.ListResources(models.ResourceFilter{
+		Kind:     query.Kind,
+		Status:   models.ResourceStatusActive,
+		BranchID: query.BranchID,
+		Limit:    -1,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookings, err := h.repo.ListResourceBookings(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	byResource := make(map[uint][]models.ResourceBooking)
+	for _, booking := range bookings {
+		byResource[booking.ResourceID] = append(byResource[booking.ResourceID], booking)
+	}
+
+	items := make([]ResourceOccupancyResponse, 0, len(resources))
+	for i := range resources {
+		item := ResourceOccupancyResponse{
+			ResourceResponse: toResourceResponse(&resources[i]),
+			Bookings:         []ResourceBookingResponse{},
+		}
+
+		slots := make([]models.TimeSlot, 0, len(byResource[resources[i].ID]))
+		for _, booking := range byResource[resources[i].ID] {
+			item.Bookings = append(item.Bookings, ResourceBookingResponse{
+				AppointmentID: booking.AppointmentID,
+				ClientID:      booking.ClientID,
+				SpecialistID:  booking.SpecialistID,
+				StartsAt:      booking.StartsAt,
+				EndsAt:        booking.EndsAt,
+			})
+
+			// Записи, выходящие за границы дня, учитываются только в пределах дня
+			slot := models.TimeSlot{StartsAt: booking.StartsAt, EndsAt: booking.EndsAt}
+			if slot.StartsAt.Before(from) {
+				slot.StartsAt = from
+			}
+			if slot.EndsAt.After(to) {
+				slot.EndsAt = to
+			}
+			slots = append(slots, slot)
+			item.BookedMinutes += int(slot.EndsAt.Sub(slot.StartsAt).Minutes())
+		}
+		item.PeakUsage = models.PeakOccupancy(slots)
+
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":      from.Format(dateLayout),
+		"resources": items,
+	})
+}
+
+func (h *ResourceHandler) loadResource(c *gin.Context) (*models.Resource, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid resource ID format"})
+		return nil, false
+	}
+
+	resource, err := h.repo.GetResourceByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "resource not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return resource, true
+}
+
+func applyResourceRequest(resource *models.Resource, req ResourceRequest) {
+	resource.Name = req.Name
+	resource.Kind = req.Kind
+	resource.Capacity = req.Capacity
+	resource.Description = req.Description
+	resource.Status = req.Status
+	if resource.Status == "" {
+		resource.Status = models.ResourceStatusActive
+	}
+}
+
+func toResourceResponse(resource *models.Resource) ResourceResponse {
+	return ResourceResponse{
+		ID:          resource.ID,
+		Name:        resource.Name,
+		Kind:        resource.Kind,
+		Capacity:    resource.Capacity,
+		Status:      resource.Status,
+		Description: resource.Description,
+		BranchID:    resource.BranchID,
+		CreatedAt:   resource.CreatedAt,
+	}
+}
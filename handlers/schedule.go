@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	dateLayout          = "2006-01-02"
+	maxAvailabilityDays = 31
+	defaultSlotMinutes  = 60
+)
+
+type ScheduleHandler struct {
+	repo        models.ScheduleRepository
+	specialists models.SpecialistRepository
+}
+
+func NewScheduleHandler(repo models.ScheduleRepository, specialists models.SpecialistRepository) *ScheduleHandler {
+	return &ScheduleHandler{
+		repo:        repo,
+		specialists: specialists,
+	}
+}
+
+type WorkingHoursRequest struct {
+	Weekday    int    `json:"weekday" binding:"required,min=1,max=7"`
+	StartTime  string `json:"start_time" binding:"required,datetime=15:04"`
+	EndTime    string `json:"end_time" binding:"required,datetime=15:04"`
+	BreakStart string `json:"break_start" binding:"required_with=BreakEnd,omitempty,datetime=15:04"`
+	BreakEnd   string `json:"break_end" binding:"required_with=BreakStart,omitempty,datetime=15:04"`
+}
+
+type WorkingHoursResponse struct {
+	Weekday    int    `json:"weekday"`
+	StartTime  string `json:"start_time"`
+	EndTime    string `json:"end_time"`
+	BreakStart string `json:"break_start,omitempty"`
+	BreakEnd   string `json:"break_end,omitempty"`
+}
+
+type ScheduleExceptionRequest struct {
+	Kind     string `json:"kind" binding:"required,oneof=vacation sick_leave day_off"`
+	StartsOn string `json:"starts_on" binding:"required,datetime=2006-01-02"`
+	EndsOn   string `json:"ends_on" binding:"required,datetime=2006-01-02"`
+	Reason   string `json:"reason" binding:"max=500"`
+}
+
+type ScheduleExceptionResponse struct {
+	ID       uint   `json:"id"`
+	Kind     string `json:"kind"`
+	StartsOn string `json:"starts_on"`
+	EndsOn   string `json:"ends_on"`
+	Reason   string `json:"reason"`
+}
+
+type TimeSlotResponse struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+func (h *ScheduleHandler) GetWorkingHours(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	hours, err := h.repo.GetWorkingHours(specialistID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toWorkingHoursResponses(hours))
+}
+
+// ReplaceWorkingHours заменяет недельный шаблон целиком; дни, отсутствующие в запросе, считаются выходными
+func (h *ScheduleHandler) ReplaceWorkingHours(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	var req []WorkingHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[int]bool, len(req))
+	hours := make([]models.WorkingHours, 0, len(req))
+	for _, item := range req {
+		if seen[item.Weekday] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate weekday " + strconv.Itoa(item.Weekday)})
+			return
+		}
+		seen[item.Weekday] = true
+
+		wh := models.WorkingHours{
+			Weekday:    item.Weekday,
+			StartTime:  item.StartTime,
+			EndTime:    item.EndTime,
+			BreakStart: item.BreakStart,
+			BreakEnd:   item.BreakEnd,
+		}
+		if err := wh.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hours = append(hours, wh)
+	}
+
+	if err := h.repo.ReplaceWorkingHours(specialistID, hours); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toWorkingHoursResponses(hours))
+}
+
+func (h *ScheduleHandler) CreateException(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startsOn, _ := time.ParseInLocation(dateLayout, req.StartsOn, time.Local)
+	endsOn, _ := time.ParseInLocation(dateLayout, req.EndsOn, time.Local)
+	if endsOn.Before(startsOn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must not be before starts_on"})
+		return
+	}
+
+	exception := &models.ScheduleException{
+		SpecialistID: specialistID,
+		Kind:         req.Kind,
+		StartsOn:     startsOn,
+		EndsOn:       endsOn,
+		Reason:       req.Reason,
+	}
+	if err := h.repo.CreateScheduleException(exception); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toScheduleExceptionResponse(exception))
+}
+
+func (h *ScheduleHandler) ListExceptions(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+
+	exceptions, err := h.repo.ListScheduleExceptions(specialistID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ScheduleExceptionResponse, 0, len(exceptions))
+	for i := range exceptions {
+		items = append(items, toScheduleExceptionResponse(&exceptions[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *ScheduleHandler) DeleteException(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	id, err := parseUint(c.Param("exception_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid exception ID format"})
+		return
+	}
+
+	if err := h.repo.DeleteScheduleException(specialistID, id); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "schedule exception not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAvailability возвращает свободные слоты специалиста за период from..to (даты включительно)
+func (h *ScheduleHandler) GetAvailability(c *gin.Context) {
+	specialistID, ok := h.specialistID(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseDateRange(c, true)
+	if !ok {
+		return
+	}
+	if to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date range must not exceed " + strconv.Itoa(maxAvailabilityDays) + " days"})
+		return
+	}
+
+	slotMinutes := defaultSlotMinutes
+	if v := c.Query("slot_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 5 || minutes > 480 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "slot_minutes must be between 5 and 480"})
+			return
+		}
+		slotMinutes = minutes
+	}
+
+	hours, err := h.repo.GetWorkingHours(specialistID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exceptions, err := h.repo.ListScheduleExceptions(specialistID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointments, err := h.repo.ListBusyAppointments(specialistID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	classes, err := h.repo.ListBusyGroupClasses(specialistID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	busy := models.BusyIntervals(appointments, classes)
+	slots := models.ComputeFreeSlots(hours, exceptions, busy, from, to, time.Duration(slotMinutes)*time.Minute, time.Now())
+
+	items := make([]TimeSlotResponse, 0, len(slots))
+	for _, slot := range slots {
+		items = append(items, TimeSlotResponse{StartsAt: slot.StartsAt, EndsAt: slot.EndsAt})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"specialist_id": specialistID,
+		"slot_minutes":  slotMinutes,
+		"slots":         items,
+	})
+}
+
+// specialistID разбирает id специалиста из пути и проверяет его существование
+func (h *ScheduleHandler) specialistID(c *gin.Context) (uint, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist ID format"})
+		return 0, false
+	}
+
+	if _, err := h.specialists.GetSpecialistByID(id); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "specialist not found"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseDateRange разбирает параметры from и to в формате ГГГГ-ММ-ДД
+func parseDateRange(c *gin.Context, required bool) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	for _, p := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := c.Query(p.name)
+		if value == "" {
+			if required {
+				c.JSON(http.StatusBadRequest, gin.H{"error": p.name + " is required"})
+				return from, to, false
+			}
+			continue
+		}
+		parsed, err := time.ParseInLocation(dateLayout, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name + " date, expected YYYY-MM-DD"})
+			return from, to, false
+		}
+		*p.target = parsed
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+func toWorkingHoursResponses(hours []models.WorkingHours) []WorkingHoursResponse {
+	items := make([]WorkingHoursResponse, 0, len(hours))
+	for _, wh := range hours {
+		items = append(items, WorkingHoursResponse{
+			Weekday:    wh.Weekday,
+			StartTime:  wh.StartTime,
+			EndTime:    wh.EndTime,
+			BreakStart: wh.BreakStart,
+			BreakEnd:   wh.BreakEnd,
+		})
+	}
+	return items
+}
+
+func toScheduleExceptionResponse(exception *models.ScheduleException) ScheduleExceptionResponse {
+	return ScheduleExceptionResponse{
+		ID:       exception.ID,
+		Kind:     exception.Kind,
+		StartsOn: exception.StartsOn.Format(dateLayout),
+		EndsOn:   exception.EndsOn.Format(dateLayout),
+		Reason:   exception.Reason,
+	}
+}
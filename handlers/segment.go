@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Источники, по которым вычисляется состав сегмента
+const (
+	segmentSourcePostgres      = "postgres"
+	segmentSourceElasticsearch = "elasticsearch"
+)
+
+type SegmentHandler struct {
+	repo models.SegmentRepository
+	es   utils.ElasticsearchClient
+}
+
+func NewSegmentHandler(repo models.SegmentRepository, es utils.ElasticsearchClient) *SegmentHandler {
+	return &SegmentHandler{
+		repo: repo,
+		es:   es,
+	}
+}
+
+type SegmentRequest struct {
+	Name        string                 `json:"name" binding:"required,max=100"`
+	Description string                 `json:"description" binding:"max=1000"`
+	Criteria    models.SegmentCriteria `json:"criteria"`
+}
+
+type SegmentResponse struct {
+	ID          uint                   `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Criteria    models.SegmentCriteria `json:"criteria"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// SegmentClientsQuery - постраничная выдача состава сегмента. Source выбирает, где вычислять
+// сегмент: в PostgreSQL (по умолчанию, все условия) или в Elasticsearch (без истории визитов).
+type SegmentClientsQuery struct {
+	PageQuery
+	Source string `form:"source" binding:"omitempty,oneof=postgres elasticsearch"`
+}
+
+type SegmentCountResponse struct {
+	SegmentID uint   `json:"segment_id"`
+	Source    string `json:"source"`
+	Count     int64  `json:"count"`
+}
+
+func (h *SegmentHandler) CreateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if !bindSegmentRequest(c, &req) {
+		return
+	}
+
+	segment := &models.Segment{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Criteria:    req.Criteria,
+	}
+	if err := h.repo.CreateSegment(segment); err != nil {
+		respondSegmentWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSegmentResponse(segment))
+}
+
+func (h *SegmentHandler) ListSegments(c *gin.Context) {
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.normalize()
+	segments, total, err := h.repo.ListSegments(page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]SegmentResponse, 0, len(segments))
+	for i := range segments {
+		items = append(items, toSegmentResponse(&segments[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *SegmentHandler) GetSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toSegmentResponse(segment))
+}
+
+func (h *SegmentHandler) UpdateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if !bindSegmentRequest(c, &req) {
+		return
+	}
+
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	segment.Name = strings.TrimSpace(req.Name)
+	segment.Description = req.Description
+	segment.Criteria = req.Criteria
+	if err := h.repo.UpdateSegment(segment); err != nil {
+		respondSegmentWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSegmentResponse(segment))
+}
+
+func (h *SegmentHandler) DeleteSegment(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid segment ID format"})
+		return
+	}
+
+	if err := h.repo.DeleteSegment(id); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "segment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSegmentClients возвращает текущий состав сегмента
+func (h *SegmentHandler) ListSegmentClients(c *gin.Context) {
+	var query SegmentClientsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	if query.Source == segmentSourceElasticsearch {
+		if !h.checkSearchable(c, segment) {
+			return
+		}
+
+		searchQuery := map[string]interface{}{
+			"query": segmentSearchQuery(segment.Criteria),
+			"sort":  []interface{}{map[string]interface{}{"id": "asc"}},
+			"from":  page.offset(),
+			"size":  page.PageSize,
+		}
+		results, err := h.es.SearchClients(c.Request.Context(), "clients", searchQuery)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		total, err := h.es.CountClients(c.Request.Context(), "clients", map[string]interface{}{"query": searchQuery["query"]})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, newPageResponse(c, decodeClientHits(results), total, page))
+		return
+	}
+
+	clients, total, err := h.repo.ListSegmentClients(segment.Criteria, time.Now(), page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, toClientResponses(clients), total, page))
+}
+
+// CountSegment возвращает размер сегмента без выборки клиентов
+func (h *SegmentHandler) CountSegment(c *gin.Context) {
+	var query SegmentClientsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+
+	resp := SegmentCountResponse{SegmentID: segment.ID, Source: segmentSourcePostgres}
+	var err error
+	if query.Source == segmentSourceElasticsearch {
+		if !h.checkSearchable(c, segment) {
+			return
+		}
+		resp.Source = segmentSourceElasticsearch
+		resp.Count, err = h.es.CountClients(c.Request.Context(), "clients", map[string]interface{}{
+			"query": segmentSearchQuery(segment.Criteria),
+		})
+	} else {
+		_, resp.Count, err = h.repo.ListSegmentClients(segment.Criteria, time.Now(), 0, 0)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// checkSearchable проверяет, что сегмент можно вычислить в Elasticsearch
+func (h *SegmentHandler) checkSearchable(c *gin.Context, segment *models.Segment) bool {
+	if h.es == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Elasticsearch service is not available"})
+		return false
+	}
+	if segment.Criteria.NeedsVisitHistory() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no_visit_days is only supported with source=postgres"})
+		return false
+	}
+	return true
+}
+
+// segmentSearchQuery переводит условия сегмента в bool-запрос к индексу клиентов
+func segmentSearchQuery(criteria models.SegmentCriteria) map[string]interface{} {
+	filters := []map[string]interface{}{}
+	term := func(field string, value interface{}) {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{field: value},
+		})
+	}
+
+	if criteria.Gender != "" {
+		term("gender", criteria.Gender)
+	}
+	ageRange := map[string]interface{}{}
+	if criteria.AgeMin > 0 {
+		ageRange["gte"] = criteria.AgeMin
+	}
+	if criteria.AgeMax > 0 {
+		ageRange["lte"] = criteria.AgeMax
+	}
+	if len(ageRange) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"age": ageRange},
+		})
+	}
+	if len(criteria.AdvertisingChannels) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"advertising_channel": criteria.AdvertisingChannels},
+		})
+	}
+	if criteria.SpecialistID != nil {
+		term("specialist_id", *criteria.SpecialistID)
+	}
+	if criteria.BranchID != nil {
+		term("branch_id", *criteria.BranchID)
+	}
+	// Каждая обязательная метка - отдельный фильтр, чтобы требовались все сразу
+	for _, id := range criteria.TagIDs {
+		term("tags.id", id)
+	}
+
+	query := map[string]interface{}{"filter": filters}
+	if len(criteria.ExcludeTagIDs) > 0 {
+		query["must_not"] = []map[string]interface{}{{
+			"terms": map[string]interface{}{"tags.id": criteria.ExcludeTagIDs},
+		}}
+	}
+	return map[string]interface{}{"bool": query}
+}
+
+func bindSegmentRequest(c *gin.Context, req *SegmentRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := req.Criteria.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *SegmentHandler) loadSegment(c *gin.Context) (*models.Segment, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid segment ID format"})
+		return nil, false
+	}
+
+	segment, err := h.repo.GetSegment(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "segment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return segment, true
+}
+
+func respondSegmentWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "segment with this name already exists"})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "segment not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func toSegmentResponse(segment *models.Segment) SegmentResponse {
+	return SegmentResponse{
+		ID:          segment.ID,
+		Name:        segment.Name,
+		Description: segment.Description,
+		Criteria:    segment.Criteria,
+		CreatedAt:   segment.CreatedAt,
+		UpdatedAt:   segment.UpdatedAt,
+	}
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionNoteHandler struct {
+	repo         models.SessionNoteRepository
+	clients      models.Repository
+	specialists  models.SpecialistRepository
+	appointments models.AppointmentRepository
+}
+
+func NewSessionNoteHandler(repo models.SessionNoteRepository, clients models.Repository, specialists models.SpecialistRepository, appointments models.AppointmentRepository) *SessionNoteHandler {
+	return &SessionNoteHandler{
+		repo:         repo,
+		clients:      clients,
+		specialists:  specialists,
+		appointments: appointments,
+	}
+}
+
+// SessionNoteContent - структурированные разделы заметки и свободный текст
+type SessionNoteContent struct {
+	Complaints      string `json:"complaints" binding:"max=5000"`
+	Assessment      string `json:"assessment" binding:"max=5000"`
+	Procedures      string `json:"procedures" binding:"max=5000"`
+	Recommendations string `json:"recommendations" binding:"max=5000"`
+	Text            string `json:"text" binding:"max=20000"`
+}
+
+type CreateSessionNoteRequest struct {
+	SpecialistID  uint   `json:"specialist_id" binding:"required"`
+	AppointmentID *uint  `json:"appointment_id"`
+	VisitDate     string `json:"visit_date" binding:"required,datetime=2006-01-02"`
+	SessionNoteContent
+}
+
+// UpdateSessionNoteRequest создает новую версию заметки. BaseVersion - версия, которую видел автор правки;
+// если ее указать, правка поверх чужих изменений будет отклонена.
+type UpdateSessionNoteRequest struct {
+	AuthorID    uint `json:"author_id" binding:"required"`
+	BaseVersion int  `json:"base_version" binding:"min=0"`
+	SessionNoteContent
+}
+
+type SessionNoteResponse struct {
+	ID            uint      `json:"id"`
+	ClientID      uint      `json:"client_id"`
+	SpecialistID  uint      `json:"specialist_id"`
+	AppointmentID *uint     `json:"appointment_id"`
+	VisitDate     string    `json:"visit_date"`
+	Version       int       `json:"version"`
+	EditedBy      uint      `json:"edited_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	EditedAt      time.Time `json:"edited_at"`
+	SessionNoteContent
+}
+
+type SessionNoteVersionResponse struct {
+	Version   int       `json:"version"`
+	AuthorID  uint      `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+	SessionNoteContent
+}
+
+func (h *SessionNoteHandler) CreateNote(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req CreateSessionNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SessionNoteContent.isEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "note content is empty"})
+		return
+	}
+
+	if req.AppointmentID != nil {
+		appointment, err := h.appointments.GetAppointmentByID(*req.AppointmentID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "appointment not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if appointment.ClientID != clientID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "appointment belongs to another client"})
+			return
+		}
+	}
+
+	visitDate, _ := time.ParseInLocation(dateLayout, req.VisitDate, time.Local)
+	note := &models.SessionNote{
+		ClientID:      clientID,
+		SpecialistID:  req.SpecialistID,
+		AppointmentID: req.AppointmentID,
+		VisitDate:     visitDate,
+	}
+	content := req.SessionNoteContent.toVersion(req.SpecialistID)
+
+	if err := h.repo.CreateSessionNote(note, content); err != nil {
+		respondSessionNoteWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSessionNoteResponse(note))
+}
+
+func (h *SessionNoteHandler) ListNotes(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var page PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page = page.normalize()
+
+	notes, total, err := h.repo.ListSessionNotes(clientID, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]SessionNoteResponse, 0, len(notes))
+	for i := range notes {
+		items = append(items, toSessionNoteResponse(&notes[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *SessionNoteHandler) GetNote(c *gin.Context) {
+	clientID, noteID, ok := h.noteIDs(c)
+	if !ok {
+		return
+	}
+
+	note, err := h.repo.GetSessionNote(clientID, noteID)
+	if err != nil {
+		respondSessionNoteWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSessionNoteResponse(note))
+}
+
+// UpdateNote не изменяет заметку, а добавляет ее новую версию
+func (h *SessionNoteHandler) UpdateNote(c *gin.Context) {
+	clientID, noteID, ok := h.noteIDs(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSessionNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SessionNoteContent.isEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "note content is empty"})
+		return
+	}
+
+	note, err := h.repo.AddSessionNoteVersion(clientID, noteID, req.BaseVersion, req.SessionNoteContent.toVersion(req.AuthorID))
+	if err != nil {
+		respondSessionNoteWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSessionNoteResponse(note))
+}
+
+func (h *SessionNoteHandler) ListNoteVersions(c *gin.Context) {
+	clientID, noteID, ok := h.noteIDs(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.repo.ListSessionNoteVersions(clientID, noteID)
+	if err != nil {
+		respondSessionNoteWriteError(c, err)
+		return
+	}
+
+	items := make([]SessionNoteVersionResponse, 0, len(versions))
+	for i := range versions {
+		items = append(items, toSessionNoteVersionResponse(&versions[i]))
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *SessionNoteHandler) GetNoteVersion(c *gin.Context) {
+	clientID, noteID, ok := h.noteIDs(c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	content, err := h.repo.GetSessionNoteVersion(clientID, noteID, version)
+	if err != nil {
+		respondSessionNoteWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSessionNoteVersionResponse(content))
+}
+
+func (h *SessionNoteHandler) noteIDs(c *gin.Context) (uint, uint, bool) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return 0, 0, false
+	}
+
+	noteID, err := parseUint(c.Param("note_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid note ID format"})
+		return 0, 0, false
+	}
+	return clientID, noteID, true
+}
+
+func respondSessionNoteWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "session note not found"})
+	case errors.Is(err, models.ErrStaleVersion):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (content SessionNoteContent) isEmpty() bool {
+	return content.Complaints == "" && content.Assessment == "" && content.Procedures == "" &&
+		content.Recommendations == "" && content.Text == ""
+}
+
+func (content SessionNoteContent) toVersion(authorID uint) *models.SessionNoteVersion {
+	return &models.SessionNoteVersion{
+		AuthorID:        authorID,
+		Complaints:      content.Complaints,
+		Assessment:      content.Assessment,
+		Procedures:      content.Procedures,
+		Recommendations: content.Recommendations,
+		Text:            content.Text,
+	}
+}
+
+func toSessionNoteContent(version *models.SessionNoteVersion) SessionNoteContent {
+	return SessionNoteContent{
+		Complaints:      version.Complaints,
+		Assessment:      version.Assessment,
+		Procedures:      version.Procedures,
+		Recommendations: version.Recommendations,
+		Text:            version.Text,
+	}
+}
+
+func toSessionNoteResponse(note *models.SessionNote) SessionNoteResponse {
+	resp := SessionNoteResponse{
+		ID:            note.ID,
+		ClientID:      note.ClientID,
+		SpecialistID:  note.SpecialistID,
+		AppointmentID: note.AppointmentID,
+		VisitDate:     note.VisitDate.Format(dateLayout),
+		Version:       note.CurrentVersion,
+		CreatedAt:     note.CreatedAt,
+	}
+	if note.Latest != nil {
+		resp.EditedBy = note.Latest.AuthorID
+		resp.EditedAt = note.Latest.CreatedAt
+		resp.SessionNoteContent = toSessionNoteContent(note.Latest)
+	}
+	return resp
+}
+
+func toSessionNoteVersionResponse(version *models.SessionNoteVersion) SessionNoteVersionResponse {
+	return SessionNoteVersionResponse{
+		Version:            version.Version,
+		AuthorID:           version.AuthorID,
+		CreatedAt:          version.CreatedAt,
+		SessionNoteContent: toSessionNoteContent(version),
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SpecialistHandler struct {
+	repo     models.SpecialistRepository
+	clients  models.Repository
+	branches models.BranchRepository
+	kafka    utils.KafkaProducer
+}
+
+func NewSpecialistHandler(repo models.SpecialistRepository, clients models.Repository, branches models.BranchRepository, kafka utils.KafkaProducer) *SpecialistHandler {
+	return &SpecialistHandler{
+		repo:     repo,
+		clients:  clients,
+		branches: branches,
+		kafka:    kafka,
+	}
+}
+
+type SpecialistRequest struct {
+	FullName       string `json:"full_name" binding:"required,min=2,max=100"`
+	Specialization string `json:"specialization" binding:"required,max=100"`
+	Phone          string `json:"phone" binding:"required,e164"`
+	Email          string `json:"email" binding:"omitempty,email"`
+	Status         string `json:"status" binding:"omitempty,oneof=active on_leave dismissed"`
+	// BranchID - филиал специалиста; при создании без него - основной, при изменении без него - прежний.
+	// Уже назначенные записи остаются в том филиале, где были сделаны.
+	BranchID uint `json:"branch_id"`
+}
+
+type SpecialistResponse struct {
+	ID             uint      `json:"id"`
+	FullName       string    `json:"full_name"`
+	Specialization string    `json:"specialization"`
+	Phone          string    `json:"phone"`
+	Email          string    `json:"email"`
+	Status         string    `json:"status"`
+	BranchID       uint      `json:"branch_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AssignSpecialistRequest - назначение клиенту специалиста; null снимает назначение
+type AssignSpecialistRequest struct {
+	SpecialistID *uint `json:"specialist_id"`
+}
+
+func (h *SpecialistHandler) CreateSpecialist(c *gin.Context) {
+	var req SpecialistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	branch, ok := resolveBranch(c, h.branches, req.BranchID)
+	if !ok {
+		return
+	}
+
+	specialist := &models.Specialist{BranchID: branch.ID}
+	applySpecialistRequest(specialist, req)
+
+	if err := h.repo.CreateSpecialist(specialist); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSpecialistResponse(specialist))
+}
+
+func (h *SpecialistHandler) GetSpecialist(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist ID format"})
+		return
+	}
+
+	specialist, err := h.repo.GetSpecialistByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSpecialistResponse(specialist))
+}
+
+type ListSpecialistsQuery struct {
+	PageQuery
+	Status         string `form:"status" binding:"omitempty,oneof=active on_leave dismissed"`
+	Specialization string `form:"specialization"`
+	BranchID       uint   `form:"branch_id"`
+}
+
+func (h *SpecialistHandler) ListSpecialists(c *gin.Context) {
+	var query ListSpecialistsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	specialists, total, err := h.repo.ListSpecialists(models.SpecialistFilter{
+		Status:         query.Status,
+		Specialization: query.Specialization,
+		BranchID:       query.BranchID,
+		Limit:          page.PageSize,
+		Offset:         page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]SpecialistResponse, 0, len(specialists))
+	for i := range specialists {
+		items = append(items, toSpecialistResponse(&specialists[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *SpecialistHandler) UpdateSpecialist(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist ID format"})
+		return
+	}
+
+	var req SpecialistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	specialist, err := h.repo.GetSpecialistByID(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.BranchID != 0 && req.BranchID != specialist.BranchID {
+		branch, ok := resolveBranch(c, h.branches, req.BranchID)
+		if !ok {
+			return
+		}
+		specialist.BranchID = branch.ID
+	}
+	applySpecialistRequest(specialist, req)
+
+	if err := h.repo.UpdateSpecialist(specialist); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSpecialistResponse(specialist))
+}
+
+func (h *SpecialistHandler) DeleteSpecialist(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist ID format"})
+		return
+	}
+
+	// Специалиста с клиентами удалять нельзя: сначала их нужно переназначить
+	count, err := h.repo.CountSpecialistClients(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "specialist has assigned clients"})
+		return
+	}
+
+	if err := h.repo.DeleteSpecialist(id); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSpecialistClients возвращает клиентов, закрепленных за специалистом
+func (h *SpecialistHandler) ListSpecialistClients(c *gin.Context) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid specialist ID format"})
+		return
+	}
+
+	var page PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page = page.normalize()
+
+	if _, err := h.repo.GetSpecialistByID(id); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	clients, total, err := h.clients.ListClients(models.ClientFilter{
+		SpecialistID: id,
+		SortBy:       "full_name",
+		Limit:        page.PageSize,
+		Offset:       page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]ClientResponse, 0, len(clients))
+	for i := range clients {
+		items = append(items, toClientResponse(&clients[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// AssignClient назначает или переназначает специалиста клиенту
+func (h *SpecialistHandler) AssignClient(c *gin.Context) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return
+	}
+
+	var req AssignSpecialistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.clients.GetClientByID(clientID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.SpecialistID != nil {
+		specialist, err := h.repo.GetSpecialistByID(*req.SpecialistID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if specialist.Status != models.SpecialistStatusActive {
+			c.JSON(http.StatusConflict, gin.H{"error": "specialist is not active"})
+			return
+		}
+	}
+
+	client.SpecialistID = req.SpecialistID
+	if err := h.clients.UpdateClient(client); err != nil {
+		respondClientWriteError(c, err)
+		return
+	}
+
+	if h.kafka != nil {
+		go publishClientEvent(h.kafka, "client_updated", client)
+	}
+
+	c.JSON(http.StatusOK, toClientResponse(client))
+}
+
+func applySpecialistRequest(specialist *models.Specialist, req SpecialistRequest) {
+	specialist.FullName = req.FullName
+	specialist.Specialization = req.Specialization
+	specialist.Phone = req.Phone
+	specialist.Email = req.Email
+	specialist.Status = req.Status
+	if specialist.Status == "" {
+		specialist.Status = models.SpecialistStatusActive
+	}
+}
+
+func toSpecialistResponse(specialist *models.Specialist) SpecialistResponse {
+	return SpecialistResponse{
+		ID:             specialist.ID,
+		FullName:       specialist.FullName,
+		Specialization: specialist.Specialization,
+		Phone:          specialist.Phone,
+		Email:          specialist.Email,
+		Status:         specialist.Status,
+		BranchID:       specialist.BranchID,
+		CreatedAt:      specialist.CreatedAt,
+	}
+}
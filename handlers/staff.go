@@ -0,0 +1,670 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const monthLayout = "2006-01"
+
+type StaffHandler struct {
+	repo        models.StaffRepository
+	specialists models.SpecialistRepository
+	branches    models.BranchRepository
+}
+
+func NewStaffHandler(repo models.StaffRepository, specialists models.SpecialistRepository, branches models.BranchRepository) *StaffHandler {
+	return &StaffHandler{
+		repo:        repo,
+		specialists: specialists,
+		branches:    branches,
+	}
+}
+
+// EmployeeRequest - сотрудник центра. SpecialistID связывает сотрудника с карточкой специалиста
+// и допускается только для должности specialist.
+type EmployeeRequest struct {
+	FullName     string `json:"full_name" binding:"required,min=2,max=100"`
+	Role         string `json:"role" binding:"required,oneof=reception cleaner specialist manager other"`
+	Phone        string `json:"phone" binding:"required,e164"`
+	SpecialistID *uint  `json:"specialist_id" binding:"omitempty,min=1"`
+	Status       string `json:"status" binding:"omitempty,oneof=active dismissed"`
+	// BranchID - основной филиал сотрудника; при создании без него - основной филиал центра, при изменении - прежний
+	BranchID uint `json:"branch_id"`
+}
+
+type EmployeeResponse struct {
+	ID           uint      `json:"id"`
+	FullName     string    `json:"full_name"`
+	Role         string    `json:"role"`
+	Phone        string    `json:"phone"`
+	SpecialistID *uint     `json:"specialist_id"`
+	BranchID     uint      `json:"branch_id"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ListEmployeesQuery struct {
+	PageQuery
+	Role     string `form:"role" binding:"omitempty,oneof=reception cleaner specialist manager other"`
+	Status   string `form:"status" binding:"omitempty,oneof=active dismissed"`
+	BranchID uint   `form:"branch_id"`
+}
+
+// ShiftRequest - плановая смена. Без branch_id смена назначается в основной филиал сотрудника.
+type ShiftRequest struct {
+	EmployeeID   uint      `json:"employee_id" binding:"required"`
+	BranchID     uint      `json:"branch_id"`
+	PlannedStart time.Time `json:"planned_start" binding:"required"`
+	PlannedEnd   time.Time `json:"planned_end" binding:"required,gtfield=PlannedStart"`
+	BreakMinutes int       `json:"break_minutes" binding:"min=0"`
+	Comment      string    `json:"comment" binding:"max=500"`
+}
+
+// UpdateShiftRequest - изменение плана смены. ClockIn и ClockOut исправляют фактические отметки,
+// например забытый уход; без них отметки остаются прежними.
+type UpdateShiftRequest struct {
+	BranchID     uint       `json:"branch_id"`
+	PlannedStart time.Time  `json:"planned_start" binding:"required"`
+	PlannedEnd   time.Time  `json:"planned_end" binding:"required,gtfield=PlannedStart"`
+	BreakMinutes int        `json:"break_minutes" binding:"min=0"`
+	ClockIn      *time.Time `json:"clock_in"`
+	ClockOut     *time.Time `json:"clock_out"`
+	Comment      string     `json:"comment" binding:"max=500"`
+}
+
+// ClockRequest - отметка прихода или ухода; без at отмечается текущее время
+type ClockRequest struct {
+	At *time.Time `json:"at"`
+}
+
+type ListShiftsQuery struct {
+	PageQuery
+	EmployeeID uint   `form:"employee_id"`
+	BranchID   uint   `form:"branch_id"`
+	Status     string `form:"status" binding:"omitempty,oneof=planned started completed cancelled"`
+}
+
+type ShiftResponse struct {
+	ID              uint       `json:"id"`
+	EmployeeID      uint       `json:"employee_id"`
+	BranchID        uint       `json:"branch_id"`
+	PlannedStart    time.Time  `json:"planned_start"`
+	PlannedEnd      time.Time  `json:"planned_end"`
+	BreakMinutes    int        `json:"break_minutes"`
+	ClockIn         *time.Time `json:"clock_in"`
+	ClockOut        *time.Time `json:"clock_out"`
+	Status          string     `json:"status"`
+	PlannedMinutes  int        `json:"planned_minutes"`
+	WorkedMinutes   int        `json:"worked_minutes"`
+	OvertimeMinutes int        `json:"overtime_minutes"`
+	LateMinutes     int        `json:"late_minutes"`
+	Comment         string     `json:"comment"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type TimesheetRowResponse struct {
+	EmployeeID      uint   `json:"employee_id"`
+	FullName        string `json:"full_name"`
+	Role            string `json:"role"`
+	Shifts          int    `json:"shifts"`
+	CompletedShifts int    `json:"completed_shifts"`
+	AbsentShifts    int    `json:"absent_shifts"`
+	OpenShifts      int    `json:"open_shifts"`
+	PlannedMinutes  int    `json:"planned_minutes"`
+	WorkedMinutes   int    `json:"worked_minutes"`
+	OvertimeMinutes int    `json:"overtime_minutes"`
+	LateMinutes     int    `json:"late_minutes"`
+}
+
+type TimesheetResponse struct {
+	Month    string                 `json:"month"`
+	BranchID uint                   `json:"branch_id,omitempty"`
+	Rows     []TimesheetRowResponse `json:"rows"`
+}
+
+func (h *StaffHandler) CreateEmployee(c *gin.Context) {
+	var req EmployeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateSpecialistLink(c, req) {
+		return
+	}
+	branch, ok := resolveBranch(c, h.branches, req.BranchID)
+	if !ok {
+		return
+	}
+
+	employee := &models.Employee{BranchID: branch.ID, Status: models.EmployeeStatusActive}
+	applyEmployeeRequest(employee, req)
+
+	if err := h.repo.CreateEmployee(employee); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "employee for this specialist already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toEmployeeResponse(employee))
+}
+
+func (h *StaffHandler) ListEmployees(c *gin.Context) {
+	var query ListEmployeesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	employees, total, err := h.repo.ListEmployees(models.EmployeeFilter{
+		Role:     query.Role,
+		Status:   query.Status,
+		BranchID: query.BranchID,
+		Limit:    page.PageSize,
+		Offset:   page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]EmployeeResponse, 0, len(employees))
+	for i := range employees {
+		items = append(items, toEmployeeResponse(&employees[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *StaffHandler) GetEmployee(c *gin.Context) {
+	employee, ok := h.loadEmployee(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toEmployeeResponse(employee))
+}
+
+// UpdateEmployee меняет данные сотрудника. Уволенному сотруднику новые смены не планируются,
+// уже запланированные остаются и отменяются отдельно.
+func (h *StaffHandler) UpdateEmployee(c *gin.Context) {
+	var req EmployeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	employee, ok := h.loadEmployee(c)
+	if !ok {
+		return
+	}
+
+	if !h.validateSpecialistLink(c, req) {
+		return
+	}
+	if req.BranchID != 0 && req.BranchID != employee.BranchID {
+		branch, ok := resolveBranch(c, h.branches, req.BranchID)
+		if !ok {
+			return
+		}
+		employee.BranchID = branch.ID
+	}
+	applyEmployeeRequest(employee, req)
+
+	if err := h.repo.UpdateEmployee(employee); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "employee for this specialist already exists"})
+		case errors.Is(err, models.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toEmployeeResponse(employee))
+}
+
+func (h *StaffHandler) CreateShift(c *gin.Context) {
+	var req ShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shift := &models.Shift{
+		EmployeeID:   req.EmployeeID,
+		PlannedStart: req.PlannedStart,
+		PlannedEnd:   req.PlannedEnd,
+		BreakMinutes: req.BreakMinutes,
+		Status:       models.ShiftStatusPlanned,
+		Comment:      req.Comment,
+	}
+	if !validateShiftPlan(c, shift) {
+		return
+	}
+
+	employee, err := h.repo.GetEmployee(req.EmployeeID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "employee not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if employee.Status != models.EmployeeStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "employee is dismissed"})
+		return
+	}
+
+	shift.BranchID = employee.BranchID
+	if req.BranchID != 0 && req.BranchID != employee.BranchID {
+		branch, ok := resolveBranch(c, h.branches, req.BranchID)
+		if !ok {
+			return
+		}
+		shift.BranchID = branch.ID
+	}
+
+	if err := h.repo.CreateShift(shift); err != nil {
+		if errors.Is(err, models.ErrShiftOverlap) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toShiftResponse(shift, time.Now()))
+}
+
+func (h *StaffHandler) ListShifts(c *gin.Context) {
+	var query ListShiftsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	page := query.PageQuery.normalize()
+	shifts, total, err := h.repo.ListShifts(models.ShiftFilter{
+		EmployeeID: query.EmployeeID,
+		BranchID:   query.BranchID,
+		Status:     query.Status,
+		From:       from,
+		To:         to,
+		Limit:      page.PageSize,
+		Offset:     page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	items := make([]ShiftResponse, 0, len(shifts))
+	for i := range shifts {
+		items = append(items, toShiftResponse(&shifts[i], now))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+func (h *StaffHandler) GetShift(c *gin.Context) {
+	shift, ok := h.loadShift(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toShiftResponse(shift, time.Now()))
+}
+
+// UpdateShift меняет план смены и исправляет отметки; отмененную смену менять нельзя
+func (h *StaffHandler) UpdateShift(c *gin.Context) {
+	var req UpdateShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan := &models.Shift{PlannedStart: req.PlannedStart, PlannedEnd: req.PlannedEnd, BreakMinutes: req.BreakMinutes}
+	if !validateShiftPlan(c, plan) {
+		return
+	}
+
+	var branchID uint
+	if req.BranchID != 0 {
+		branch, ok := resolveBranch(c, h.branches, req.BranchID)
+		if !ok {
+			return
+		}
+		branchID = branch.ID
+	}
+
+	// Статус проверяется под блокировкой строки, чтобы не затереть параллельные отметку или отмену
+	h.changeShift(c, func(shift *models.Shift) error {
+		if shift.Status == models.ShiftStatusCancelled {
+			return models.ErrShiftState
+		}
+		shift.PlannedStart = req.PlannedStart
+		shift.PlannedEnd = req.PlannedEnd
+		shift.BreakMinutes = req.BreakMinutes
+		shift.Comment = req.Comment
+		if branchID != 0 {
+			shift.BranchID = branchID
+		}
+		return shift.CorrectClock(req.ClockIn, req.ClockOut)
+	})
+}
+
+// CancelShift отменяет смену, на которую сотрудник еще не пришел
+func (h *StaffHandler) CancelShift(c *gin.Context) {
+	h.changeShift(c, func(shift *models.Shift) error {
+		if shift.Status != models.ShiftStatusPlanned {
+			return models.ErrShiftState
+		}
+		shift.Status = models.ShiftStatusCancelled
+		return nil
+	})
+}
+
+func (h *StaffHandler) ClockIn(c *gin.Context) {
+	at, ok := bindClockTime(c)
+	if !ok {
+		return
+	}
+	h.changeShift(c, func(shift *models.Shift) error {
+		return shift.StartShift(at)
+	})
+}
+
+func (h *StaffHandler) ClockOut(c *gin.Context) {
+	at, ok := bindClockTime(c)
+	if !ok {
+		return
+	}
+	h.changeShift(c, func(shift *models.Shift) error {
+		return shift.FinishShift(at)
+	})
+}
+
+// GetTimesheet возвращает табель за месяц (month=ГГГГ-ММ) по сменам филиала или всего центра
+func (h *StaffHandler) GetTimesheet(c *gin.Context) {
+	month, branchID, rows, ok := h.buildTimesheet(c)
+	if !ok {
+		return
+	}
+
+	items := make([]TimesheetRowResponse, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, toTimesheetRowResponse(row))
+	}
+
+	c.JSON(http.StatusOK, TimesheetResponse{Month: month, BranchID: branchID, Rows: items})
+}
+
+// ExportTimesheet выгружает табель за месяц в CSV; время - в часах с двумя знаками после точки
+func (h *StaffHandler) ExportTimesheet(c *gin.Context) {
+	month, branchID, rows, ok := h.buildTimesheet(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{"employee_id", "full_name", "role", "shifts", "completed_shifts", "absent_shifts",
+		"open_shifts", "planned_hours", "worked_hours", "overtime_hours", "late_minutes"})
+	for _, row := range rows {
+		_ = writer.Write([]string{
+			strconv.FormatUint(uint64(row.EmployeeID), 10),
+			row.FullName,
+			row.Role,
+			strconv.Itoa(row.Shifts),
+			strconv.Itoa(row.CompletedShifts),
+			strconv.Itoa(row.AbsentShifts),
+			strconv.Itoa(row.OpenShifts),
+			formatHours(row.PlannedMinutes),
+			formatHours(row.WorkedMinutes),
+			formatHours(row.OvertimeMinutes),
+			strconv.Itoa(row.LateMinutes),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileName := "timesheet-" + month + ".csv"
+	if branchID != 0 {
+		fileName = fmt.Sprintf("timesheet-%s-branch-%d.csv", month, branchID)
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// buildTimesheet разбирает month и branch_id и строит табель; при ошибке ответ уже отправлен
+func (h *StaffHandler) buildTimesheet(c *gin.Context) (string, uint, []models.TimesheetRow, bool) {
+	month := c.Query("month")
+	if month == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month is required"})
+		return "", 0, nil, false
+	}
+	from, err := time.ParseInLocation(monthLayout, month, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month, expected YYYY-MM"})
+		return "", 0, nil, false
+	}
+
+	branchID, ok := parseBranchQuery(c)
+	if !ok {
+		return "", 0, nil, false
+	}
+
+	rows, err := h.repo.Timesheet(from, from.AddDate(0, 1, 0), branchID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", 0, nil, false
+	}
+	return month, branchID, rows, true
+}
+
+// changeShift применяет change к смене из пути и отправляет результат
+func (h *StaffHandler) changeShift(c *gin.Context, change func(shift *models.Shift) error) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shift ID format"})
+		return
+	}
+
+	shift, err := h.repo.ChangeShift(id, change)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "shift not found"})
+		case errors.Is(err, models.ErrShiftState), errors.Is(err, models.ErrShiftOverlap):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrInvalidClock):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toShiftResponse(shift, time.Now()))
+}
+
+// validateSpecialistLink проверяет ссылку на специалиста; при ошибке ответ уже отправлен
+func (h *StaffHandler) validateSpecialistLink(c *gin.Context, req EmployeeRequest) bool {
+	if req.SpecialistID == nil {
+		return true
+	}
+	if req.Role != models.EmployeeRoleSpecialist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specialist_id is allowed only for the specialist role"})
+		return false
+	}
+	if _, err := h.specialists.GetSpecialistByID(*req.SpecialistID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// loadEmployee загружает сотрудника по id из пути; при ошибке ответ уже отправлен
+func (h *StaffHandler) loadEmployee(c *gin.Context) (*models.Employee, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid employee ID format"})
+		return nil, false
+	}
+
+	employee, err := h.repo.GetEmployee(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return employee, true
+}
+
+// loadShift загружает смену по id из пути; при ошибке ответ уже отправлен
+func (h *StaffHandler) loadShift(c *gin.Context) (*models.Shift, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shift ID format"})
+		return nil, false
+	}
+
+	shift, err := h.repo.GetShift(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shift not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return shift, true
+}
+
+// validateShiftPlan проверяет длительность смены и перерыва; при ошибке ответ уже отправлен
+func validateShiftPlan(c *gin.Context, shift *models.Shift) bool {
+	duration := shift.PlannedEnd.Sub(shift.PlannedStart)
+	if duration > models.MaxShiftDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "shift must not be longer than 24 hours"})
+		return false
+	}
+	if time.Duration(shift.BreakMinutes)*time.Minute >= duration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "break must be shorter than the shift"})
+		return false
+	}
+	return true
+}
+
+// bindClockTime разбирает необязательное тело отметки; при ошибке ответ уже отправлен
+func bindClockTime(c *gin.Context) (time.Time, bool) {
+	var req ClockRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return time.Time{}, false
+		}
+	}
+	if req.At == nil {
+		return time.Now(), true
+	}
+	return *req.At, true
+}
+
+func formatHours(minutes int) string {
+	return strconv.FormatFloat(float64(minutes)/60, 'f', 2, 64)
+}
+
+func applyEmployeeRequest(employee *models.Employee, req EmployeeRequest) {
+	employee.FullName = req.FullName
+	employee.Role = req.Role
+	employee.Phone = req.Phone
+	employee.SpecialistID = req.SpecialistID
+	if req.Status != "" {
+		employee.Status = req.Status
+	}
+}
+
+func toEmployeeResponse(employee *models.Employee) EmployeeResponse {
+	return EmployeeResponse{
+		ID:           employee.ID,
+		FullName:     employee.FullName,
+		Role:         employee.Role,
+		Phone:        employee.Phone,
+		SpecialistID: employee.SpecialistID,
+		BranchID:     employee.BranchID,
+		Status:       employee.Status,
+		CreatedAt:    employee.CreatedAt,
+	}
+}
+
+func toTimesheetRowResponse(row models.TimesheetRow) TimesheetRowResponse {
+	return TimesheetRowResponse{
+		EmployeeID:      row.EmployeeID,
+		FullName:        row.FullName,
+		Role:            row.Role,
+		Shifts:          row.Shifts,
+		CompletedShifts: row.CompletedShifts,
+		AbsentShifts:    row.AbsentShifts,
+		OpenShifts:      row.OpenShifts,
+		PlannedMinutes:  row.PlannedMinutes,
+		WorkedMinutes:   row.WorkedMinutes,
+		OvertimeMinutes: row.OvertimeMinutes,
+		LateMinutes:     row.LateMinutes,
+	}
+}
+
+func toShiftResponse(shift *models.Shift, now time.Time) ShiftResponse {
+	return ShiftResponse{
+		ID:              shift.ID,
+		EmployeeID:      shift.EmployeeID,
+		BranchID:        shift.BranchID,
+		PlannedStart:    shift.PlannedStart,
+		PlannedEnd:      shift.PlannedEnd,
+		BreakMinutes:    shift.BreakMinutes,
+		ClockIn:         shift.ClockIn,
+		ClockOut:        shift.ClockOut,
+		Status:          shift.EffectiveStatus(now),
+		PlannedMinutes:  shift.PlannedMinutes(),
+		WorkedMinutes:   shift.WorkedMinutes(),
+		OvertimeMinutes: shift.OvertimeMinutes(),
+		LateMinutes:     shift.LateMinutes(),
+		Comment:         shift.Comment,
+		CreatedAt:       shift.CreatedAt,
+	}
+}
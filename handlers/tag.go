@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"wellness-step-by-step/step-08/models"
+	"wellness-step-by-step/step-08/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	repo    models.TagRepository
+	clients models.Repository
+	kafka   utils.KafkaProducer
+}
+
+func NewTagHandler(repo models.TagRepository, clients models.Repository, kafka utils.KafkaProducer) *TagHandler {
+	return &TagHandler{
+		repo:    repo,
+		clients: clients,
+		kafka:   kafka,
+	}
+}
+
+type TagRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type ClientTagsRequest struct {
+	TagIDs []uint `json:"tag_ids" binding:"max=50"`
+}
+
+type TagResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := &models.Tag{Name: strings.TrimSpace(req.Name), Color: req.Color}
+	if err := h.repo.CreateTag(tag); err != nil {
+		respondTagWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toTagResponse(tag))
+}
+
+func (h *TagHandler) ListTags(c *gin.Context) {
+	tags, err := h.repo.ListTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toTagResponses(tags))
+}
+
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, ok := h.loadTag(c)
+	if !ok {
+		return
+	}
+
+	tag.Name = strings.TrimSpace(req.Name)
+	tag.Color = req.Color
+	if err := h.repo.UpdateTag(tag); err != nil {
+		respondTagWriteError(c, err)
+		return
+	}
+	h.reindexTaggedClients(tag.ID, nil)
+
+	c.JSON(http.StatusOK, toTagResponse(tag))
+}
+
+// DeleteTag удаляет метку и снимает ее со всех клиентов
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	tag, ok := h.loadTag(c)
+	if !ok {
+		return
+	}
+
+	clientIDs, err := h.repo.TagClientIDs(tag.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.DeleteTag(tag.ID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.reindexTaggedClients(tag.ID, clientIDs)
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetClientTags заменяет набор меток клиента
+func (h *TagHandler) SetClientTags(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req ClientTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, err := h.repo.SetClientTags(clientID, req.TagIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.reindexTaggedClients(0, []uint{clientID})
+
+	c.JSON(http.StatusOK, toTagResponses(tags))
+}
+
+// reindexTaggedClients публикует client_updated для клиентов, у которых изменились метки,
+// чтобы консьюмер обновил их документы в поисковом индексе. Если clientIDs не передан,
+// берутся текущие владельцы метки tagID.
+func (h *TagHandler) reindexTaggedClients(tagID uint, clientIDs []uint) {
+	if h.kafka == nil {
+		return
+	}
+
+	go func() {
+		ids := clientIDs
+		if ids == nil {
+			var err error
+			if ids, err = h.repo.TagClientIDs(tagID); err != nil {
+				log.Printf("Failed to list clients of tag %d: %v", tagID, err)
+				return
+			}
+		}
+
+		for _, id := range ids {
+			client, err := h.clients.GetClientByID(id)
+			if err != nil {
+				log.Printf("Failed to load client %d for reindexing: %v", id, err)
+				continue
+			}
+			publishClientEvent(h.kafka, "client_updated", client)
+		}
+	}()
+}
+
+func (h *TagHandler) loadTag(c *gin.Context) (*models.Tag, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag ID format"})
+		return nil, false
+	}
+
+	tag, err := h.repo.GetTag(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return tag, true
+}
+
+func respondTagWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "tag with this name already exists"})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func toTagResponse(tag *models.Tag) TagResponse {
+	return TagResponse{
+		ID:    tag.ID,
+		Name:  tag.Name,
+		Color: tag.Color,
+	}
+}
+
+func toTagResponses(tags []models.Tag) []TagResponse {
+	items := make([]TagResponse, 0, len(tags))
+	for i := range tags {
+		items = append(items, toTagResponse(&tags[i]))
+	}
+	return items
+}
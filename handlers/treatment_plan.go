@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TreatmentHandler struct {
+	repo         models.TreatmentRepository
+	clients      models.Repository
+	appointments models.AppointmentRepository
+}
+
+func NewTreatmentHandler(repo models.TreatmentRepository, clients models.Repository, appointments models.AppointmentRepository) *TreatmentHandler {
+	return &TreatmentHandler{
+		repo:         repo,
+		clients:      clients,
+		appointments: appointments,
+	}
+}
+
+type TreatmentGoalRequest struct {
+	Description   string   `json:"description" binding:"required,max=500"`
+	Metric        string   `json:"metric" binding:"omitempty,max=50"`
+	BaselineValue *float64 `json:"baseline_value"`
+	TargetValue   *float64 `json:"target_value"`
+}
+
+type TreatmentMilestoneRequest struct {
+	Title string `json:"title" binding:"required,max=200"`
+	DueOn string `json:"due_on" binding:"required,datetime=2006-01-02"`
+}
+
+type CreateTreatmentPlanRequest struct {
+	SpecialistID    uint                        `json:"specialist_id" binding:"required"`
+	Title           string                      `json:"title" binding:"required,max=200"`
+	Description     string                      `json:"description" binding:"max=2000"`
+	PlannedSessions int                         `json:"planned_sessions" binding:"required,min=1,max=500"`
+	StartsOn        string                      `json:"starts_on" binding:"required,datetime=2006-01-02"`
+	EndsOn          string                      `json:"ends_on" binding:"omitempty,datetime=2006-01-02"`
+	Goals           []TreatmentGoalRequest      `json:"goals" binding:"required,min=1,dive"`
+	Milestones      []TreatmentMilestoneRequest `json:"milestones" binding:"dive"`
+}
+
+type UpdateTreatmentPlanRequest struct {
+	Title           string `json:"title" binding:"required,max=200"`
+	Description     string `json:"description" binding:"max=2000"`
+	PlannedSessions int    `json:"planned_sessions" binding:"required,min=1,max=500"`
+	EndsOn          string `json:"ends_on" binding:"omitempty,datetime=2006-01-02"`
+	Status          string `json:"status" binding:"required,oneof=active completed cancelled"`
+}
+
+type MilestoneStatusRequest struct {
+	Reached bool `json:"reached"`
+}
+
+type ProgressMeasurementRequest struct {
+	TreatmentPlanID *uint      `json:"treatment_plan_id"`
+	AppointmentID   *uint      `json:"appointment_id"`
+	Metric          string     `json:"metric" binding:"required,max=50"`
+	Value           *float64   `json:"value" binding:"required"`
+	Unit            string     `json:"unit" binding:"max=20"`
+	MeasuredAt      *time.Time `json:"measured_at"`
+	Comment         string     `json:"comment" binding:"max=1000"`
+}
+
+type TreatmentGoalResponse struct {
+	ID              uint     `json:"id"`
+	Description     string   `json:"description"`
+	Metric          string   `json:"metric,omitempty"`
+	BaselineValue   *float64 `json:"baseline_value"`
+	TargetValue     *float64 `json:"target_value"`
+	LatestValue     *float64 `json:"latest_value"`
+	ProgressPercent *float64 `json:"progress_percent"`
+}
+
+type TreatmentMilestoneResponse struct {
+	ID        uint       `json:"id"`
+	Title     string     `json:"title"`
+	DueOn     string     `json:"due_on"`
+	ReachedAt *time.Time `json:"reached_at"`
+}
+
+type TreatmentPlanResponse struct {
+	ID                uint                         `json:"id"`
+	ClientID          uint                         `json:"client_id"`
+	SpecialistID      uint                         `json:"specialist_id"`
+	Title             string                       `json:"title"`
+	Description       string                       `json:"description"`
+	Status            string                       `json:"status"`
+	StartsOn          string                       `json:"starts_on"`
+	EndsOn            *string                      `json:"ends_on"`
+	PlannedSessions   int                          `json:"planned_sessions"`
+	CompletedSessions int64                        `json:"completed_sessions"`
+	Goals             []TreatmentGoalResponse      `json:"goals"`
+	Milestones        []TreatmentMilestoneResponse `json:"milestones"`
+}
+
+type ProgressMeasurementResponse struct {
+	ID              uint      `json:"id"`
+	Metric          string    `json:"metric"`
+	Value           float64   `json:"value"`
+	Unit            string    `json:"unit"`
+	MeasuredAt      time.Time `json:"measured_at"`
+	TreatmentPlanID *uint     `json:"treatment_plan_id"`
+	AppointmentID   *uint     `json:"appointment_id"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+// ProgressSeries - замеры одного показателя в хронологическом порядке
+type ProgressSeries struct {
+	Metric string                        `json:"metric"`
+	Points []ProgressMeasurementResponse `json:"points"`
+}
+
+func (h *TreatmentHandler) CreatePlan(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req CreateTreatmentPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan := &models.TreatmentPlan{
+		ClientID:        clientID,
+		SpecialistID:    req.SpecialistID,
+		Title:           req.Title,
+		Description:     req.Description,
+		PlannedSessions: req.PlannedSessions,
+		Status:          models.TreatmentPlanStatusActive,
+	}
+	plan.StartsOn, _ = time.ParseInLocation(dateLayout, req.StartsOn, time.Local)
+	if req.EndsOn != "" {
+		endsOn, _ := time.ParseInLocation(dateLayout, req.EndsOn, time.Local)
+		if endsOn.Before(plan.StartsOn) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must not be before starts_on"})
+			return
+		}
+		plan.EndsOn = &endsOn
+	}
+
+	for _, goal := range req.Goals {
+		if goal.Metric != "" && (goal.BaselineValue == nil || goal.TargetValue == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "goal with metric requires baseline_value and target_value"})
+			return
+		}
+		plan.Goals = append(plan.Goals, models.TreatmentGoal{
+			Description:   goal.Description,
+			Metric:        goal.Metric,
+			BaselineValue: goal.BaselineValue,
+			TargetValue:   goal.TargetValue,
+		})
+	}
+	for _, milestone := range req.Milestones {
+		dueOn, _ := time.ParseInLocation(dateLayout, milestone.DueOn, time.Local)
+		plan.Milestones = append(plan.Milestones, models.TreatmentMilestone{
+			Title: milestone.Title,
+			DueOn: dueOn,
+		})
+	}
+
+	if err := h.repo.CreateTreatmentPlan(plan); err != nil {
+		if errors.Is(err, models.ErrInvalidReference) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toTreatmentPlanResponse(plan, 0, nil))
+}
+
+func (h *TreatmentHandler) ListPlans(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	plans, err := h.repo.ListTreatmentPlans(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.planResponses(clientID, plans)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *TreatmentHandler) GetPlan(c *gin.Context) {
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.planResponses(plan.ClientID, []models.TreatmentPlan{*plan})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items[0])
+}
+
+func (h *TreatmentHandler) UpdatePlan(c *gin.Context) {
+	var req UpdateTreatmentPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	plan.Title = req.Title
+	plan.Description = req.Description
+	plan.PlannedSessions = req.PlannedSessions
+	plan.Status = req.Status
+	plan.EndsOn = nil
+	if req.EndsOn != "" {
+		endsOn, _ := time.ParseInLocation(dateLayout, req.EndsOn, time.Local)
+		if endsOn.Before(plan.StartsOn) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must not be before starts_on"})
+			return
+		}
+		plan.EndsOn = &endsOn
+	}
+
+	if err := h.repo.UpdateTreatmentPlan(plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.planResponses(plan.ClientID, []models.TreatmentPlan{*plan})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items[0])
+}
+
+// UpdateMilestone отмечает контрольную точку достигнутой или снимает отметку
+func (h *TreatmentHandler) UpdateMilestone(c *gin.Context) {
+	var req MilestoneStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	milestoneID, err := parseUint(c.Param("milestone_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid milestone ID format"})
+		return
+	}
+
+	var reachedAt *time.Time
+	if req.Reached {
+		now := time.Now()
+		reachedAt = &now
+	}
+
+	milestone, err := h.repo.SetMilestoneReached(plan.ID, milestoneID, reachedAt)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "milestone not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toTreatmentMilestoneResponse(milestone))
+}
+
+func (h *TreatmentHandler) CreateMeasurement(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	var req ProgressMeasurementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	measurement := &models.ProgressMeasurement{
+		ClientID:        clientID,
+		TreatmentPlanID: req.TreatmentPlanID,
+		AppointmentID:   req.AppointmentID,
+		Metric:          req.Metric,
+		Value:           *req.Value,
+		Unit:            req.Unit,
+		MeasuredAt:      time.Now(),
+		Comment:         req.Comment,
+	}
+
+	if req.TreatmentPlanID != nil {
+		if _, err := h.repo.GetTreatmentPlan(clientID, *req.TreatmentPlanID); err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "treatment plan not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Замер, привязанный к визиту, по умолчанию датируется временем визита
+	if req.AppointmentID != nil {
+		appointment, err := h.appointments.GetAppointmentByID(*req.AppointmentID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "appointment not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if appointment.ClientID != clientID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "appointment belongs to another client"})
+			return
+		}
+		measurement.MeasuredAt = appointment.StartsAt
+	}
+	if req.MeasuredAt != nil {
+		measurement.MeasuredAt = *req.MeasuredAt
+	}
+
+	if err := h.repo.CreateProgressMeasurement(measurement); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toProgressMeasurementResponse(measurement))
+}
+
+// GetProgressTimeline возвращает планы клиента с прогрессом по целям и замеры, сгруппированные по показателям
+func (h *TreatmentHandler) GetProgressTimeline(c *gin.Context) {
+	clientID, ok := loadClientID(c, h.clients)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseDateRange(c, false)
+	if !ok {
+		return
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	plans, err := h.repo.ListTreatmentPlans(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	planItems, err := h.planResponses(clientID, plans)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	measurements, err := h.repo.ListProgressMeasurements(clientID, c.Query("metric"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	series := []ProgressSeries{}
+	index := map[string]int{}
+	for i := range measurements {
+		m := &measurements[i]
+		pos, ok := index[m.Metric]
+		if !ok {
+			pos = len(series)
+			index[m.Metric] = pos
+			series = append(series, ProgressSeries{Metric: m.Metric})
+		}
+		series[pos].Points = append(series[pos].Points, toProgressMeasurementResponse(m))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":    clientID,
+		"plans":        planItems,
+		"measurements": series,
+	})
+}
+
+// planResponses дополняет планы числом завершенных сессий и прогрессом целей по последним замерам
+func (h *TreatmentHandler) planResponses(clientID uint, plans []models.TreatmentPlan) ([]TreatmentPlanResponse, error) {
+	measurements, err := h.repo.ListProgressMeasurements(clientID, "", time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TreatmentPlanResponse, 0, len(plans))
+	for i := range plans {
+		completed, err := h.repo.CountCompletedSessions(&plans[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, toTreatmentPlanResponse(&plans[i], completed, measurements))
+	}
+	return items, nil
+}
+
+func (h *TreatmentHandler) loadPlan(c *gin.Context) (*models.TreatmentPlan, bool) {
+	clientID, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return nil, false
+	}
+
+	planID, err := parseUint(c.Param("plan_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid treatment plan ID format"})
+		return nil, false
+	}
+
+	plan, err := h.repo.GetTreatmentPlan(clientID, planID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "treatment plan not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return plan, true
+}
+
+// loadClientID разбирает id клиента из пути и проверяет его существование
+func loadClientID(c *gin.Context, clients models.Repository) (uint, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client ID format"})
+		return 0, false
+	}
+
+	if _, err := clients.GetClientByID(id); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	return id, true
+}
+
+// toTreatmentPlanResponse строит ответ; measurements - замеры клиента в хронологическом порядке
+func toTreatmentPlanResponse(plan *models.TreatmentPlan, completed int64, measurements []models.ProgressMeasurement) TreatmentPlanResponse {
+	resp := TreatmentPlanResponse{
+		ID:                plan.ID,
+		ClientID:          plan.ClientID,
+		SpecialistID:      plan.SpecialistID,
+		Title:             plan.Title,
+		Description:       plan.Description,
+		Status:            plan.Status,
+		StartsOn:          plan.StartsOn.Format(dateLayout),
+		PlannedSessions:   plan.PlannedSessions,
+		CompletedSessions: completed,
+		Goals:             make([]TreatmentGoalResponse, 0, len(plan.Goals)),
+		Milestones:        make([]TreatmentMilestoneResponse, 0, len(plan.Milestones)),
+	}
+	if plan.EndsOn != nil {
+		endsOn := plan.EndsOn.Format(dateLayout)
+		resp.EndsOn = &endsOn
+	}
+
+	// Последний замер каждого показателя, сделанный с начала плана и не привязанный к другому плану
+	latest := map[string]float64{}
+	for _, m := range measurements {
+		if m.MeasuredAt.Before(plan.StartsOn) {
+			continue
+		}
+		if m.TreatmentPlanID != nil && *m.TreatmentPlanID != plan.ID {
+			continue
+		}
+		latest[m.Metric] = m.Value
+	}
+
+	for i := range plan.Goals {
+		goal := &plan.Goals[i]
+		item := TreatmentGoalResponse{
+			ID:            goal.ID,
+			Description:   goal.Description,
+			Metric:        goal.Metric,
+			BaselineValue: goal.BaselineValue,
+			TargetValue:   goal.TargetValue,
+		}
+		if value, ok := latest[goal.Metric]; ok && goal.Metric != "" {
+			item.LatestValue = &value
+			if percent, ok := goal.Progress(value); ok {
+				item.ProgressPercent = &percent
+			}
+		}
+		resp.Goals = append(resp.Goals, item)
+	}
+
+	for i := range plan.Milestones {
+		resp.Milestones = append(resp.Milestones, toTreatmentMilestoneResponse(&plan.Milestones[i]))
+	}
+	return resp
+}
+
+func toTreatmentMilestoneResponse(milestone *models.TreatmentMilestone) TreatmentMilestoneResponse {
+	return TreatmentMilestoneResponse{
+		ID:        milestone.ID,
+		Title:     milestone.Title,
+		DueOn:     milestone.DueOn.Format(dateLayout),
+		ReachedAt: milestone.ReachedAt,
+	}
+}
+
+func toProgressMeasurementResponse(m *models.ProgressMeasurement) ProgressMeasurementResponse {
+	return ProgressMeasurementResponse{
+		ID:              m.ID,
+		Metric:          m.Metric,
+		Value:           m.Value,
+		Unit:            m.Unit,
+		MeasuredAt:      m.MeasuredAt,
+		TreatmentPlanID: m.TreatmentPlanID,
+		AppointmentID:   m.AppointmentID,
+		Comment:         m.Comment,
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"wellness-step-by-step/step-08/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistHandler struct {
+	repo        models.WaitlistRepository
+	clients     models.Repository
+	specialists models.SpecialistRepository
+}
+
+func NewWaitlistHandler(repo models.WaitlistRepository, clients models.Repository, specialists models.SpecialistRepository) *WaitlistHandler {
+	return &WaitlistHandler{
+		repo:        repo,
+		clients:     clients,
+		specialists: specialists,
+	}
+}
+
+// WaitlistWindowRequest - предпочтительное время; weekday 0 означает любой день недели
+type WaitlistWindowRequest struct {
+	Weekday   int    `json:"weekday" binding:"min=0,max=7"`
+	StartTime string `json:"start_time" binding:"required,datetime=15:04"`
+	EndTime   string `json:"end_time" binding:"required,datetime=15:04"`
+}
+
+type WaitlistRequest struct {
+	ClientID     uint                    `json:"client_id" binding:"required"`
+	SpecialistID uint                    `json:"specialist_id" binding:"required"`
+	DateFrom     string                  `json:"date_from" binding:"required,datetime=2006-01-02"`
+	DateTo       string                  `json:"date_to" binding:"required,datetime=2006-01-02"`
+	Windows      []WaitlistWindowRequest `json:"windows" binding:"max=14,dive"`
+	Comment      string                  `json:"comment" binding:"max=500"`
+}
+
+type WaitlistWindowResponse struct {
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+type WaitlistResponse struct {
+	ID             uint                     `json:"id"`
+	ClientID       uint                     `json:"client_id"`
+	SpecialistID   uint                     `json:"specialist_id"`
+	DateFrom       string                   `json:"date_from"`
+	DateTo         string                   `json:"date_to"`
+	Status         string                   `json:"status"`
+	Comment        string                   `json:"comment"`
+	Windows        []WaitlistWindowResponse `json:"windows"`
+	LastNotifiedAt *time.Time               `json:"last_notified_at"`
+	CreatedAt      time.Time                `json:"created_at"`
+}
+
+func (h *WaitlistHandler) CreateEntry(c *gin.Context) {
+	var req WaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dateFrom, _ := time.ParseInLocation(dateLayout, req.DateFrom, time.Local)
+	dateTo, _ := time.ParseInLocation(dateLayout, req.DateTo, time.Local)
+	if dateTo.Before(dateFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date_to must not be before date_from"})
+		return
+	}
+
+	entry := &models.WaitlistEntry{
+		ClientID:     req.ClientID,
+		SpecialistID: req.SpecialistID,
+		DateFrom:     dateFrom,
+		DateTo:       dateTo,
+		Status:       models.WaitlistStatusWaiting,
+		Comment:      req.Comment,
+	}
+	for _, w := range req.Windows {
+		window := models.WaitlistWindow{Weekday: w.Weekday, StartTime: w.StartTime, EndTime: w.EndTime}
+		if err := window.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entry.Windows = append(entry.Windows, window)
+	}
+
+	if _, err := h.clients.GetClientByID(req.ClientID); err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	specialist, err := h.specialists.GetSpecialistByID(req.SpecialistID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "specialist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if specialist.Status == models.SpecialistStatusDismissed {
+		c.JSON(http.StatusConflict, gin.H{"error": "specialist is dismissed"})
+		return
+	}
+
+	if err := h.repo.CreateWaitlistEntry(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toWaitlistResponse(entry))
+}
+
+func (h *WaitlistHandler) GetEntry(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toWaitlistResponse(entry))
+}
+
+type ListWaitlistQuery struct {
+	PageQuery
+	ClientID     uint   `form:"client_id"`
+	SpecialistID uint   `form:"specialist_id"`
+	BranchID     uint   `form:"branch_id"`
+	Status       string `form:"status" binding:"omitempty,oneof=waiting fulfilled cancelled"`
+}
+
+func (h *WaitlistHandler) ListEntries(c *gin.Context) {
+	var query ListWaitlistQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := query.PageQuery.normalize()
+	entries, total, err := h.repo.ListWaitlistEntries(models.WaitlistFilter{
+		ClientID:     query.ClientID,
+		SpecialistID: query.SpecialistID,
+		BranchID:     query.BranchID,
+		Status:       query.Status,
+		Limit:        page.PageSize,
+		Offset:       page.offset(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]WaitlistResponse, 0, len(entries))
+	for i := range entries {
+		items = append(items, toWaitlistResponse(&entries[i]))
+	}
+
+	c.JSON(http.StatusOK, newPageResponse(c, items, total, page))
+}
+
+// CancelEntry снимает клиента с листа ожидания
+func (h *WaitlistHandler) CancelEntry(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+
+	if entry.Status != models.WaitlistStatusWaiting {
+		c.JSON(http.StatusConflict, gin.H{"error": "waitlist entry is already " + entry.Status})
+		return
+	}
+
+	entry.Status = models.WaitlistStatusCancelled
+	if err := h.repo.UpdateWaitlistEntry(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toWaitlistResponse(entry))
+}
+
+func (h *WaitlistHandler) loadEntry(c *gin.Context) (*models.WaitlistEntry, bool) {
+	id, err := parseUint(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid waitlist entry ID format"})
+		return nil, false
+	}
+
+	entry, err := h.repo.GetWaitlistEntry(id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "waitlist entry not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return entry, true
+}
+
+func toWaitlistResponse(entry *models.WaitlistEntry) WaitlistResponse {
+	windows := make([]WaitlistWindowResponse, 0, len(entry.Windows))
+	for _, w := range entry.Windows {
+		windows = append(windows, WaitlistWindowResponse{
+			Weekday:   w.Weekday,
+			StartTime: w.StartTime,
+			EndTime:   w.EndTime,
+		})
+	}
+	return WaitlistResponse{
+		ID:             entry.ID,
+		ClientID:       entry.ClientID,
+		SpecialistID:   entry.SpecialistID,
+		DateFrom:       entry.DateFrom.Format(dateLayout),
+		DateTo:         entry.DateTo.Format(dateLayout),
+		Status:         entry.Status,
+		Comment:        entry.Comment,
+		Windows:        windows,
+		LastNotifiedAt: entry.LastNotifiedAt,
+		CreatedAt:      entry.CreatedAt,
+	}
+}
@@ -135,15 +135,50 @@ func main() {
 		}()
 	}
 
-	// 5. Инициализация обработчиков
-	clientHandler := handlers.NewClientHandler(dbRepo, kafkaProducer, esClient)
+	// 5. Инициализация файлового хранилища
+	fileStorage, err := utils.NewFileStorage()
+	if err != nil {
+		logger.Printf("WARNING: File storage initialization failed: %v", err)
+	}
 
-	// 6. Инициализация Consumer
+	// 6. Инициализация обработчиков
+	clientHandler := handlers.NewClientHandler(dbRepo, dbRepo, dbRepo, kafkaProducer, esClient)
+	specialistHandler := handlers.NewSpecialistHandler(dbRepo, dbRepo, dbRepo, kafkaProducer)
+	appointmentHandler := handlers.NewAppointmentHandler(dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, kafkaProducer)
+	scheduleHandler := handlers.NewScheduleHandler(dbRepo, dbRepo)
+	sessionNoteHandler := handlers.NewSessionNoteHandler(dbRepo, dbRepo, dbRepo, dbRepo)
+	treatmentHandler := handlers.NewTreatmentHandler(dbRepo, dbRepo, dbRepo)
+	packageHandler := handlers.NewPackageHandler(dbRepo, dbRepo, kafkaProducer)
+	invoiceHandler := handlers.NewInvoiceHandler(dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, dbRepo, kafkaProducer)
+	productHandler := handlers.NewProductHandler(dbRepo, dbRepo, kafkaProducer)
+	waitlistHandler := handlers.NewWaitlistHandler(dbRepo, dbRepo, dbRepo)
+	resourceHandler := handlers.NewResourceHandler(dbRepo, dbRepo)
+	groupClassHandler := handlers.NewGroupClassHandler(dbRepo, dbRepo, dbRepo, dbRepo, kafkaProducer)
+	questionnaireHandler := handlers.NewQuestionnaireHandler(dbRepo, dbRepo)
+	consentHandler := handlers.NewConsentHandler(dbRepo, dbRepo)
+	attachmentHandler := handlers.NewAttachmentHandler(dbRepo, dbRepo, fileStorage)
+	tagHandler := handlers.NewTagHandler(dbRepo, dbRepo, kafkaProducer)
+	segmentHandler := handlers.NewSegmentHandler(dbRepo, esClient)
+	channelHandler := handlers.NewChannelHandler(dbRepo)
+	branchHandler := handlers.NewBranchHandler(dbRepo)
+	staffHandler := handlers.NewStaffHandler(dbRepo, dbRepo, dbRepo)
+	referralHandler := handlers.NewReferralHandler(dbRepo, dbRepo)
+	loyaltyHandler := handlers.NewLoyaltyHandler(dbRepo, dbRepo, dbRepo, kafkaProducer)
+	promoCodeHandler := handlers.NewPromoCodeHandler(dbRepo, dbRepo)
+	giftCertificateHandler := handlers.NewGiftCertificateHandler(dbRepo, dbRepo)
+	feedbackHandler := handlers.NewFeedbackHandler(dbRepo, dbRepo, kafkaProducer)
+	duplicateHandler := handlers.NewDuplicateHandler(dbRepo, dbRepo, kafkaProducer, esClient)
+	organizationHandler := handlers.NewOrganizationHandler(dbRepo, dbRepo)
+
+	// Клиенты, получившие филиал при миграции, переиндексируются, чтобы поиск по branch_id их находил
+	clientHandler.ReindexClients(dbRepo.BackfilledClientIDs())
+
+	// 7. Инициализация Consumer
 	clientConsumer := consumer.NewClientConsumer(dbRepo, redisClient, esClient)
 	go clientConsumer.Start(context.Background())
 	defer clientConsumer.Stop()
 
-	// 7. Настройка маршрутов
+	// 8. Настройка маршрутов
 	router := gin.New()
 	router.Use(middleware.SentryMiddleware())
 	router.Use(gin.Logger(), gin.Recovery())
@@ -166,10 +201,197 @@ func main() {
 	api := router.Group("/api/v1")
 	{
 		api.POST("/clients", clientHandler.CreateClient)
+		api.GET("/clients", clientHandler.ListClients)
 		api.GET("/clients/:id", clientHandler.GetClient)
 		api.PUT("/clients/:id", clientHandler.UpdateClient)
 		api.DELETE("/clients/:id", clientHandler.DeleteClient)
 		api.GET("/clients/search", clientHandler.SearchClients) // Новый endpoint для поиска
+		api.PUT("/clients/:id/specialist", specialistHandler.AssignClient)
+		api.POST("/clients/:id/notes", sessionNoteHandler.CreateNote)
+		api.GET("/clients/:id/notes", sessionNoteHandler.ListNotes)
+		api.GET("/clients/:id/notes/:note_id", sessionNoteHandler.GetNote)
+		api.PUT("/clients/:id/notes/:note_id", sessionNoteHandler.UpdateNote)
+		api.GET("/clients/:id/notes/:note_id/versions", sessionNoteHandler.ListNoteVersions)
+		api.GET("/clients/:id/notes/:note_id/versions/:version", sessionNoteHandler.GetNoteVersion)
+		api.POST("/clients/:id/treatment-plans", treatmentHandler.CreatePlan)
+		api.GET("/clients/:id/treatment-plans", treatmentHandler.ListPlans)
+		api.GET("/clients/:id/treatment-plans/:plan_id", treatmentHandler.GetPlan)
+		api.PUT("/clients/:id/treatment-plans/:plan_id", treatmentHandler.UpdatePlan)
+		api.PATCH("/clients/:id/treatment-plans/:plan_id/milestones/:milestone_id", treatmentHandler.UpdateMilestone)
+		api.POST("/clients/:id/measurements", treatmentHandler.CreateMeasurement)
+		api.GET("/clients/:id/progress", treatmentHandler.GetProgressTimeline)
+		api.POST("/clients/:id/packages", packageHandler.CreatePackage)
+		api.GET("/clients/:id/packages", packageHandler.ListPackages)
+		api.GET("/clients/:id/packages/:package_id", packageHandler.GetPackage)
+		api.POST("/clients/:id/packages/:package_id/freeze", packageHandler.FreezePackage)
+		api.POST("/clients/:id/packages/:package_id/unfreeze", packageHandler.UnfreezePackage)
+		api.DELETE("/clients/:id/packages/:package_id", packageHandler.CancelPackage)
+		api.GET("/clients/:id/balance", invoiceHandler.GetClientBalance)
+
+		api.POST("/specialists", specialistHandler.CreateSpecialist)
+		api.GET("/specialists", specialistHandler.ListSpecialists)
+		api.GET("/specialists/:id", specialistHandler.GetSpecialist)
+		api.PUT("/specialists/:id", specialistHandler.UpdateSpecialist)
+		api.DELETE("/specialists/:id", specialistHandler.DeleteSpecialist)
+		api.GET("/specialists/:id/clients", specialistHandler.ListSpecialistClients)
+		api.GET("/specialists/:id/working-hours", scheduleHandler.GetWorkingHours)
+		api.PUT("/specialists/:id/working-hours", scheduleHandler.ReplaceWorkingHours)
+		api.GET("/specialists/:id/exceptions", scheduleHandler.ListExceptions)
+		api.POST("/specialists/:id/exceptions", scheduleHandler.CreateException)
+		api.DELETE("/specialists/:id/exceptions/:exception_id", scheduleHandler.DeleteException)
+		api.GET("/specialists/:id/availability", scheduleHandler.GetAvailability)
+
+		api.POST("/appointments", appointmentHandler.CreateAppointment)
+		api.GET("/appointments", appointmentHandler.ListAppointments)
+		api.GET("/appointments/:id", appointmentHandler.GetAppointment)
+		api.PUT("/appointments/:id", appointmentHandler.RescheduleAppointment)
+		api.PATCH("/appointments/:id/status", appointmentHandler.UpdateAppointmentStatus)
+		api.POST("/appointments/:id/survey", feedbackHandler.IssueSurvey)
+		api.GET("/appointments/:id/survey", feedbackHandler.GetAppointmentSurvey)
+
+		api.POST("/resources", resourceHandler.CreateResource)
+		api.GET("/resources", resourceHandler.ListResources)
+		api.GET("/resources/occupancy", resourceHandler.GetOccupancy)
+		api.GET("/resources/:id", resourceHandler.GetResource)
+		api.PUT("/resources/:id", resourceHandler.UpdateResource)
+		api.DELETE("/resources/:id", resourceHandler.DeleteResource)
+
+		api.POST("/questionnaires", questionnaireHandler.CreateTemplate)
+		api.GET("/questionnaires", questionnaireHandler.ListTemplates)
+		api.GET("/questionnaires/:id", questionnaireHandler.GetTemplate)
+		api.PUT("/questionnaires/:id", questionnaireHandler.UpdateTemplate)
+		api.POST("/clients/:id/questionnaires", questionnaireHandler.FillQuestionnaire)
+		api.GET("/clients/:id/questionnaires", questionnaireHandler.ListResponses)
+		api.GET("/clients/:id/questionnaires/:response_id", questionnaireHandler.GetResponse)
+
+		api.POST("/consents", consentHandler.CreateTemplate)
+		api.GET("/consents", consentHandler.ListTemplates)
+		api.GET("/consents/:id", consentHandler.GetTemplate)
+		api.PUT("/consents/:id", consentHandler.UpdateTemplate)
+		api.GET("/consents/:id/render", consentHandler.RenderDocument)
+		api.POST("/clients/:id/consents", consentHandler.SignConsent)
+		api.GET("/clients/:id/consents", consentHandler.ListSignatures)
+		api.GET("/clients/:id/consents/:signature_id", consentHandler.GetSignature)
+		api.GET("/clients/:id/consents/:signature_id/image", consentHandler.GetSignatureImage)
+		api.DELETE("/clients/:id/consents/:signature_id", consentHandler.RevokeSignature)
+
+		api.POST("/clients/:id/attachments", attachmentHandler.UploadAttachment)
+		api.GET("/clients/:id/attachments", attachmentHandler.ListAttachments)
+		api.GET("/clients/:id/attachments/:attachment_id", attachmentHandler.GetAttachment)
+		api.GET("/clients/:id/attachments/:attachment_id/download", attachmentHandler.DownloadAttachment)
+		api.DELETE("/clients/:id/attachments/:attachment_id", attachmentHandler.DeleteAttachment)
+
+		api.POST("/tags", tagHandler.CreateTag)
+		api.GET("/tags", tagHandler.ListTags)
+		api.PUT("/tags/:id", tagHandler.UpdateTag)
+		api.DELETE("/tags/:id", tagHandler.DeleteTag)
+		api.PUT("/clients/:id/tags", tagHandler.SetClientTags)
+		api.GET("/clients/:id/duplicates", duplicateHandler.FindDuplicates)
+		api.POST("/clients/:id/merge", duplicateHandler.MergeClients)
+
+		api.POST("/organizations", organizationHandler.CreateOrganization)
+		api.GET("/organizations", organizationHandler.ListOrganizations)
+		api.GET("/organizations/:id", organizationHandler.GetOrganization)
+		api.PUT("/organizations/:id", organizationHandler.UpdateOrganization)
+		api.GET("/organizations/:id/clients", organizationHandler.ListOrganizationClients)
+		api.GET("/organizations/:id/balance", invoiceHandler.GetOrganizationBalance)
+		api.POST("/clients/:id/relationships", organizationHandler.CreateRelationship)
+		api.GET("/clients/:id/relationships", organizationHandler.ListRelationships)
+		api.DELETE("/clients/:id/relationships/:relationship_id", organizationHandler.DeleteRelationship)
+		api.GET("/clients/:id/dependents", organizationHandler.ListDependents)
+
+		api.POST("/branches", branchHandler.CreateBranch)
+		api.GET("/branches", branchHandler.ListBranches)
+		api.GET("/branches/:id", branchHandler.GetBranch)
+		api.PUT("/branches/:id", branchHandler.UpdateBranch)
+
+		api.POST("/employees", staffHandler.CreateEmployee)
+		api.GET("/employees", staffHandler.ListEmployees)
+		api.GET("/employees/:id", staffHandler.GetEmployee)
+		api.PUT("/employees/:id", staffHandler.UpdateEmployee)
+		api.POST("/shifts", staffHandler.CreateShift)
+		api.GET("/shifts", staffHandler.ListShifts)
+		api.GET("/shifts/:id", staffHandler.GetShift)
+		api.PUT("/shifts/:id", staffHandler.UpdateShift)
+		api.POST("/shifts/:id/cancel", staffHandler.CancelShift)
+		api.POST("/shifts/:id/clock-in", staffHandler.ClockIn)
+		api.POST("/shifts/:id/clock-out", staffHandler.ClockOut)
+		api.GET("/timesheets", staffHandler.GetTimesheet)
+		api.GET("/timesheets/export", staffHandler.ExportTimesheet)
+
+		api.POST("/channels", channelHandler.CreateChannel)
+		api.GET("/channels", channelHandler.ListChannels)
+		api.PUT("/channels/:id", channelHandler.UpdateChannel)
+		api.GET("/reports/channels", channelHandler.GetChannelReport)
+		api.POST("/clients/:id/referrals", referralHandler.CreateReferral)
+		api.GET("/clients/:id/referrals", referralHandler.ListClientReferrals)
+		api.GET("/referrals", referralHandler.ListReferrals)
+		api.POST("/referrals/:id/reward", referralHandler.RewardReferral)
+		api.POST("/referrals/:id/cancel", referralHandler.CancelReferral)
+
+		api.POST("/segments", segmentHandler.CreateSegment)
+		api.GET("/segments", segmentHandler.ListSegments)
+		api.GET("/segments/:id", segmentHandler.GetSegment)
+		api.PUT("/segments/:id", segmentHandler.UpdateSegment)
+		api.DELETE("/segments/:id", segmentHandler.DeleteSegment)
+		api.GET("/segments/:id/clients", segmentHandler.ListSegmentClients)
+		api.GET("/segments/:id/count", segmentHandler.CountSegment)
+
+		api.POST("/classes", groupClassHandler.CreateClass)
+		api.GET("/classes", groupClassHandler.ListClasses)
+		api.GET("/classes/:id", groupClassHandler.GetClass)
+		api.PUT("/classes/:id", groupClassHandler.UpdateClass)
+		api.POST("/classes/:id/cancel", groupClassHandler.CancelClass)
+		api.POST("/classes/:id/enrollments", groupClassHandler.Enroll)
+		api.DELETE("/classes/:id/enrollments/:client_id", groupClassHandler.CancelEnrollment)
+		api.PUT("/classes/:id/enrollments/:client_id/attendance", groupClassHandler.MarkAttendance)
+
+		api.POST("/waitlist", waitlistHandler.CreateEntry)
+		api.GET("/waitlist", waitlistHandler.ListEntries)
+		api.GET("/waitlist/:id", waitlistHandler.GetEntry)
+		api.DELETE("/waitlist/:id", waitlistHandler.CancelEntry)
+
+		api.POST("/invoices", invoiceHandler.CreateInvoice)
+		api.GET("/invoices", invoiceHandler.ListInvoices)
+		api.GET("/invoices/:id", invoiceHandler.GetInvoice)
+		api.POST("/invoices/:id/cancel", invoiceHandler.CancelInvoice)
+		api.POST("/invoices/:id/payments", invoiceHandler.AddPayment)
+		api.POST("/invoices/:id/refunds", invoiceHandler.AddRefund)
+		api.GET("/reports/cash", invoiceHandler.GetCashReport)
+
+		api.POST("/products", productHandler.CreateProduct)
+		api.GET("/products", productHandler.ListProducts)
+		api.GET("/products/:id", productHandler.GetProduct)
+		api.PUT("/products/:id", productHandler.UpdateProduct)
+		api.GET("/stock", productHandler.ListStock)
+		api.POST("/stock/movements", productHandler.CreateStockMovement)
+		api.GET("/stock/movements", productHandler.ListStockMovements)
+		api.POST("/invoices/:id/loyalty-redemptions", loyaltyHandler.RedeemPoints)
+
+		api.POST("/loyalty/rules", loyaltyHandler.CreateRule)
+		api.GET("/loyalty/rules", loyaltyHandler.ListRules)
+		api.PUT("/loyalty/rules/:id", loyaltyHandler.UpdateRule)
+		api.POST("/loyalty/expire", loyaltyHandler.ExpirePoints)
+		api.GET("/clients/:id/loyalty", loyaltyHandler.GetBalance)
+		api.GET("/clients/:id/loyalty/entries", loyaltyHandler.ListEntries)
+		api.POST("/clients/:id/loyalty/adjustments", loyaltyHandler.AdjustPoints)
+
+		api.POST("/promo-codes", promoCodeHandler.CreatePromoCode)
+		api.GET("/promo-codes", promoCodeHandler.ListPromoCodes)
+		api.GET("/promo-codes/:id", promoCodeHandler.GetPromoCode)
+		api.PUT("/promo-codes/:id", promoCodeHandler.UpdatePromoCode)
+
+		api.POST("/gift-certificates", giftCertificateHandler.CreateGiftCertificate)
+		api.GET("/gift-certificates", giftCertificateHandler.ListGiftCertificates)
+		api.GET("/gift-certificates/:id", giftCertificateHandler.GetGiftCertificate)
+		api.POST("/gift-certificates/:id/cancel", giftCertificateHandler.CancelGiftCertificate)
+
+		api.GET("/feedback", feedbackHandler.ListFeedback)
+		api.GET("/reports/specialist-ratings", feedbackHandler.GetSpecialistRatings)
+		api.GET("/reports/nps", feedbackHandler.GetNPSReport)
+		// Публичные ссылки из опросов открывают клиенты без авторизации
+		api.GET("/public/surveys/:token", feedbackHandler.GetPublicSurvey)
+		api.POST("/public/surveys/:token", feedbackHandler.SubmitFeedback)
 
 		api.GET("/health", func(c *gin.Context) {
 			ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
@@ -212,7 +434,7 @@ func main() {
 		})
 	}
 
-	// 8. Запуск сервера
+	// 9. Запуск сервера
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
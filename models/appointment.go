@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы записи на прием
+const (
+	AppointmentStatusBooked    = "booked"
+	AppointmentStatusConfirmed = "confirmed"
+	AppointmentStatusCompleted = "completed"
+	AppointmentStatusCancelled = "cancelled"
+	AppointmentStatusNoShow    = "no_show"
+)
+
+// activeAppointmentStatuses - статусы, при которых запись занимает время специалиста и помещения
+var activeAppointmentStatuses = []string{AppointmentStatusBooked, AppointmentStatusConfirmed}
+
+// appointmentTransitions - допустимые переходы между статусами
+var appointmentTransitions = map[string][]string{
+	AppointmentStatusBooked:    {AppointmentStatusConfirmed, AppointmentStatusCompleted, AppointmentStatusCancelled, AppointmentStatusNoShow},
+	AppointmentStatusConfirmed: {AppointmentStatusCompleted, AppointmentStatusCancelled, AppointmentStatusNoShow},
+}
+
+type Appointment struct {
+	gorm.Model
+	ClientID     uint        `gorm:"not null;index"`
+	Client       *Client     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID uint        `gorm:"not null;index"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	// BranchID - филиал специалиста на момент записи
+	BranchID uint      `gorm:"not null;index"`
+	Branch   *Branch   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Room     string    `gorm:"not null;index"`
+	StartsAt time.Time `gorm:"not null;index"`
+	EndsAt   time.Time `gorm:"not null"`
+	Status   string    `gorm:"not null;default:booked"`
+	Comment  string
+	// Resources - помещения и оборудование, забронированные на время записи
+	Resources []Resource `gorm:"many2many:appointment_resources;"`
+}
+
+// IsActive сообщает, занимает ли запись время в расписании
+func (a *Appointment) IsActive() bool {
+	for _, status := range activeAppointmentStatuses {
+		if a.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBillable сообщает, можно ли выставить счет за запись: за завершенный визит или предоплатой
+// за предстоящий. Отмененные и пропущенные визиты не выставляются.
+func (a *Appointment) IsBillable() bool {
+	return a.Status == AppointmentStatusCompleted || a.IsActive()
+}
+
+// CanTransitionAppointment проверяет, допустим ли переход записи из статуса from в статус to
+func CanTransitionAppointment(from, to string) bool {
+	for _, allowed := range appointmentTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AppointmentFilter - параметры выборки списка записей
+type AppointmentFilter struct {
+	ClientID     uint
+	SpecialistID uint
+	BranchID     uint
+	Room         string
+	ResourceID   uint
+	Status       string
+	From         time.Time
+	To           time.Time
+	Limit        int
+	Offset       int
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOverlap возвращается, если запись пересекается с другой записью специалиста или помещения
+var ErrOverlap = errors.New("appointment overlaps with an existing one")
+
+// ErrResourceBusy возвращается, если ресурс на это время уже занят на всю вместимость
+var ErrResourceBusy = errors.New("resource is fully booked for the requested time")
+
+// ErrAppointmentStatus возвращается, если текущий статус записи не допускает изменения
+var ErrAppointmentStatus = errors.New("appointment status does not allow this change")
+
+type AppointmentRepository interface {
+	CreateAppointment(appointment *Appointment) error
+	GetAppointmentByID(id uint) (*Appointment, error)
+	// UpdateAppointment сохраняет активную запись и заменяет набор ее ресурсов на appointment.Resources;
+	// если запись тем временем закрыли, возвращается ErrAppointmentStatus
+	UpdateAppointment(appointment *Appointment) error
+	// ChangeAppointmentStatus переводит запись в статус status, проверяя переход по статусу в базе,
+	// и сохраняет только статус; недопустимый переход - ErrAppointmentStatus
+	ChangeAppointmentStatus(appointment *Appointment, status string) error
+	ListAppointments(filter AppointmentFilter) ([]Appointment, int64, error)
+}
+
+func (r *PostgresRepository) CreateAppointment(appointment *Appointment) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkAppointmentOverlap(tx, appointment); err != nil {
+			return err
+		}
+		return tx.Create(appointment).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrOverlap) || errors.Is(err, ErrResourceBusy) {
+			return err
+		}
+		return fmt.Errorf("failed to create appointment: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetAppointmentByID(id uint) (*Appointment, error) {
+	var appointment Appointment
+	if err := r.db.Preload("Resources", orderResources).First(&appointment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+	return &appointment, nil
+}
+
+func (r *PostgresRepository) UpdateAppointment(appointment *Appointment) error {
+	var rowsAffected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current Appointment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, appointment.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if !current.IsActive() {
+			return fmt.Errorf("%w: appointment is %s", ErrAppointmentStatus, current.Status)
+		}
+
+		if appointment.IsActive() {
+			if err := checkAppointmentOverlap(tx, appointment); err != nil {
+				return err
+			}
+		}
+		result := tx.Omit(clause.Associations).Save(appointment)
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 {
+			return nil
+		}
+		return tx.Model(appointment).Association("Resources").Replace(appointment.Resources)
+	})
+	if err != nil {
+		if errors.Is(err, ErrOverlap) || errors.Is(err, ErrResourceBusy) || errors.Is(err, ErrAppointmentStatus) {
+			return err
+		}
+		return fmt.Errorf("failed to update appointment: %w", translateError(err))
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ChangeAppointmentStatus(appointment *Appointment, status string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return transitionAppointment(tx, appointment, status)
+	})
+	if err != nil {
+		if errors.Is(err, ErrAppointmentStatus) {
+			return err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to change appointment status: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListAppointments(filter AppointmentFilter) ([]Appointment, int64, error) {
+	query := r.db.Model(&Appointment{})
+
+	if filter.ClientID != 0 {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.SpecialistID != 0 {
+		query = query.Where("specialist_id = ?", filter.SpecialistID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Room != "" {
+		query = query.Where("room = ?", filter.Room)
+	}
+	if filter.ResourceID != 0 {
+		query = query.Where("id IN (?)", r.db.Table("appointment_resources").
+			Select("appointment_id").
+			Where("resource_id = ?", filter.ResourceID))
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("ends_at > ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("starts_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count appointments: %w", err)
+	}
+
+	var appointments []Appointment
+	if err := query.Preload("Resources", orderResources).Order("starts_at, id").Limit(filter.Limit).Offset(filter.Offset).Find(&appointments).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list appointments: %w", err)
+	}
+	return appointments, total, nil
+}
+
+// transitionAppointment блокирует запись в транзакции tx, проверяет переход в status из статуса в базе,
+// а не из загруженного ранее, и обновляет только статус
+func transitionAppointment(tx *gorm.DB, appointment *Appointment, status string) error {
+	var current Appointment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, appointment.ID).Error; err != nil {
+		return err
+	}
+	if !CanTransitionAppointment(current.Status, status) {
+		return fmt.Errorf("%w: cannot change appointment status from %s to %s", ErrAppointmentStatus, current.Status, status)
+	}
+	if err := tx.Model(&current).Update("status", status).Error; err != nil {
+		return err
+	}
+	appointment.Status = status
+	appointment.UpdatedAt = current.UpdatedAt
+	return nil
+}
+
+// checkAppointmentOverlap ищет записи и групповые занятия того же специалиста или в том же помещении филиала,
+// пересекающиеся по времени, и проверяет вместимость забронированных ресурсов. Advisory-блокировки
+// сериализуют параллельные бронирования одного специалиста, помещения и ресурса до конца транзакции.
+func checkAppointmentOverlap(tx *gorm.DB, appointment *Appointment) error {
+	if err := lockSchedule(tx, appointment.SpecialistID, appointment.BranchID, appointment.Room); err != nil {
+		return err
+	}
+	conflict, err := hasScheduleConflict(tx, appointment.ID, 0, appointment.SpecialistID, appointment.BranchID, appointment.Room,
+		appointment.StartsAt, appointment.EndsAt)
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return ErrOverlap
+	}
+	return checkResourceCapacity(tx, appointment)
+}
+
+// lockSchedule берет advisory-блокировки специалиста и помещения филиала до конца транзакции
+func lockSchedule(tx *gorm.DB, specialistID, branchID uint, room string) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("specialist:%d", specialistID)).Error; err != nil {
+		return err
+	}
+	if room == "" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("room:%d:%s", branchID, room)).Error
+}
+
+// hasScheduleConflict сообщает, заняты ли специалист или помещение филиала branchID в интервале [startsAt, endsAt)
+// активной записью или назначенным групповым занятием, кроме проверяемых appointmentID и classID.
+// Пустое помещение (например, запись только с ресурсами) конфликтует лишь по специалисту.
+func hasScheduleConflict(tx *gorm.DB, appointmentID, classID, specialistID, branchID uint, room string, startsAt, endsAt time.Time) (bool, error) {
+	owner := func(query *gorm.DB) *gorm.DB {
+		if room == "" {
+			return query.Where("specialist_id = ?", specialistID)
+		}
+		return query.Where("(specialist_id = ? OR (room = ? AND branch_id = ?))", specialistID, room, branchID)
+	}
+
+	var count int64
+	err := owner(tx.Model(&Appointment{})).
+		Where("id <> ?", appointmentID).
+		Where("status IN ?", activeAppointmentStatuses).
+		Where("starts_at < ? AND ends_at > ?", endsAt, startsAt).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = owner(tx.Model(&GroupClass{})).
+		Where("id <> ?", classID).
+		Where("status = ?", GroupClassStatusScheduled).
+		Where("starts_at < ? AND ends_at > ?", endsAt, startsAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// checkResourceCapacity проверяет, что вместе с новой записью ни один ресурс не будет занят
+// одновременно большим числом записей, чем его вместимость
+func checkResourceCapacity(tx *gorm.DB, appointment *Appointment) error {
+	resources := append([]Resource(nil), appointment.Resources...)
+	// Блокировки берутся в порядке id, чтобы параллельные транзакции не ждали друг друга по кругу
+	sort.Slice(resources, func(i, j int) bool { return resources[i].ID < resources[j].ID })
+
+	for _, resource := range resources {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("resource:%d", resource.ID)).Error; err != nil {
+			return err
+		}
+
+		var slots []TimeSlot
+		err := resourceBookingsQuery(tx).
+			Select("appointments.starts_at, appointments.ends_at").
+			Where("appointment_resources.resource_id = ?", resource.ID).
+			Where("appointments.id <> ?", appointment.ID).
+			Where("appointments.starts_at < ? AND appointments.ends_at > ?", appointment.EndsAt, appointment.StartsAt).
+			Scan(&slots).Error
+		if err != nil {
+			return err
+		}
+
+		// Пересекающиеся записи обрезаются по границам новой: важна занятость только внутри ее интервала
+		for i := range slots {
+			if slots[i].StartsAt.Before(appointment.StartsAt) {
+				slots[i].StartsAt = appointment.StartsAt
+			}
+			if slots[i].EndsAt.After(appointment.EndsAt) {
+				slots[i].EndsAt = appointment.EndsAt
+			}
+		}
+		if PeakOccupancy(slots) >= resource.Capacity {
+			return fmt.Errorf("%w: %s", ErrResourceBusy, resource.Name)
+		}
+	}
+	return nil
+}
+
+func orderResources(db *gorm.DB) *gorm.DB {
+	return db.Order("resources.kind, resources.name")
+}
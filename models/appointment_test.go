@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestCanTransitionAppointment(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{AppointmentStatusBooked, AppointmentStatusConfirmed, true},
+		{AppointmentStatusBooked, AppointmentStatusCancelled, true},
+		{AppointmentStatusConfirmed, AppointmentStatusNoShow, true},
+		{AppointmentStatusConfirmed, AppointmentStatusCompleted, true},
+		{AppointmentStatusCompleted, AppointmentStatusCancelled, false},
+		{AppointmentStatusConfirmed, AppointmentStatusBooked, false},
+		{AppointmentStatusCancelled, AppointmentStatusConfirmed, false},
+		{AppointmentStatusNoShow, AppointmentStatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransitionAppointment(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionAppointment(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAppointmentIsBillable(t *testing.T) {
+	for status, want := range map[string]bool{
+		AppointmentStatusBooked:    true,
+		AppointmentStatusConfirmed: true,
+		AppointmentStatusCompleted: true,
+		AppointmentStatusCancelled: false,
+		AppointmentStatusNoShow:    false,
+	} {
+		appointment := Appointment{Status: status}
+		if got := appointment.IsBillable(); got != want {
+			t.Errorf("IsBillable() for %s = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// Attachment - файл в карточке клиента (анализы, справки, фото). Содержимое лежит во внешнем
+// хранилище под ключом StorageKey, в БД - только метаданные и SHA-256 для проверки целостности.
+type Attachment struct {
+	gorm.Model
+	ClientID     uint    `gorm:"not null;index"`
+	Client       *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FileName     string  `gorm:"not null"`
+	ContentType  string  `gorm:"not null"`
+	Size         int64   `gorm:"not null"`
+	StorageKey   string  `gorm:"not null;uniqueIndex"`
+	Checksum     string  `gorm:"type:varchar(64);not null"`
+	Description  string
+	SpecialistID *uint       `gorm:"index"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository interface {
+	CreateAttachment(attachment *Attachment) error
+	GetAttachment(clientID, attachmentID uint) (*Attachment, error)
+	ListAttachments(clientID uint) ([]Attachment, error)
+	// DeleteAttachment удаляет метаданные окончательно: содержимое файла удаляется из хранилища вместе с ними
+	DeleteAttachment(clientID, attachmentID uint) error
+}
+
+func (r *PostgresRepository) CreateAttachment(attachment *Attachment) error {
+	if err := r.db.Create(attachment).Error; err != nil {
+		return fmt.Errorf("failed to create attachment: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetAttachment(clientID, attachmentID uint) (*Attachment, error) {
+	var attachment Attachment
+	if err := r.db.Where("client_id = ?", clientID).First(&attachment, attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return &attachment, nil
+}
+
+func (r *PostgresRepository) ListAttachments(clientID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := r.db.Where("client_id = ?", clientID).Order("created_at DESC, id DESC").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+func (r *PostgresRepository) DeleteAttachment(clientID, attachmentID uint) error {
+	result := r.db.Unscoped().Where("client_id = ?", clientID).Delete(&Attachment{}, attachmentID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete attachment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
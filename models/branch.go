@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBranchCode - код филиала, который создается при первом запуске. К нему относятся данные,
+// заведенные до появления филиалов, и специалисты и ресурсы, для которых филиал не указан.
+const DefaultBranchCode = "main"
+
+// Branch - филиал центра. Специалисты, ресурсы, записи и групповые занятия относятся к филиалу,
+// у клиента филиал - домашний. Филиал не удаляется, а отключается.
+type Branch struct {
+	ID        uint   `gorm:"primarykey"`
+	Code      string `gorm:"not null;uniqueIndex"`
+	Name      string `gorm:"not null"`
+	Address   string
+	Phone     string
+	Active    bool `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// branchScopedTables - таблицы с обязательной ссылкой на филиал
+var branchScopedTables = []string{"specialists", "resources", "appointments", "group_classes"}
+
+// assignDefaultBranch создает основной филиал и относит к нему строки, созданные до появления филиалов.
+// Колонки добавляются здесь, а не AutoMigrate, потому что NOT NULL без значения нельзя добавить в непустую таблицу.
+// Возвращает клиентов, получивших филиал: их документы в поисковом индексе еще без branch_id.
+func assignDefaultBranch(db *gorm.DB) ([]uint, error) {
+	branch := Branch{Code: DefaultBranchCode, Name: "Основной филиал", Active: true}
+	if err := db.Where(Branch{Code: DefaultBranchCode}).FirstOrCreate(&branch).Error; err != nil {
+		return nil, err
+	}
+
+	var clientIDs []uint
+	for _, table := range append(branchScopedTables, "clients") {
+		if !db.Migrator().HasTable(table) || db.Migrator().HasColumn(table, "branch_id") {
+			continue
+		}
+		if err := db.Exec("ALTER TABLE ? ADD COLUMN branch_id bigint", clause.Table{Name: table}).Error; err != nil {
+			return nil, err
+		}
+		if table == "clients" {
+			if err := db.Table(table).Where("deleted_at IS NULL").Order("id").Pluck("id", &clientIDs).Error; err != nil {
+				return nil, err
+			}
+		}
+		if err := db.Table(table).Where("branch_id IS NULL").Update("branch_id", branch.ID).Error; err != nil {
+			return nil, err
+		}
+		if table == "clients" {
+			continue
+		}
+		if err := db.Exec("ALTER TABLE ? ALTER COLUMN branch_id SET NOT NULL", clause.Table{Name: table}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return clientIDs, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type BranchRepository interface {
+	CreateBranch(branch *Branch) error
+	GetBranch(id uint) (*Branch, error)
+	GetBranchByCode(code string) (*Branch, error)
+	ListBranches(activeOnly bool) ([]Branch, error)
+	UpdateBranch(branch *Branch) error
+}
+
+func (r *PostgresRepository) CreateBranch(branch *Branch) error {
+	if err := r.db.Create(branch).Error; err != nil {
+		return fmt.Errorf("failed to create branch: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetBranch(id uint) (*Branch, error) {
+	var branch Branch
+	if err := r.db.First(&branch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+	return &branch, nil
+}
+
+func (r *PostgresRepository) GetBranchByCode(code string) (*Branch, error) {
+	var branch Branch
+	if err := r.db.Where("code = ?", code).First(&branch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+	return &branch, nil
+}
+
+func (r *PostgresRepository) ListBranches(activeOnly bool) ([]Branch, error) {
+	query := r.db.Order("name, id")
+	if activeOnly {
+		query = query.Where("active")
+	}
+
+	var branches []Branch
+	if err := query.Find(&branches).Error; err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	return branches, nil
+}
+
+func (r *PostgresRepository) UpdateBranch(branch *Branch) error {
+	result := r.db.Save(branch)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update branch: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdvertisingChannel - справочник рекламных каналов. Клиент ссылается на канал по коду;
+// канал не удаляется, а отключается, чтобы сохранить атрибуцию прежних клиентов.
+type AdvertisingChannel struct {
+	ID        uint   `gorm:"primarykey"`
+	Code      string `gorm:"not null;uniqueIndex"`
+	Name      string `gorm:"not null"`
+	Active    bool   `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// defaultAdvertisingChannels - каналы, которыми заполняется справочник при первом запуске
+var defaultAdvertisingChannels = []AdvertisingChannel{
+	{Code: ChannelInstagram, Name: "Instagram", Active: true},
+	{Code: ChannelVK, Name: "ВКонтакте", Active: true},
+	{Code: ChannelTelegram, Name: "Telegram", Active: true},
+	{Code: ChannelSearch, Name: "Поиск", Active: true},
+	{Code: ChannelRecommendation, Name: "Рекомендация", Active: true},
+	{Code: ChannelSignboard, Name: "Вывеска", Active: true},
+	{Code: ChannelOther, Name: "Другое", Active: true},
+}
+
+// ChannelReportRow - показатели канала за период: новые клиенты, сколько из них дошли до оплаченного
+// завершенного визита в этом же периоде, и выручка (оплаты за вычетом возвратов) от клиентов канала
+type ChannelReportRow struct {
+	Channel          string
+	Name             string
+	NewClients       int64
+	ConvertedClients int64
+	Revenue          Money
+}
+
+// ConversionRate возвращает долю новых клиентов, дошедших до оплаченного визита
+func (r ChannelReportRow) ConversionRate() float64 {
+	if r.NewClients == 0 {
+		return 0
+	}
+	return float64(r.ConvertedClients) / float64(r.NewClients)
+}
+
+// seedAdvertisingChannels заполняет справочник стандартными каналами и кодами, уже встречающимися у клиентов
+func seedAdvertisingChannels(db *gorm.DB) error {
+	for _, channel := range defaultAdvertisingChannels {
+		if err := db.Where(AdvertisingChannel{Code: channel.Code}).FirstOrCreate(&channel).Error; err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasColumn(&Client{}, "advertising_channel") {
+		return nil
+	}
+	return db.Exec(`INSERT INTO advertising_channels (code, name, active, created_at, updated_at)
+		SELECT DISTINCT advertising_channel, advertising_channel, true, now(), now() FROM clients
+		WHERE advertising_channel NOT IN (SELECT code FROM advertising_channels)`).Error
+}
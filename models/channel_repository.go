@@ -0,0 +1,151 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChannelRepository interface {
+	CreateChannel(channel *AdvertisingChannel) error
+	GetChannel(id uint) (*AdvertisingChannel, error)
+	GetChannelByCode(code string) (*AdvertisingChannel, error)
+	// ListChannels возвращает справочник; activeOnly скрывает отключенные каналы
+	ListChannels(activeOnly bool) ([]AdvertisingChannel, error)
+	UpdateChannel(channel *AdvertisingChannel) error
+	// ChannelReport строит показатели каналов по клиентам, зарегистрированным в [from, to),
+	// и оплатам за тот же период; branchID ограничивает отчет клиентами филиала, 0 - весь центр
+	ChannelReport(from, to time.Time, branchID uint) ([]ChannelReportRow, error)
+}
+
+func (r *PostgresRepository) CreateChannel(channel *AdvertisingChannel) error {
+	if err := r.db.Create(channel).Error; err != nil {
+		return fmt.Errorf("failed to create channel: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetChannel(id uint) (*AdvertisingChannel, error) {
+	var channel AdvertisingChannel
+	if err := r.db.First(&channel, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	return &channel, nil
+}
+
+func (r *PostgresRepository) GetChannelByCode(code string) (*AdvertisingChannel, error) {
+	var channel AdvertisingChannel
+	if err := r.db.Where("code = ?", code).First(&channel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	return &channel, nil
+}
+
+func (r *PostgresRepository) ListChannels(activeOnly bool) ([]AdvertisingChannel, error) {
+	query := r.db.Order("name, id")
+	if activeOnly {
+		query = query.Where("active")
+	}
+
+	var channels []AdvertisingChannel
+	if err := query.Find(&channels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list channels: %w", err)
+	}
+	return channels, nil
+}
+
+func (r *PostgresRepository) UpdateChannel(channel *AdvertisingChannel) error {
+	result := r.db.Save(channel)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update channel: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ChannelReport(from, to time.Time, branchID uint) ([]ChannelReportRow, error) {
+	clients := r.db.Model(&Client{})
+	payments := r.db.Table("payments").Joins("JOIN clients ON clients.id = payments.client_id")
+	if branchID != 0 {
+		clients = clients.Where("branch_id = ?", branchID)
+		payments = payments.Where("clients.branch_id = ?", branchID)
+	}
+
+	// Конверсия: завершенный визит нового клиента в периоде выставлен в полностью оплаченном счете
+	var acquisition []ChannelReportRow
+	err := clients.
+		Select(`advertising_channel AS channel, COUNT(*) AS new_clients,
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM invoice_lines
+				JOIN invoices ON invoices.id = invoice_lines.invoice_id
+				JOIN appointments ON appointments.id = invoice_lines.appointment_id
+				WHERE appointments.client_id = clients.id AND appointments.status = ?
+					AND appointments.starts_at >= ? AND appointments.starts_at < ?
+					AND invoices.status = ? AND invoices.deleted_at IS NULL)) AS converted_clients`,
+			AppointmentStatusCompleted, from, to, InvoiceStatusPaid).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("advertising_channel").
+		Scan(&acquisition).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to build channel report: %w", err)
+	}
+
+	// Выручка учитывает и клиентов, слитых с другими или удаленных: оплаты остаются в кассе.
+	// Оплаты баллами и сертификатами выручкой не считаются.
+	var revenue []ChannelReportRow
+	err = payments.
+		Select("clients.advertising_channel AS channel, COALESCE(SUM(CASE WHEN payments.kind = ? THEN -payments.amount ELSE payments.amount END), 0) AS revenue",
+			PaymentKindRefund).
+		Where("payments.paid_at >= ? AND payments.paid_at < ?", from, to).
+		Where("payments.method NOT IN ?", nonCashPaymentMethods).
+		Group("clients.advertising_channel").
+		Scan(&revenue).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to build channel report: %w", err)
+	}
+
+	channels, err := r.ListChannels(false)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]*ChannelReportRow{}
+	for _, channel := range channels {
+		rows[channel.Code] = &ChannelReportRow{Channel: channel.Code, Name: channel.Name}
+	}
+	row := func(code string) *ChannelReportRow {
+		if rows[code] == nil {
+			rows[code] = &ChannelReportRow{Channel: code, Name: code}
+		}
+		return rows[code]
+	}
+	for _, a := range acquisition {
+		row(a.Channel).NewClients = a.NewClients
+		row(a.Channel).ConvertedClients = a.ConvertedClients
+	}
+	for _, v := range revenue {
+		row(v.Channel).Revenue = v.Revenue
+	}
+
+	result := make([]ChannelReportRow, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Revenue != result[j].Revenue {
+			return result[i].Revenue > result[j].Revenue
+		}
+		return result[i].Channel < result[j].Channel
+	})
+	return result, nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestChannelReportRowConversionRate(t *testing.T) {
+	tests := []struct {
+		name string
+		row  ChannelReportRow
+		want float64
+	}{
+		{"no new clients", ChannelReportRow{}, 0},
+		{"half converted", ChannelReportRow{NewClients: 4, ConvertedClients: 2}, 0.5},
+		{"all converted", ChannelReportRow{NewClients: 3, ConvertedClients: 3}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.row.ConversionRate(); got != tt.want {
+			t.Errorf("%s: ConversionRate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
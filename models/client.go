@@ -1,18 +1,76 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Допустимые значения пола в анкете
+const (
+	GenderMale   = "male"
+	GenderFemale = "female"
+)
+
+// Стандартные рекламные каналы, которыми заполняется справочник AdvertisingChannel
+const (
+	ChannelInstagram      = "instagram"
+	ChannelVK             = "vk"
+	ChannelTelegram       = "telegram"
+	ChannelSearch         = "search"
+	ChannelRecommendation = "recommendation"
+	ChannelSignboard      = "signboard"
+	ChannelOther          = "other"
+)
 
 type Client struct {
 	gorm.Model
-	FullName           string `gorm:"not null"`
-	Phone              string `gorm:"not null"`
-	Email              string `gorm:"not null;unique"`
-	AdvertisingChannel string `gorm:"not null"`
+	FullName           string              `gorm:"not null"`
+	Phone              string              `gorm:"not null"`
+	Email              string              `gorm:"not null;unique"`
+	AdvertisingChannel string              `gorm:"not null;index"`
+	Channel            *AdvertisingChannel `gorm:"foreignKey:AdvertisingChannel;references:Code;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID       *uint
+	Specialist         *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// BranchID - домашний филиал клиента; записываться можно и в другие филиалы
+	BranchID        *uint   `gorm:"index"`
+	Branch          *Branch `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	MeetingPlace    string  `gorm:"not null"`
+	Occupation      string  `gorm:"not null"`
+	Gender          string  `gorm:"not null"`
+	Age             int     `gorm:"not null"`
+	ReasonForVisit  string  `gorm:"not null"`
+	SpecialistNotes string
+	Tags            []Tag `gorm:"many2many:client_tags;"`
+}
+
+// ClientFilter - параметры выборки списка клиентов
+type ClientFilter struct {
 	SpecialistID       uint
-	MeetingPlace       string `gorm:"not null"`
-	Occupation         string `gorm:"not null"`
-	Gender             string `gorm:"not null"`
-	Age                int    `gorm:"not null"`
-	ReasonForVisit     string `gorm:"not null"`
-	SpecialistNotes    string
+	BranchID           uint
+	Gender             string
+	AdvertisingChannel string
+	AgeMin             int
+	AgeMax             int
+	CreatedFrom        time.Time
+	CreatedTo          time.Time
+	TagID              uint
+	SortBy             string
+	SortDesc           bool
+	Limit              int
+	Offset             int
+}
+
+// clientSortColumns - допустимые ключи сортировки и соответствующие им колонки
+var clientSortColumns = map[string]string{
+	"id":         "id",
+	"full_name":  "full_name",
+	"age":        "age",
+	"created_at": "created_at",
+}
+
+// IsValidClientSort проверяет, поддерживается ли ключ сортировки
+func IsValidClientSort(key string) bool {
+	_, ok := clientSortColumns[key]
+	return ok
 }
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ConsentTemplate - шаблон информированного согласия. Body может содержать подстановки
+// {{full_name}}, {{phone}}, {{email}} и {{date}}. Services - услуги (специализации специалистов),
+// запись на которые требует подписанного согласия. Изменение текста увеличивает Version,
+// и подписи прежних версий перестают действовать.
+type ConsentTemplate struct {
+	gorm.Model
+	Code      string     `gorm:"not null;uniqueIndex"`
+	Title     string     `gorm:"not null"`
+	Body      string     `gorm:"type:text;not null"`
+	Services  StringList `gorm:"type:jsonb;not null;default:'[]'"`
+	ValidDays int        `gorm:"not null;default:0"`
+	Version   int        `gorm:"not null;default:1"`
+}
+
+// ConsentSignature - подпись клиента под конкретной версией согласия. Вместе с подписью
+// сохраняется отрисованный текст документа и его SHA-256, чтобы подтвердить, что именно было подписано.
+type ConsentSignature struct {
+	gorm.Model
+	ClientID          uint             `gorm:"not null;index"`
+	Client            *Client          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TemplateID        uint             `gorm:"not null;index"`
+	Template          *ConsentTemplate `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	TemplateVersion   int              `gorm:"not null"`
+	SignerName        string           `gorm:"not null"`
+	SignedAt          time.Time        `gorm:"not null"`
+	IPAddress         string           `gorm:"not null"`
+	UserAgent         string
+	Document          string `gorm:"type:text;not null"`
+	DocumentHash      string `gorm:"type:varchar(64);not null"`
+	SignatureImage    []byte `gorm:"type:bytea"`
+	SignatureMimeType string
+	ExpiresAt         *time.Time
+	RevokedAt         *time.Time
+}
+
+// StringList - список строк в jsonb-колонке
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return jsonValue(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return jsonScan(value, l)
+}
+
+// NormalizeServices приводит названия услуг к нижнему регистру и убирает пустые и повторяющиеся
+func NormalizeServices(services []string) StringList {
+	result := StringList{}
+	seen := make(map[string]bool, len(services))
+	for _, service := range services {
+		service = strings.ToLower(strings.TrimSpace(service))
+		if service == "" || seen[service] {
+			continue
+		}
+		seen[service] = true
+		result = append(result, service)
+	}
+	return result
+}
+
+// Render подставляет данные клиента в текст согласия
+func (t *ConsentTemplate) Render(client *Client, at time.Time) string {
+	return strings.NewReplacer(
+		"{{full_name}}", client.FullName,
+		"{{phone}}", client.Phone,
+		"{{email}}", client.Email,
+		"{{date}}", at.Format(dateLayout),
+	).Replace(t.Body)
+}
+
+// DocumentHash возвращает SHA-256 текста документа в hex
+func DocumentHash(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsValid сообщает, действует ли подпись в момент at для текущей версии шаблона
+func (s *ConsentSignature) IsValid(templateVersion int, at time.Time) bool {
+	if s.RevokedAt != nil || s.TemplateVersion != templateVersion {
+		return false
+	}
+	return s.ExpiresAt == nil || at.Before(*s.ExpiresAt)
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConsentRequired возвращается, если для услуги не подписано обязательное согласие
+var ErrConsentRequired = errors.New("signed consent is required for this service")
+
+type ConsentRepository interface {
+	CreateConsentTemplate(template *ConsentTemplate) error
+	GetConsentTemplate(id uint) (*ConsentTemplate, error)
+	ListConsentTemplates(limit, offset int) ([]ConsentTemplate, int64, error)
+	// UpdateConsentTemplate сохраняет шаблон; при изменении текста увеличивает его версию
+	UpdateConsentTemplate(template *ConsentTemplate) error
+	CreateConsentSignature(signature *ConsentSignature) error
+	GetConsentSignature(clientID, signatureID uint) (*ConsentSignature, error)
+	ListConsentSignatures(clientID uint) ([]ConsentSignature, error)
+	RevokeConsentSignature(clientID, signatureID uint, at time.Time) (*ConsentSignature, error)
+	// MissingConsents возвращает шаблоны, обязательные для услуги, без действующей на момент at подписи клиента
+	MissingConsents(clientID uint, service string, at time.Time) ([]ConsentTemplate, error)
+}
+
+func (r *PostgresRepository) CreateConsentTemplate(template *ConsentTemplate) error {
+	template.Version = 1
+	if err := r.db.Create(template).Error; err != nil {
+		return fmt.Errorf("failed to create consent template: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetConsentTemplate(id uint) (*ConsentTemplate, error) {
+	var template ConsentTemplate
+	if err := r.db.First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get consent template: %w", err)
+	}
+	return &template, nil
+}
+
+func (r *PostgresRepository) ListConsentTemplates(limit, offset int) ([]ConsentTemplate, int64, error) {
+	query := r.db.Model(&ConsentTemplate{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count consent templates: %w", err)
+	}
+
+	var templates []ConsentTemplate
+	if err := query.Order("title, id").Limit(limit).Offset(offset).Find(&templates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list consent templates: %w", err)
+	}
+	return templates, total, nil
+}
+
+func (r *PostgresRepository) UpdateConsentTemplate(template *ConsentTemplate) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current ConsentTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, template.ID).Error; err != nil {
+			return err
+		}
+
+		template.Version = current.Version
+		if current.Body != template.Body {
+			template.Version++
+		}
+		return tx.Save(template).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update consent template: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreateConsentSignature(signature *ConsentSignature) error {
+	if err := r.db.Omit(clause.Associations).Create(signature).Error; err != nil {
+		return fmt.Errorf("failed to save consent signature: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetConsentSignature(clientID, signatureID uint) (*ConsentSignature, error) {
+	var signature ConsentSignature
+	if err := r.db.Preload("Template").Where("client_id = ?", clientID).First(&signature, signatureID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get consent signature: %w", err)
+	}
+	return &signature, nil
+}
+
+// ListConsentSignatures возвращает подписи клиента без текста документа и изображения подписи
+func (r *PostgresRepository) ListConsentSignatures(clientID uint) ([]ConsentSignature, error) {
+	var signatures []ConsentSignature
+	err := r.db.Omit("document", "signature_image").
+		Preload("Template").
+		Where("client_id = ?", clientID).
+		Order("signed_at DESC, id DESC").
+		Find(&signatures).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consent signatures: %w", err)
+	}
+	return signatures, nil
+}
+
+func (r *PostgresRepository) RevokeConsentSignature(clientID, signatureID uint, at time.Time) (*ConsentSignature, error) {
+	signature, err := r.GetConsentSignature(clientID, signatureID)
+	if err != nil {
+		return nil, err
+	}
+	if signature.RevokedAt != nil {
+		return signature, nil
+	}
+
+	signature.RevokedAt = &at
+	if err := r.db.Model(signature).Update("revoked_at", at).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke consent signature: %w", err)
+	}
+	return signature, nil
+}
+
+func (r *PostgresRepository) MissingConsents(clientID uint, service string, at time.Time) ([]ConsentTemplate, error) {
+	service = strings.ToLower(strings.TrimSpace(service))
+	if service == "" {
+		return nil, nil
+	}
+
+	contains, err := json.Marshal([]string{service})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []ConsentTemplate
+	err = r.db.Where("services @> ?", string(contains)).
+		Where("NOT EXISTS (?)", r.db.Model(&ConsentSignature{}).
+			Select("1").
+			Where("consent_signatures.template_id = consent_templates.id").
+			Where("consent_signatures.template_version = consent_templates.version").
+			Where("consent_signatures.client_id = ?", clientID).
+			Where("consent_signatures.revoked_at IS NULL").
+			Where("(consent_signatures.expires_at IS NULL OR consent_signatures.expires_at > ?)", at)).
+		Order("id").
+		Find(&missing).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check consents: %w", err)
+	}
+	return missing, nil
+}
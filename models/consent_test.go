@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsentRenderAndValidity(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	template := ConsentTemplate{Body: "Я, {{full_name}}, {{date}} даю согласие", Version: 2}
+	client := Client{FullName: "Анна Иванова"}
+
+	document := template.Render(&client, now)
+	if want := "Я, Анна Иванова, 2025-03-10 даю согласие"; document != want {
+		t.Fatalf("Render() = %q, want %q", document, want)
+	}
+	if len(DocumentHash(document)) != 64 {
+		t.Errorf("DocumentHash() should return hex SHA-256")
+	}
+
+	expires := now.AddDate(0, 0, 30)
+	signature := ConsentSignature{TemplateVersion: 2, ExpiresAt: &expires}
+	if !signature.IsValid(2, now) {
+		t.Error("signature of the current version should be valid")
+	}
+	if signature.IsValid(3, now) {
+		t.Error("signature of an outdated version should be invalid")
+	}
+	if signature.IsValid(2, expires) {
+		t.Error("expired signature should be invalid")
+	}
+	signature.RevokedAt = &now
+	if signature.IsValid(2, now) {
+		t.Error("revoked signature should be invalid")
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MinDuplicateScore - порог, начиная с которого клиент показывается как возможный дубликат
+const MinDuplicateScore = 0.25
+
+// DuplicateCandidate - возможный дубликат клиента с оценкой от 0 до 1 и причинами совпадения
+type DuplicateCandidate struct {
+	Client  Client
+	Score   float64
+	Reasons []string
+}
+
+// MergeResult - итог слияния: оставшийся клиент и число перенесенных записей по таблицам
+type MergeResult struct {
+	Survivor *Client
+	MergedID uint
+	Moved    map[string]int64
+}
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// NormalizePhone оставляет последние 10 цифр номера, чтобы +7 916..., 8 (916)... и 916...
+// считались одним номером
+func NormalizePhone(phone string) string {
+	digits := nonDigits.ReplaceAllString(phone, "")
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
+
+// NormalizeEmail приводит адрес к нижнему регистру и отбрасывает "+метку" в локальной части
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+// NameSimilarity сравнивает ФИО без учета регистра, "ё" и порядка слов; 1 - полное совпадение
+func NameSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeName(a)), []rune(normalizeName(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// ScoreDuplicate оценивает, насколько candidate похож на client. Совпадение телефона или email
+// весит больше, чем похожее имя: однофамильцы встречаются чаще, чем общий номер.
+func ScoreDuplicate(client, candidate *Client) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if phone := NormalizePhone(client.Phone); phone != "" && phone == NormalizePhone(candidate.Phone) {
+		score += 0.45
+		reasons = append(reasons, "phone")
+	}
+	if NormalizeEmail(client.Email) == NormalizeEmail(candidate.Email) {
+		score += 0.45
+		reasons = append(reasons, "email")
+	}
+	if similarity := NameSimilarity(client.FullName, candidate.FullName); similarity >= 0.8 {
+		score += 0.3 * similarity
+		reasons = append(reasons, "name")
+	}
+
+	return math.Round(math.Min(score, 1)*100) / 100, reasons
+}
+
+func normalizeName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	words := strings.Fields(name)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
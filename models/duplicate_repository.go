@@ -0,0 +1,223 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMergeConflict возвращается, если записи клиентов нельзя объединить без потери данных
+var ErrMergeConflict = errors.New("clients cannot be merged")
+
+// clientOwnedTables - таблицы, строки которых переносятся на оставшегося клиента при слиянии
+var clientOwnedTables = []string{
+	"appointments",
+	"session_notes",
+	"treatment_plans",
+	"progress_measurements",
+	"questionnaire_responses",
+	"consent_signatures",
+	"attachments",
+	"class_enrollments",
+	"packages",
+	"invoices",
+	"payments",
+	"waitlist_entries",
+	"loyalty_entries",
+	"surveys",
+	"feedback_responses",
+}
+
+type DuplicateRepository interface {
+	// FindDuplicateCandidates ищет клиентов с тем же нормализованным телефоном, email или именем,
+	// а также клиентов из nameMatchIDs (найденных нечетким поиском по имени), и оценивает их
+	FindDuplicateCandidates(client *Client, nameMatchIDs []uint) ([]DuplicateCandidate, error)
+	// MergeClients переносит данные duplicateID на survivorID и мягко удаляет дубликат
+	MergeClients(survivorID, duplicateID uint) (*MergeResult, error)
+}
+
+func (r *PostgresRepository) FindDuplicateCandidates(client *Client, nameMatchIDs []uint) ([]DuplicateCandidate, error) {
+	conditions := []string{"regexp_replace(lower(email), '\\+[^@]*@', '@') = ?", "lower(full_name) = ?"}
+	args := []interface{}{NormalizeEmail(client.Email), strings.ToLower(client.FullName)}
+	if phone := NormalizePhone(client.Phone); phone != "" {
+		conditions = append(conditions, "right(regexp_replace(phone, '\\D', '', 'g'), 10) = ?")
+		args = append(args, phone)
+	}
+	if len(nameMatchIDs) > 0 {
+		conditions = append(conditions, "id IN ?")
+		args = append(args, nameMatchIDs)
+	}
+
+	var clients []Client
+	err := r.db.Preload("Tags", orderTags).
+		Where("id <> ?", client.ID).
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Order("id").
+		Limit(100).
+		Find(&clients).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate candidates: %w", err)
+	}
+
+	candidates := make([]DuplicateCandidate, 0, len(clients))
+	for _, candidate := range clients {
+		score, reasons := ScoreDuplicate(client, &candidate)
+		if score >= MinDuplicateScore {
+			candidates = append(candidates, DuplicateCandidate{Client: candidate, Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	return candidates, nil
+}
+
+func (r *PostgresRepository) MergeClients(survivorID, duplicateID uint) (*MergeResult, error) {
+	result := &MergeResult{MergedID: duplicateID, Moved: map[string]int64{}}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем обоих клиентов в порядке id, чтобы встречные слияния не зациклились
+		var clients []Client
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{survivorID, duplicateID}).
+			Order("id").
+			Find(&clients).Error
+		if err != nil {
+			return err
+		}
+		if len(clients) != 2 {
+			return ErrNotFound
+		}
+		survivor, duplicate := &clients[0], &clients[1]
+		if survivor.ID != survivorID {
+			survivor, duplicate = duplicate, survivor
+		}
+
+		var shared int64
+		err = tx.Table("class_enrollments AS d").
+			Joins("JOIN class_enrollments AS s ON s.group_class_id = d.group_class_id").
+			Where("d.client_id = ? AND s.client_id = ?", duplicateID, survivorID).
+			Count(&shared).Error
+		if err != nil {
+			return err
+		}
+		if shared > 0 {
+			return fmt.Errorf("%w: both clients are enrolled in the same group class", ErrMergeConflict)
+		}
+
+		// Связи дубликата с самим оставшимся клиентом теряют смысл, а повторяющие уже имеющиеся - лишние
+		err = tx.Exec("DELETE FROM client_relationships WHERE (client_id = ? AND related_client_id = ?) OR (client_id = ? AND related_client_id = ?)",
+			duplicateID, survivorID, survivorID, duplicateID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`DELETE FROM client_relationships d WHERE d.client_id = ? AND EXISTS (
+			SELECT 1 FROM client_relationships s WHERE s.client_id = ? AND s.kind = d.kind
+			AND s.related_client_id IS NOT DISTINCT FROM d.related_client_id
+			AND s.organization_id IS NOT DISTINCT FROM d.organization_id)`, duplicateID, survivorID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`DELETE FROM client_relationships d WHERE d.related_client_id = ? AND EXISTS (
+			SELECT 1 FROM client_relationships s WHERE s.related_client_id = ? AND s.kind = d.kind AND s.client_id = d.client_id)`,
+			duplicateID, survivorID).Error
+		if err != nil {
+			return err
+		}
+		for _, column := range []string{"client_id", "related_client_id"} {
+			moved := tx.Table("client_relationships").Where(column+" = ?", duplicateID).Update(column, survivorID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			result.Moved["client_relationships"] += moved.RowsAffected
+		}
+		if result.Moved["client_relationships"] == 0 {
+			delete(result.Moved, "client_relationships")
+		}
+
+		for _, table := range clientOwnedTables {
+			moved := tx.Table(table).Where("client_id = ?", duplicateID).Update("client_id", survivorID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			if moved.RowsAffected > 0 {
+				result.Moved[table] = moved.RowsAffected
+			}
+		}
+
+		// Счета, которые дубликат оплачивал за других, теперь оплачивает оставшийся клиент
+		payer := tx.Table("invoices").Where("payer_client_id = ?", duplicateID).Update("payer_client_id", survivorID)
+		if payer.Error != nil {
+			return payer.Error
+		}
+		if payer.RowsAffected > 0 {
+			result.Moved["invoices_as_payer"] = payer.RowsAffected
+		}
+		for _, column := range []string{"purchaser_client_id", "recipient_client_id"} {
+			moved := tx.Table("gift_certificates").Where(column+" = ?", duplicateID).Update(column, survivorID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			result.Moved["gift_certificates"] += moved.RowsAffected
+		}
+		if result.Moved["gift_certificates"] == 0 {
+			delete(result.Moved, "gift_certificates")
+		}
+
+		// Реферал между двумя записями одного человека теряет смысл; пригласивший у клиента только один
+		err = tx.Exec(`DELETE FROM referrals WHERE (referrer_id = ? AND referred_id = ?) OR (referrer_id = ? AND referred_id = ?)
+			OR (referred_id = ? AND EXISTS (SELECT 1 FROM referrals s WHERE s.referred_id = ?))`,
+			survivorID, duplicateID, duplicateID, survivorID, duplicateID, survivorID).Error
+		if err != nil {
+			return err
+		}
+		for _, column := range []string{"referrer_id", "referred_id"} {
+			moved := tx.Table("referrals").Where(column+" = ?", duplicateID).Update(column, survivorID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			result.Moved["referrals"] += moved.RowsAffected
+		}
+		if result.Moved["referrals"] == 0 {
+			delete(result.Moved, "referrals")
+		}
+
+		err = tx.Exec("INSERT INTO client_tags (client_id, tag_id) SELECT ?, tag_id FROM client_tags WHERE client_id = ? ON CONFLICT DO NOTHING",
+			survivorID, duplicateID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM client_tags WHERE client_id = ?", duplicateID).Error; err != nil {
+			return err
+		}
+
+		// Анкета оставшегося клиента главная; из дубликата берем только то, чего в ней нет
+		if survivor.SpecialistID == nil {
+			survivor.SpecialistID = duplicate.SpecialistID
+		}
+		if survivor.BranchID == nil {
+			survivor.BranchID = duplicate.BranchID
+		}
+		if notes := strings.TrimSpace(duplicate.SpecialistNotes); notes != "" && !strings.Contains(survivor.SpecialistNotes, notes) {
+			survivor.SpecialistNotes = strings.TrimSpace(survivor.SpecialistNotes + "\n\n" + notes)
+		}
+		if err := tx.Omit("Tags").Save(survivor).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(duplicate).Error; err != nil {
+			return err
+		}
+
+		result.Survivor = survivor
+		return tx.Preload("Tags", orderTags).First(survivor, survivorID).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrMergeConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to merge clients: %w", translateError(err))
+	}
+	return result, nil
+}
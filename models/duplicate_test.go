@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestNormalizePhoneAndEmail(t *testing.T) {
+	for _, phone := range []string{"+79161234567", "8 (916) 123-45-67", "916 123 45 67"} {
+		if got := NormalizePhone(phone); got != "9161234567" {
+			t.Errorf("NormalizePhone(%q) = %q, want 9161234567", phone, got)
+		}
+	}
+
+	if got := NormalizeEmail(" Anna.Petrova+spa@Mail.RU "); got != "anna.petrova@mail.ru" {
+		t.Errorf("NormalizeEmail() = %q, want anna.petrova@mail.ru", got)
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	client := &Client{FullName: "Петрова Анна", Phone: "+79161234567", Email: "anna@mail.ru"}
+
+	tests := []struct {
+		name        string
+		candidate   Client
+		wantReasons int
+		wantMin     float64
+	}{
+		{"same phone, swapped name", Client{FullName: "Анна Петрова", Phone: "8 916 123-45-67", Email: "other@mail.ru"}, 2, 0.7},
+		{"typo in name only", Client{FullName: "Петрова Ана", Phone: "+79990000000", Email: "x@mail.ru"}, 1, MinDuplicateScore},
+		{"unrelated", Client{FullName: "Сидоров Петр", Phone: "+79990000000", Email: "x@mail.ru"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		score, reasons := ScoreDuplicate(client, &tt.candidate)
+		if len(reasons) != tt.wantReasons || score < tt.wantMin {
+			t.Errorf("%s: ScoreDuplicate() = %v, %v", tt.name, score, reasons)
+		}
+	}
+}
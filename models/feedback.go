@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// SurveyTTL - сколько действует ссылка на опрос после завершения визита
+const SurveyTTL = 30 * 24 * time.Hour
+
+// ErrSurveyExpired возвращается при попытке ответить на опрос после истечения срока ссылки
+var ErrSurveyExpired = errors.New("survey link has expired")
+
+// Survey - опрос клиента о завершенном визите. Клиент открывает его по публичной ссылке с Token
+// без авторизации; на один визит выдается один опрос, ответить на него можно один раз.
+type Survey struct {
+	ID            uint              `gorm:"primarykey"`
+	AppointmentID uint              `gorm:"not null;uniqueIndex"`
+	Appointment   *Appointment      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ClientID      uint              `gorm:"not null;index"`
+	Client        *Client           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID  uint              `gorm:"not null;index"`
+	Specialist    *Specialist       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Token         string            `gorm:"not null;uniqueIndex"`
+	ExpiresAt     time.Time         `gorm:"not null"`
+	CreatedAt     time.Time         `gorm:"not null"`
+	Response      *FeedbackResponse `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// FeedbackResponse - ответ клиента на опрос: оценка визита у специалиста от 1 до 5,
+// готовность рекомендовать центр от 0 до 10 для NPS и комментарий.
+// Клиент и специалист копируются из опроса, чтобы отчеты не соединяли таблицы.
+type FeedbackResponse struct {
+	ID           uint        `gorm:"primarykey"`
+	SurveyID     uint        `gorm:"not null;uniqueIndex"`
+	ClientID     uint        `gorm:"not null;index"`
+	Client       *Client     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID uint        `gorm:"not null;index"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Rating       int         `gorm:"not null"`
+	Recommend    int         `gorm:"not null"`
+	Comment      string
+	CreatedAt    time.Time `gorm:"not null;index"`
+}
+
+// FeedbackFilter - параметры выборки ответов; нулевые значения не ограничивают выборку
+type FeedbackFilter struct {
+	ClientID     uint
+	SpecialistID uint
+	BranchID     uint
+	From         time.Time
+	To           time.Time
+	Limit        int
+	Offset       int
+}
+
+// SpecialistRating - средняя оценка специалиста по ответам за период
+type SpecialistRating struct {
+	SpecialistID  uint
+	FullName      string
+	Responses     int64
+	AverageRating float64
+}
+
+// NPSReport - распределение ответов о готовности рекомендовать центр:
+// промоутеры ставят 9-10, критики - от 0 до 6, остальные нейтральны
+type NPSReport struct {
+	Responses  int64
+	Promoters  int64
+	Passives   int64
+	Detractors int64
+}
+
+// Expired сообщает, истек ли срок ссылки на опрос к моменту now
+func (s *Survey) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Score возвращает NPS - разницу долей промоутеров и критиков в процентах, от -100 до 100
+func (r NPSReport) Score() float64 {
+	if r.Responses == 0 {
+		return 0
+	}
+	score := float64(r.Promoters-r.Detractors) * 100 / float64(r.Responses)
+	return math.Round(score*10) / 10
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeedbackRepository interface {
+	// IssueSurvey возвращает опрос по визиту, создавая его из survey, если опроса еще нет.
+	// created сообщает, что опрос создан этим вызовом.
+	IssueSurvey(survey *Survey) (issued *Survey, created bool, err error)
+	GetSurveyByAppointment(appointmentID uint) (*Survey, error)
+	// GetSurveyByToken загружает опрос по публичной ссылке вместе со специалистом, визитом и ответом
+	GetSurveyByToken(token string) (*Survey, error)
+	// SubmitFeedback сохраняет ответ на опрос; повторный ответ - ErrDuplicate
+	SubmitFeedback(survey *Survey, response *FeedbackResponse, now time.Time) error
+	ListFeedback(filter FeedbackFilter) ([]FeedbackResponse, int64, error)
+	// SpecialistRatings считает средние оценки специалистов по ответам за [from, to) о визитах в филиал branchID;
+	// branchID 0 - по всему центру
+	SpecialistRatings(from, to time.Time, branchID uint) ([]SpecialistRating, error)
+	// NPSReport считает распределение ответов за [from, to); specialistID и branchID 0 - по всему центру
+	NPSReport(from, to time.Time, specialistID, branchID uint) (*NPSReport, error)
+}
+
+func (r *PostgresRepository) IssueSurvey(survey *Survey) (*Survey, bool, error) {
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "appointment_id"}}, DoNothing: true}).Create(survey)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to issue survey: %w", translateError(result.Error))
+	}
+	if result.RowsAffected > 0 {
+		return survey, true, nil
+	}
+
+	existing, err := r.GetSurveyByAppointment(survey.AppointmentID)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (r *PostgresRepository) GetSurveyByAppointment(appointmentID uint) (*Survey, error) {
+	var survey Survey
+	if err := r.db.Preload("Response").Where("appointment_id = ?", appointmentID).First(&survey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+	return &survey, nil
+}
+
+func (r *PostgresRepository) GetSurveyByToken(token string) (*Survey, error) {
+	var survey Survey
+	err := r.db.Preload("Specialist").Preload("Appointment").Preload("Response").
+		Where("token = ?", token).
+		First(&survey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+	return &survey, nil
+}
+
+func (r *PostgresRepository) SubmitFeedback(survey *Survey, response *FeedbackResponse, now time.Time) error {
+	if survey.Expired(now) {
+		return ErrSurveyExpired
+	}
+
+	response.SurveyID = survey.ID
+	response.ClientID = survey.ClientID
+	response.SpecialistID = survey.SpecialistID
+	response.CreatedAt = now
+	if err := r.db.Create(response).Error; err != nil {
+		return fmt.Errorf("failed to submit feedback: %w", translateError(err))
+	}
+	survey.Response = response
+	return nil
+}
+
+// feedbackInBranch - условие на ответ об оценке визита в филиале; филиал берется из записи опроса
+const feedbackInBranch = `survey_id IN (SELECT surveys.id FROM surveys
+	JOIN appointments ON appointments.id = surveys.appointment_id WHERE appointments.branch_id = ?)`
+
+func (r *PostgresRepository) ListFeedback(filter FeedbackFilter) ([]FeedbackResponse, int64, error) {
+	query := r.db.Model(&FeedbackResponse{})
+	if filter.ClientID != 0 {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.SpecialistID != 0 {
+		query = query.Where("specialist_id = ?", filter.SpecialistID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where(feedbackInBranch, filter.BranchID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count feedback: %w", err)
+	}
+
+	var responses []FeedbackResponse
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&responses).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list feedback: %w", err)
+	}
+	return responses, total, nil
+}
+
+func (r *PostgresRepository) SpecialistRatings(from, to time.Time, branchID uint) ([]SpecialistRating, error) {
+	query := r.db.Table("feedback_responses AS f").
+		Select("f.specialist_id, s.full_name, COUNT(*) AS responses, ROUND(AVG(f.rating), 2) AS average_rating").
+		Joins("JOIN specialists AS s ON s.id = f.specialist_id").
+		Where("f.created_at >= ? AND f.created_at < ?", from, to)
+	if branchID != 0 {
+		query = query.Where("f."+feedbackInBranch, branchID)
+	}
+
+	var ratings []SpecialistRating
+	err := query.Group("f.specialist_id, s.full_name").
+		Order("average_rating DESC, responses DESC, f.specialist_id").
+		Scan(&ratings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to build specialist ratings: %w", err)
+	}
+	return ratings, nil
+}
+
+func (r *PostgresRepository) NPSReport(from, to time.Time, specialistID, branchID uint) (*NPSReport, error) {
+	query := r.db.Model(&FeedbackResponse{}).
+		Select(`COUNT(*) AS responses,
+			COUNT(*) FILTER (WHERE recommend >= 9) AS promoters,
+			COUNT(*) FILTER (WHERE recommend BETWEEN 7 AND 8) AS passives,
+			COUNT(*) FILTER (WHERE recommend <= 6) AS detractors`).
+		Where("created_at >= ? AND created_at < ?", from, to)
+	if specialistID != 0 {
+		query = query.Where("specialist_id = ?", specialistID)
+	}
+	if branchID != 0 {
+		query = query.Where(feedbackInBranch, branchID)
+	}
+
+	var report NPSReport
+	if err := query.Scan(&report).Error; err != nil {
+		return nil, fmt.Errorf("failed to build NPS report: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNPSReportScore(t *testing.T) {
+	tests := []struct {
+		report NPSReport
+		want   float64
+	}{
+		{NPSReport{}, 0},
+		{NPSReport{Responses: 10, Promoters: 6, Passives: 2, Detractors: 2}, 40},
+		{NPSReport{Responses: 3, Promoters: 0, Passives: 1, Detractors: 2}, -66.7},
+		{NPSReport{Responses: 4, Promoters: 4}, 100},
+	}
+	for _, tt := range tests {
+		if got := tt.report.Score(); got != tt.want {
+			t.Errorf("%+v: Score() = %v, want %v", tt.report, got, tt.want)
+		}
+	}
+}
+
+func TestSurveyExpired(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	survey := &Survey{ExpiresAt: now.Add(SurveyTTL)}
+	if survey.Expired(now) {
+		t.Error("fresh survey is expired")
+	}
+	if !survey.Expired(now.Add(SurveyTTL)) {
+		t.Error("survey is not expired at ExpiresAt")
+	}
+}
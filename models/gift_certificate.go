@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Виды подарочных сертификатов: на сумму или на число сессий
+const (
+	GiftCertificateKindAmount   = "amount"
+	GiftCertificateKindSessions = "sessions"
+)
+
+// Статусы сертификата. Redeemed и expired не хранятся, а вычисляются в EffectiveStatus.
+const (
+	GiftCertificateStatusActive    = "active"
+	GiftCertificateStatusCancelled = "cancelled"
+	GiftCertificateStatusRedeemed  = "redeemed"
+	GiftCertificateStatusExpired   = "expired"
+)
+
+// ErrCertificateNotApplicable возвращается, если сертификатом нельзя оплатить счет
+var ErrCertificateNotApplicable = errors.New("gift certificate cannot be applied to this invoice")
+
+// GiftCertificate - подарочный сертификат. Сертификат на сумму оплачивает счета до исчерпания Balance,
+// сертификат на сессии - строки счета с визитами, по сессии за единицу. Покупатель и получатель
+// указываются ссылкой на клиента или именем; если указан RecipientClientID, сертификатом
+// оплачиваются только счета этого клиента.
+type GiftCertificate struct {
+	ID                uint   `gorm:"primarykey"`
+	Code              string `gorm:"not null;uniqueIndex"`
+	Kind              string `gorm:"not null"`
+	Amount            Money  `gorm:"type:numeric(12,2);not null;default:0"`
+	Balance           Money  `gorm:"type:numeric(12,2);not null;default:0"`
+	Sessions          *int
+	RemainingSessions *int
+	PurchaserClientID *uint   `gorm:"index"`
+	PurchaserClient   *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PurchaserName     string
+	RecipientClientID *uint   `gorm:"index"`
+	RecipientClient   *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	RecipientName     string
+	ExpiresAt         time.Time `gorm:"type:date;not null"`
+	Status            string    `gorm:"not null;default:active"`
+	Comment           string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Usages            []GiftCertificateUsage
+}
+
+// GiftCertificateUsage - оплата счета сертификатом: сумма платежа и списанные сессии.
+// Возврат оплаты на сертификат записывается такой же строкой с отрицательными суммой и сессиями.
+type GiftCertificateUsage struct {
+	ID                uint             `gorm:"primarykey"`
+	GiftCertificateID uint             `gorm:"not null;index"`
+	GiftCertificate   *GiftCertificate `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	InvoiceID         uint             `gorm:"not null;index"`
+	Invoice           *Invoice         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PaymentID         uint             `gorm:"not null;uniqueIndex"`
+	Payment           *Payment         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Amount            Money            `gorm:"type:numeric(12,2);not null"`
+	Sessions          int              `gorm:"not null;default:0"`
+	CreatedAt         time.Time
+}
+
+// EffectiveStatus возвращает статус сертификата с учетом срока действия и остатка на момент now
+func (g *GiftCertificate) EffectiveStatus(now time.Time) string {
+	if g.Status != GiftCertificateStatusActive {
+		return g.Status
+	}
+	if now.Format(dateLayout) > g.ExpiresAt.Format(dateLayout) {
+		return GiftCertificateStatusExpired
+	}
+	if g.Kind == GiftCertificateKindSessions {
+		if g.RemainingSessions == nil || *g.RemainingSessions <= 0 {
+			return GiftCertificateStatusRedeemed
+		}
+	} else if g.Balance <= 0 {
+		return GiftCertificateStatusRedeemed
+	}
+	return GiftCertificateStatusActive
+}
+
+// CheckApplicable проверяет, что сертификатом можно оплатить счет: он действует на дату счета
+// и, если указан получатель, счет выставлен ему
+func (g *GiftCertificate) CheckApplicable(invoice *Invoice) error {
+	if status := g.EffectiveStatus(invoice.IssuedAt); status != GiftCertificateStatusActive {
+		return fmt.Errorf("%w: certificate is %s", ErrCertificateNotApplicable, status)
+	}
+	if g.RecipientClientID != nil && *g.RecipientClientID != invoice.ClientID {
+		return fmt.Errorf("%w: certificate belongs to another client", ErrCertificateNotApplicable)
+	}
+	return nil
+}
+
+// Cover рассчитывает, какую часть неоплаченного остатка счета покрывает сертификат.
+// Для сертификата на сессии покрываются строки с визитами целиком, пока хватает сессий;
+// сумма строки уменьшается пропорционально скидке на счет. Если остаток счета меньше суммы строки,
+// списываются только сессии, стоимость которых он покрывает целиком.
+func (g *GiftCertificate) Cover(invoice *Invoice) (Money, int) {
+	outstanding := invoice.Outstanding()
+	if g.Kind != GiftCertificateKindSessions {
+		return min(g.Balance, outstanding), 0
+	}
+	if g.RemainingSessions == nil || invoice.Subtotal <= 0 {
+		return 0, 0
+	}
+
+	var amount Money
+	sessions := 0
+	for _, line := range invoice.Lines {
+		if line.AppointmentID == nil || sessions+line.Quantity > *g.RemainingSessions {
+			continue
+		}
+		value := Money(int64(line.Total) * int64(invoice.Total) / int64(invoice.Subtotal))
+		quantity := line.Quantity
+		if value > outstanding-amount {
+			quantity = int(int64(outstanding-amount) * int64(line.Quantity) / int64(value))
+			if quantity <= 0 {
+				continue
+			}
+			value = Money(int64(value) * int64(quantity) / int64(line.Quantity))
+		}
+		sessions += quantity
+		amount += value
+	}
+	return amount, sessions
+}
+
+// RefundedSessions возвращает, сколько сессий вернуть на сертификат, когда из оплаты u возвращается refund,
+// а раньше уже возвращено previously. Возвращается доля сессий, равная доле возврата, с округлением вниз,
+// поэтому после возврата всей суммы на сертификате снова все списанные сессии.
+func (u *GiftCertificateUsage) RefundedSessions(previously, refund Money) int {
+	if u.Amount <= 0 || u.Sessions <= 0 {
+		return 0
+	}
+	sessions := func(refunded Money) int {
+		return int(int64(u.Sessions) * int64(min(refunded, u.Amount)) / int64(u.Amount))
+	}
+	return sessions(previously+refund) - sessions(previously)
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GiftCertificateRepository interface {
+	CreateGiftCertificate(certificate *GiftCertificate) error
+	GetGiftCertificate(id uint) (*GiftCertificate, error)
+	ListGiftCertificates(filter GiftCertificateFilter) ([]GiftCertificate, int64, error)
+	CancelGiftCertificate(id uint) (*GiftCertificate, error)
+}
+
+// GiftCertificateFilter - параметры выборки сертификатов; ClientID ищет и по покупателю, и по получателю
+type GiftCertificateFilter struct {
+	Code     string
+	ClientID uint
+	Limit    int
+	Offset   int
+}
+
+func (r *PostgresRepository) CreateGiftCertificate(certificate *GiftCertificate) error {
+	if err := r.db.Create(certificate).Error; err != nil {
+		return fmt.Errorf("failed to create gift certificate: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetGiftCertificate(id uint) (*GiftCertificate, error) {
+	var certificate GiftCertificate
+	err := r.db.Preload("Usages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		First(&certificate, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get gift certificate: %w", err)
+	}
+	return &certificate, nil
+}
+
+func (r *PostgresRepository) ListGiftCertificates(filter GiftCertificateFilter) ([]GiftCertificate, int64, error) {
+	query := r.db.Model(&GiftCertificate{})
+
+	if filter.Code != "" {
+		query = query.Where("code = ?", filter.Code)
+	}
+	if filter.ClientID != 0 {
+		query = query.Where("purchaser_client_id = ? OR recipient_client_id = ?", filter.ClientID, filter.ClientID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count gift certificates: %w", err)
+	}
+
+	var certificates []GiftCertificate
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&certificates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list gift certificates: %w", err)
+	}
+	return certificates, total, nil
+}
+
+// CancelGiftCertificate аннулирует сертификат; проведенные им оплаты остаются в силе
+func (r *PostgresRepository) CancelGiftCertificate(id uint) (*GiftCertificate, error) {
+	var certificate GiftCertificate
+	result := r.db.Model(&certificate).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Update("status", GiftCertificateStatusCancelled)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel gift certificate: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &certificate, nil
+}
+
+// applyGiftCertificate оплачивает сертификатом новый счет в транзакции tx. Строка сертификата
+// блокируется, чтобы параллельные счета не потратили один остаток дважды.
+func applyGiftCertificate(tx *gorm.DB, invoice *Invoice, code string) error {
+	var certificate GiftCertificate
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&certificate).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownCode
+		}
+		return err
+	}
+
+	if err := certificate.CheckApplicable(invoice); err != nil {
+		return err
+	}
+
+	amount, sessions := certificate.Cover(invoice)
+	if amount <= 0 {
+		return fmt.Errorf("%w: nothing to pay with it", ErrCertificateNotApplicable)
+	}
+
+	payment := &Payment{
+		Kind:    PaymentKindPayment,
+		Method:  PaymentMethodGiftCertificate,
+		Amount:  amount,
+		PaidAt:  invoice.IssuedAt,
+		Comment: certificate.Code,
+	}
+	if err := applyPayment(tx, invoice, payment); err != nil {
+		return err
+	}
+	invoice.Payments = append(invoice.Payments, *payment)
+
+	updates := map[string]interface{}{}
+	if certificate.Kind == GiftCertificateKindSessions {
+		updates["remaining_sessions"] = gorm.Expr("remaining_sessions - ?", sessions)
+	} else {
+		updates["balance"] = gorm.Expr("balance - ?", amount)
+	}
+	if err := tx.Model(&certificate).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&GiftCertificateUsage{
+		GiftCertificateID: certificate.ID,
+		InvoiceID:         invoice.ID,
+		PaymentID:         payment.ID,
+		Amount:            amount,
+		Sessions:          sessions,
+	}).Error
+}
+
+// creditGiftCertificate возвращает на сертификат возврат refund по счету invoice, оплаченному им;
+// previously - сумма прежних возвратов на сертификат по этому счету
+func creditGiftCertificate(tx *gorm.DB, invoice *Invoice, refund *Payment, previously Money) error {
+	var usage GiftCertificateUsage
+	if err := tx.Where("invoice_id = ? AND amount > 0", invoice.ID).Order("id").First(&usage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefundExceedsPaid
+		}
+		return err
+	}
+
+	var certificate GiftCertificate
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&certificate, usage.GiftCertificateID).Error; err != nil {
+		return err
+	}
+
+	sessions := usage.RefundedSessions(previously, refund.Amount)
+	updates := map[string]interface{}{}
+	if certificate.Kind == GiftCertificateKindSessions {
+		updates["remaining_sessions"] = gorm.Expr("remaining_sessions + ?", sessions)
+	} else {
+		updates["balance"] = gorm.Expr("balance + ?", refund.Amount)
+	}
+	if err := tx.Model(&certificate).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&GiftCertificateUsage{
+		GiftCertificateID: certificate.ID,
+		InvoiceID:         invoice.ID,
+		PaymentID:         refund.ID,
+		Amount:            -refund.Amount,
+		Sessions:          -sessions,
+	}).Error
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGiftCertificateEffectiveStatus(t *testing.T) {
+	expires := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	sessions := func(n int) *int { return &n }
+
+	tests := []struct {
+		name        string
+		certificate GiftCertificate
+		now         time.Time
+		want        string
+	}{
+		{"amount with balance", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 100000, Status: GiftCertificateStatusActive},
+			expires, GiftCertificateStatusActive},
+		{"amount spent", GiftCertificate{Kind: GiftCertificateKindAmount, Status: GiftCertificateStatusActive},
+			expires, GiftCertificateStatusRedeemed},
+		{"sessions left", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(1), Status: GiftCertificateStatusActive},
+			expires, GiftCertificateStatusActive},
+		{"sessions spent", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(0), Status: GiftCertificateStatusActive},
+			expires, GiftCertificateStatusRedeemed},
+		{"last day", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 100000, Status: GiftCertificateStatusActive},
+			expires.Add(23 * time.Hour), GiftCertificateStatusActive},
+		{"expired", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 100000, Status: GiftCertificateStatusActive},
+			expires.AddDate(0, 0, 1), GiftCertificateStatusExpired},
+		{"cancelled", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 100000, Status: GiftCertificateStatusCancelled},
+			expires, GiftCertificateStatusCancelled},
+	}
+	for _, tt := range tests {
+		tt.certificate.ExpiresAt = expires
+		if got := tt.certificate.EffectiveStatus(tt.now); got != tt.want {
+			t.Errorf("%s: EffectiveStatus() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGiftCertificateCover(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	sessions := func(n int) *int { return &n }
+	// Счет на 10 000 со скидкой 10%: два визита по 3 000 (второй - две сессии) и товар на 2 000
+	invoice := &Invoice{
+		Lines: []InvoiceLine{
+			{AppointmentID: id(1), Quantity: 1, Total: 300000},
+			{AppointmentID: id(2), Quantity: 2, Total: 600000},
+			{ProductID: id(3), Quantity: 1, Total: 100000},
+		},
+		Subtotal: 1000000,
+		Total:    900000,
+	}
+
+	tests := []struct {
+		name         string
+		certificate  GiftCertificate
+		paid         Money
+		wantAmount   Money
+		wantSessions int
+	}{
+		{"amount covers part", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 250000}, 0, 250000, 0},
+		{"amount over outstanding", GiftCertificate{Kind: GiftCertificateKindAmount, Balance: 2000000}, 100000, 800000, 0},
+		{"sessions cover all visits", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(5)}, 0, 810000, 3},
+		{"sessions skip line that does not fit", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(1)}, 0, 270000, 1},
+		{"sessions limited by outstanding", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(3)}, 500000, 270000, 1},
+		{"sessions partly cover a line", GiftCertificate{Kind: GiftCertificateKindSessions, RemainingSessions: sessions(5)}, 300000, 540000, 2},
+		{"no sessions", GiftCertificate{Kind: GiftCertificateKindSessions}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		invoice.PaidTotal = tt.paid
+		amount, used := tt.certificate.Cover(invoice)
+		if amount != tt.wantAmount || used != tt.wantSessions {
+			t.Errorf("%s: Cover() = %d, %d, want %d, %d", tt.name, amount, used, tt.wantAmount, tt.wantSessions)
+		}
+	}
+}
+
+func TestGiftCertificateCheckApplicable(t *testing.T) {
+	recipient := uint(7)
+	issued := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	certificate := GiftCertificate{
+		Kind:              GiftCertificateKindAmount,
+		Balance:           100000,
+		RecipientClientID: &recipient,
+		ExpiresAt:         issued.AddDate(0, 1, 0),
+		Status:            GiftCertificateStatusActive,
+	}
+
+	if err := certificate.CheckApplicable(&Invoice{ClientID: 7, IssuedAt: issued}); err != nil {
+		t.Errorf("recipient invoice: %v", err)
+	}
+	if err := certificate.CheckApplicable(&Invoice{ClientID: 8, IssuedAt: issued}); !errors.Is(err, ErrCertificateNotApplicable) {
+		t.Errorf("other client invoice: err = %v, want ErrCertificateNotApplicable", err)
+	}
+	if err := certificate.CheckApplicable(&Invoice{ClientID: 7, IssuedAt: issued.AddDate(0, 2, 0)}); !errors.Is(err, ErrCertificateNotApplicable) {
+		t.Errorf("invoice after expiry: err = %v, want ErrCertificateNotApplicable", err)
+	}
+}
+
+func TestGiftCertificateUsageRefundedSessions(t *testing.T) {
+	usage := GiftCertificateUsage{Amount: 900000, Sessions: 3}
+	tests := []struct {
+		previously Money
+		refund     Money
+		want       int
+	}{
+		{0, 900000, 3},
+		{0, 300000, 1},
+		{0, 200000, 0},
+		{200000, 200000, 1},
+		{400000, 500000, 2},
+	}
+	for _, tt := range tests {
+		if got := usage.RefundedSessions(tt.previously, tt.refund); got != tt.want {
+			t.Errorf("RefundedSessions(%d, %d) = %d, want %d", tt.previously, tt.refund, got, tt.want)
+		}
+	}
+}
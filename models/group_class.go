@@ -0,0 +1,111 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы группового занятия
+const (
+	GroupClassStatusScheduled = "scheduled"
+	GroupClassStatusCancelled = "cancelled"
+)
+
+// Статусы записи клиента на групповое занятие
+const (
+	EnrollmentStatusEnrolled   = "enrolled"
+	EnrollmentStatusWaitlisted = "waitlisted"
+	EnrollmentStatusCancelled  = "cancelled"
+	EnrollmentStatusAttended   = "attended"
+	EnrollmentStatusNoShow     = "no_show"
+)
+
+// GroupClass - групповое занятие (йога, групповой фитнес) с инструктором и ограниченным числом мест.
+// Service определяет, какими абонементами оплачивается посещение; пустое значение - специализация инструктора.
+type GroupClass struct {
+	gorm.Model
+	Title        string `gorm:"not null"`
+	Service      string
+	SpecialistID uint        `gorm:"not null;index"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	// BranchID - филиал инструктора на момент создания занятия
+	BranchID    uint      `gorm:"not null;index"`
+	Branch      *Branch   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Room        string    `gorm:"index"`
+	StartsAt    time.Time `gorm:"not null;index"`
+	EndsAt      time.Time `gorm:"not null"`
+	Capacity    int       `gorm:"not null"`
+	Status      string    `gorm:"not null;default:scheduled"`
+	Description string
+	Enrollments []ClassEnrollment
+	// EnrolledCount - число занятых мест, вычисляется при чтении
+	EnrolledCount int `gorm:"->;-:migration"`
+}
+
+// ClassEnrollment - запись клиента на занятие. При повторной записи после отмены используется
+// та же строка; QueuedAt задает порядок в листе ожидания.
+type ClassEnrollment struct {
+	ID           uint        `gorm:"primarykey"`
+	GroupClassID uint        `gorm:"not null;uniqueIndex:idx_class_enrollment_client"`
+	GroupClass   *GroupClass `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ClientID     uint        `gorm:"not null;uniqueIndex:idx_class_enrollment_client;index"`
+	Client       *Client     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Status       string      `gorm:"not null;index"`
+	QueuedAt     time.Time   `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// GroupClassFilter - параметры выборки расписания групповых занятий
+type GroupClassFilter struct {
+	SpecialistID uint
+	BranchID     uint
+	Status       string
+	From         time.Time
+	To           time.Time
+	Limit        int
+	Offset       int
+}
+
+// seatStatuses - статусы записи, при которых клиент занимает место на занятии
+var seatStatuses = []string{EnrollmentStatusEnrolled, EnrollmentStatusAttended, EnrollmentStatusNoShow}
+
+// IsActive сообщает, записан ли клиент на занятие или стоит в листе ожидания
+func (e *ClassEnrollment) IsActive() bool {
+	return e.Status == EnrollmentStatusEnrolled || e.Status == EnrollmentStatusWaitlisted
+}
+
+// FreeSeats возвращает число свободных мест на занятии
+func (g *GroupClass) FreeSeats() int {
+	if free := g.Capacity - g.EnrolledCount; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// HasSeat сообщает, остается ли на занятии свободное место при taken занятых
+func (g *GroupClass) HasSeat(taken int64) bool {
+	return taken < int64(g.Capacity)
+}
+
+// nextWaitlisted выбирает из листа ожидания не больше free записей в порядке очереди
+func nextWaitlisted(waitlisted []ClassEnrollment, free int64) []ClassEnrollment {
+	queue := make([]ClassEnrollment, 0, len(waitlisted))
+	for _, enrollment := range waitlisted {
+		if enrollment.Status == EnrollmentStatusWaitlisted {
+			queue = append(queue, enrollment)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		if !queue[i].QueuedAt.Equal(queue[j].QueuedAt) {
+			return queue[i].QueuedAt.Before(queue[j].QueuedAt)
+		}
+		return queue[i].ID < queue[j].ID
+	})
+	if int64(len(queue)) > free {
+		queue = queue[:max(free, 0)]
+	}
+	return queue
+}
@@ -0,0 +1,411 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrClassClosed возвращается, если занятие отменено или уже началось
+	ErrClassClosed = errors.New("group class is closed")
+	// ErrAlreadyEnrolled возвращается при повторной записи клиента на занятие
+	ErrAlreadyEnrolled = errors.New("client is already enrolled in the class")
+	// ErrNotEnrolled возвращается, если запись клиента не в том статусе, который нужен для операции
+	ErrNotEnrolled = errors.New("client is not enrolled in the class")
+	// ErrCapacityTooLow возвращается при уменьшении вместимости ниже числа занятых мест
+	ErrCapacityTooLow = errors.New("capacity is less than the number of enrolled clients")
+	// ErrClassNotStarted возвращается при отметке посещения занятия, которое еще не началось
+	ErrClassNotStarted = errors.New("group class has not started yet")
+)
+
+type GroupClassRepository interface {
+	CreateGroupClass(class *GroupClass) error
+	GetGroupClass(id uint) (*GroupClass, error)
+	ListGroupClasses(filter GroupClassFilter) ([]GroupClass, int64, error)
+	// UpdateGroupClass сохраняет изменения запланированного занятия и возвращает записи, переведенные из листа ожидания на освободившиеся места
+	UpdateGroupClass(class *GroupClass) ([]ClassEnrollment, error)
+	// CancelGroupClass отменяет занятие вместе с записями и возвращает отмененные записи
+	CancelGroupClass(id uint) (*GroupClass, []ClassEnrollment, error)
+	EnrollClient(classID, clientID uint, now time.Time) (*ClassEnrollment, error)
+	// CancelEnrollment отменяет запись клиента и возвращает записи, переведенные из листа ожидания
+	CancelEnrollment(classID, clientID uint, now time.Time) (*ClassEnrollment, []ClassEnrollment, error)
+	// MarkAttendance отмечает посещение или неявку на начавшееся занятие; за посещение списывается сессия с абонемента
+	MarkAttendance(classID, clientID uint, status string, packageID *uint, now time.Time) (*ClassEnrollment, *Package, *PackageDebit, error)
+}
+
+func (r *PostgresRepository) CreateGroupClass(class *GroupClass) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkGroupClassOverlap(tx, class); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(class).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrOverlap) {
+			return err
+		}
+		return fmt.Errorf("failed to create group class: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetGroupClass(id uint) (*GroupClass, error) {
+	var class GroupClass
+	err := r.db.Preload("Enrollments", func(db *gorm.DB) *gorm.DB { return db.Order("queued_at, id") }).
+		First(&class, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get group class: %w", err)
+	}
+
+	for _, enrollment := range class.Enrollments {
+		for _, status := range seatStatuses {
+			if enrollment.Status == status {
+				class.EnrolledCount++
+			}
+		}
+	}
+	return &class, nil
+}
+
+func (r *PostgresRepository) ListGroupClasses(filter GroupClassFilter) ([]GroupClass, int64, error) {
+	query := r.db.Model(&GroupClass{})
+	if filter.SpecialistID != 0 {
+		query = query.Where("specialist_id = ?", filter.SpecialistID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("ends_at > ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("starts_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count group classes: %w", err)
+	}
+
+	var classes []GroupClass
+	err := query.Select("group_classes.*, (?) AS enrolled_count",
+		r.db.Model(&ClassEnrollment{}).
+			Select("COUNT(*)").
+			Where("class_enrollments.group_class_id = group_classes.id").
+			Where("class_enrollments.status IN ?", seatStatuses)).
+		Order("starts_at, id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&classes).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list group classes: %w", err)
+	}
+	return classes, total, nil
+}
+
+func (r *PostgresRepository) UpdateGroupClass(class *GroupClass) ([]ClassEnrollment, error) {
+	var promoted []ClassEnrollment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current GroupClass
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, class.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != GroupClassStatusScheduled {
+			return ErrClassClosed
+		}
+		if err := checkGroupClassOverlap(tx, class); err != nil {
+			return err
+		}
+
+		taken, err := countTakenSeats(tx, class.ID)
+		if err != nil {
+			return err
+		}
+		if int64(class.Capacity) < taken {
+			return ErrCapacityTooLow
+		}
+
+		// Статус и записи меняются отдельными операциями, поэтому сохраняются только редактируемые поля
+		err = tx.Model(class).
+			Select("title", "service", "specialist_id", "branch_id", "room", "starts_at", "ends_at", "capacity", "description").
+			Updates(class).Error
+		if err != nil {
+			return err
+		}
+		class.Status = current.Status
+		promoted, err = promoteWaitlisted(tx, class)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		if errors.Is(err, ErrOverlap) || errors.Is(err, ErrCapacityTooLow) || errors.Is(err, ErrClassClosed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update group class: %w", translateError(err))
+	}
+	return promoted, nil
+}
+
+func (r *PostgresRepository) CancelGroupClass(id uint) (*GroupClass, []ClassEnrollment, error) {
+	var (
+		class     GroupClass
+		cancelled []ClassEnrollment
+	)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&class, id).Error; err != nil {
+			return err
+		}
+		if class.Status == GroupClassStatusCancelled {
+			return ErrClassClosed
+		}
+
+		class.Status = GroupClassStatusCancelled
+		if err := tx.Model(&class).Update("status", class.Status).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&cancelled).
+			Clauses(clause.Returning{}).
+			Where("group_class_id = ? AND status IN ?", id, []string{EnrollmentStatusEnrolled, EnrollmentStatusWaitlisted}).
+			Update("status", EnrollmentStatusCancelled).Error
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		if errors.Is(err, ErrClassClosed) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to cancel group class: %w", err)
+	}
+	return &class, cancelled, nil
+}
+
+// EnrollClient записывает клиента на занятие. Если свободных мест нет, клиент попадает в лист ожидания.
+func (r *PostgresRepository) EnrollClient(classID, clientID uint, now time.Time) (*ClassEnrollment, error) {
+	var enrollment ClassEnrollment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		class, err := loadOpenGroupClass(tx, classID, now)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("group_class_id = ? AND client_id = ?", classID, clientID).First(&enrollment).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && enrollment.Status != EnrollmentStatusCancelled {
+			return ErrAlreadyEnrolled
+		}
+
+		taken, err := countTakenSeats(tx, classID)
+		if err != nil {
+			return err
+		}
+
+		enrollment.GroupClassID = classID
+		enrollment.ClientID = clientID
+		enrollment.QueuedAt = now
+		enrollment.Status = EnrollmentStatusWaitlisted
+		if class.HasSeat(taken) {
+			enrollment.Status = EnrollmentStatusEnrolled
+		}
+		return tx.Save(&enrollment).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrClassClosed) || errors.Is(err, ErrAlreadyEnrolled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to enroll client: %w", translateError(err))
+	}
+	return &enrollment, nil
+}
+
+func (r *PostgresRepository) CancelEnrollment(classID, clientID uint, now time.Time) (*ClassEnrollment, []ClassEnrollment, error) {
+	var (
+		enrollment ClassEnrollment
+		promoted   []ClassEnrollment
+	)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		class, err := loadOpenGroupClass(tx, classID, now)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("group_class_id = ? AND client_id = ?", classID, clientID).
+			First(&enrollment).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotEnrolled
+			}
+			return err
+		}
+		if !enrollment.IsActive() {
+			return ErrNotEnrolled
+		}
+
+		freesSeat := enrollment.Status == EnrollmentStatusEnrolled
+		enrollment.Status = EnrollmentStatusCancelled
+		if err := tx.Model(&enrollment).Update("status", enrollment.Status).Error; err != nil {
+			return err
+		}
+		if freesSeat {
+			promoted, err = promoteWaitlisted(tx, class)
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrClassClosed) || errors.Is(err, ErrNotEnrolled) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to cancel enrollment: %w", err)
+	}
+	return &enrollment, promoted, nil
+}
+
+func (r *PostgresRepository) MarkAttendance(classID, clientID uint, status string, packageID *uint, now time.Time) (*ClassEnrollment, *Package, *PackageDebit, error) {
+	var (
+		enrollment ClassEnrollment
+		debited    *Package
+		debit      *PackageDebit
+	)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var class GroupClass
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&class, classID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if class.Status != GroupClassStatusScheduled {
+			return ErrClassClosed
+		}
+		if class.StartsAt.After(now) {
+			return ErrClassNotStarted
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("group_class_id = ? AND client_id = ?", classID, clientID).
+			First(&enrollment).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotEnrolled
+			}
+			return err
+		}
+		if enrollment.Status != EnrollmentStatusEnrolled {
+			return ErrNotEnrolled
+		}
+
+		enrollment.Status = status
+		if err := tx.Save(&enrollment).Error; err != nil {
+			return err
+		}
+		if status != EnrollmentStatusAttended {
+			return nil
+		}
+
+		service := class.Service
+		if service == "" {
+			var specialist Specialist
+			if err := tx.First(&specialist, class.SpecialistID).Error; err != nil {
+				return err
+			}
+			service = specialist.Specialization
+		}
+		enrollmentID := enrollment.ID
+		debited, debit, err = debitPackage(tx, clientID, service, class.StartsAt, packageID,
+			PackageDebit{ClassEnrollmentID: &enrollmentID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrClassClosed) || errors.Is(err, ErrNotEnrolled) ||
+			errors.Is(err, ErrClassNotStarted) || errors.Is(err, ErrNoPackage) {
+			return nil, nil, nil, err
+		}
+		return nil, nil, nil, fmt.Errorf("failed to mark attendance: %w", translateError(err))
+	}
+	return &enrollment, debited, debit, nil
+}
+
+// loadOpenGroupClass блокирует занятие и проверяет, что на него еще можно записаться или отменить запись
+func loadOpenGroupClass(tx *gorm.DB, id uint, now time.Time) (*GroupClass, error) {
+	var class GroupClass
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&class, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if class.Status != GroupClassStatusScheduled || !class.StartsAt.After(now) {
+		return nil, ErrClassClosed
+	}
+	return &class, nil
+}
+
+func countTakenSeats(tx *gorm.DB, classID uint) (int64, error) {
+	var taken int64
+	err := tx.Model(&ClassEnrollment{}).
+		Where("group_class_id = ? AND status IN ?", classID, seatStatuses).
+		Count(&taken).Error
+	return taken, err
+}
+
+// promoteWaitlisted переводит клиентов из листа ожидания на свободные места в порядке очереди
+func promoteWaitlisted(tx *gorm.DB, class *GroupClass) ([]ClassEnrollment, error) {
+	taken, err := countTakenSeats(tx, class.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !class.HasSeat(taken) {
+		return nil, nil
+	}
+
+	var waitlisted []ClassEnrollment
+	if err := tx.Where("group_class_id = ? AND status = ?", class.ID, EnrollmentStatusWaitlisted).Find(&waitlisted).Error; err != nil {
+		return nil, err
+	}
+	promoted := nextWaitlisted(waitlisted, int64(class.Capacity)-taken)
+	if len(promoted) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(promoted))
+	for i := range promoted {
+		promoted[i].Status = EnrollmentStatusEnrolled
+		ids = append(ids, promoted[i].ID)
+	}
+	if err := tx.Model(&ClassEnrollment{}).Where("id IN ?", ids).Update("status", EnrollmentStatusEnrolled).Error; err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// checkGroupClassOverlap проверяет, что инструктор и зал филиала свободны на время занятия
+func checkGroupClassOverlap(tx *gorm.DB, class *GroupClass) error {
+	if err := lockSchedule(tx, class.SpecialistID, class.BranchID, class.Room); err != nil {
+		return err
+	}
+	conflict, err := hasScheduleConflict(tx, 0, class.ID, class.SpecialistID, class.BranchID, class.Room, class.StartsAt, class.EndsAt)
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return ErrOverlap
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupClassSeats(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		taken    int
+		wantFree int
+		wantSeat bool
+	}{
+		{"empty", 10, 0, 10, true},
+		{"one left", 10, 9, 1, true},
+		{"full", 10, 10, 0, false},
+		{"overbooked after capacity cut", 8, 10, 0, false},
+	}
+	for _, tt := range tests {
+		class := &GroupClass{Capacity: tt.capacity, EnrolledCount: tt.taken}
+		if got := class.FreeSeats(); got != tt.wantFree {
+			t.Errorf("%s: FreeSeats() = %d, want %d", tt.name, got, tt.wantFree)
+		}
+		if got := class.HasSeat(int64(tt.taken)); got != tt.wantSeat {
+			t.Errorf("%s: HasSeat(%d) = %v, want %v", tt.name, tt.taken, got, tt.wantSeat)
+		}
+	}
+}
+
+func TestNextWaitlisted(t *testing.T) {
+	at := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	waitlisted := []ClassEnrollment{
+		{ID: 4, Status: EnrollmentStatusWaitlisted, QueuedAt: at.Add(2 * time.Minute)},
+		{ID: 3, Status: EnrollmentStatusWaitlisted, QueuedAt: at},
+		{ID: 1, Status: EnrollmentStatusCancelled, QueuedAt: at.Add(-time.Hour)},
+		{ID: 2, Status: EnrollmentStatusWaitlisted, QueuedAt: at},
+	}
+
+	tests := []struct {
+		free int64
+		want []uint
+	}{
+		{0, nil},
+		{2, []uint{2, 3}},
+		{5, []uint{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		got := nextWaitlisted(waitlisted, tt.free)
+		if len(got) != len(tt.want) {
+			t.Fatalf("nextWaitlisted(free=%d) = %+v, want ids %v", tt.free, got, tt.want)
+		}
+		for i, enrollment := range got {
+			if enrollment.ID != tt.want[i] {
+				t.Errorf("nextWaitlisted(free=%d)[%d].ID = %d, want %d", tt.free, i, enrollment.ID, tt.want[i])
+			}
+		}
+	}
+}
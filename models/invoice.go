@@ -0,0 +1,171 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы счета
+const (
+	InvoiceStatusIssued        = "issued"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusCancelled     = "cancelled"
+)
+
+// Способы оплаты. Loyalty - оплата баллами программы лояльности, GiftCertificate - подарочным
+// сертификатом; такие оплаты проводятся только погашением баллов или сертификата.
+const (
+	PaymentMethodCash            = "cash"
+	PaymentMethodCard            = "card"
+	PaymentMethodTransfer        = "transfer"
+	PaymentMethodLoyalty         = "loyalty"
+	PaymentMethodGiftCertificate = "gift_certificate"
+)
+
+// nonCashPaymentMethods - оплаты баллами и сертификатами: в кассу и выручку они не попадают
+var nonCashPaymentMethods = []string{PaymentMethodLoyalty, PaymentMethodGiftCertificate}
+
+func isNonCashMethod(method string) bool {
+	return method == PaymentMethodLoyalty || method == PaymentMethodGiftCertificate
+}
+
+// Виды движений денег по счету
+const (
+	PaymentKindPayment = "payment"
+	PaymentKindRefund  = "refund"
+)
+
+// Invoice - счет клиенту. Суммы пересчитываются из строк методом Recalculate,
+// PaidTotal - сумма оплат за вычетом возвратов. Если указан плательщик - опекун, родственник
+// (PayerClientID) или организация (PayerOrganizationID), - долг по счету числится за ним.
+// Discount включает скидку по промокоду PromoDiscount.
+type Invoice struct {
+	gorm.Model
+	Number              string        `gorm:"uniqueIndex"`
+	ClientID            uint          `gorm:"not null;index"`
+	Client              *Client       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PayerClientID       *uint         `gorm:"index"`
+	PayerClient         *Client       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PayerOrganizationID *uint         `gorm:"index"`
+	PayerOrganization   *Organization `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PromoCodeID         *uint         `gorm:"index"`
+	PromoCode           *PromoCode    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PromoDiscount       Money         `gorm:"type:numeric(12,2);not null;default:0"`
+	Status              string        `gorm:"not null;default:issued;index"`
+	Subtotal            Money         `gorm:"type:numeric(12,2);not null;default:0"`
+	Discount            Money         `gorm:"type:numeric(12,2);not null;default:0"`
+	Total               Money         `gorm:"type:numeric(12,2);not null;default:0"`
+	PaidTotal           Money         `gorm:"type:numeric(12,2);not null;default:0"`
+	IssuedAt            time.Time     `gorm:"not null;index"`
+	Comment             string
+	Lines               []InvoiceLine
+	Payments            []Payment
+}
+
+// InvoiceLine - строка счета: визит, абонемент, товар или произвольная услуга.
+// Товар списывается со склада филиала BranchID при выставлении счета.
+type InvoiceLine struct {
+	ID              uint         `gorm:"primarykey"`
+	InvoiceID       uint         `gorm:"not null;index"`
+	AppointmentID   *uint        `gorm:"index"`
+	Appointment     *Appointment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PackageID       *uint        `gorm:"index"`
+	Package         *Package     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ProductID       *uint        `gorm:"index"`
+	Product         *Product     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	BranchID        *uint        `gorm:"index"`
+	Branch          *Branch      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Description     string       `gorm:"not null"`
+	Quantity        int          `gorm:"not null"`
+	UnitPrice       Money        `gorm:"type:numeric(12,2);not null"`
+	DiscountPercent int          `gorm:"not null;default:0"`
+	Total           Money        `gorm:"type:numeric(12,2);not null"`
+}
+
+// Payment - оплата или возврат по счету. Сумма всегда положительная, направление задает Kind.
+type Payment struct {
+	ID        uint      `gorm:"primarykey"`
+	InvoiceID uint      `gorm:"not null;index"`
+	Invoice   *Invoice  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ClientID  uint      `gorm:"not null;index"`
+	Kind      string    `gorm:"not null"`
+	Method    string    `gorm:"not null"`
+	Amount    Money     `gorm:"type:numeric(12,2);not null"`
+	PaidAt    time.Time `gorm:"not null;index"`
+	Comment   string
+	CreatedAt time.Time
+}
+
+// InvoiceCodes - коды, погашаемые при выставлении счета: промокод на скидку
+// и подарочный сертификат, которым сразу оплачивается счет
+type InvoiceCodes struct {
+	PromoCode           string
+	GiftCertificateCode string
+}
+
+// InvoiceFilter - параметры выборки списка счетов
+type InvoiceFilter struct {
+	ClientID            uint
+	PayerClientID       uint
+	PayerOrganizationID uint
+	Status              string
+	BranchID            uint
+	From                time.Time
+	To                  time.Time
+	Limit               int
+	Offset              int
+}
+
+// ClientBalance - сводка расчетов с плательщиком (клиентом или организацией) по неотмененным счетам
+type ClientBalance struct {
+	Invoiced    Money
+	Paid        Money
+	Outstanding Money
+}
+
+// CashReportRow - итог движений денег за период по способу оплаты и виду операции
+type CashReportRow struct {
+	Method string
+	Kind   string
+	Count  int64
+	Amount Money
+}
+
+// Recalculate пересчитывает суммы строк и итоги счета. Скидка на счет не может превышать сумму строк.
+func (inv *Invoice) Recalculate() {
+	inv.Subtotal = 0
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		gross := line.UnitPrice * Money(line.Quantity)
+		line.Total = gross - gross.Percent(line.DiscountPercent)
+		inv.Subtotal += line.Total
+	}
+
+	if inv.Discount > inv.Subtotal {
+		inv.Discount = inv.Subtotal
+	}
+	inv.Total = inv.Subtotal - inv.Discount
+	inv.refreshStatus()
+}
+
+// Outstanding возвращает сумму, которую осталось оплатить
+func (inv *Invoice) Outstanding() Money {
+	if inv.Status == InvoiceStatusCancelled {
+		return 0
+	}
+	return inv.Total - inv.PaidTotal
+}
+
+func (inv *Invoice) refreshStatus() {
+	switch {
+	case inv.Status == InvoiceStatusCancelled:
+	case inv.PaidTotal >= inv.Total:
+		inv.Status = InvoiceStatusPaid
+	case inv.PaidTotal > 0:
+		inv.Status = InvoiceStatusPartiallyPaid
+	default:
+		inv.Status = InvoiceStatusIssued
+	}
+}
@@ -0,0 +1,419 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyInvoiced    = errors.New("appointment or package is already invoiced")
+	ErrInvoiceCancelled   = errors.New("invoice is cancelled")
+	ErrInvoiceHasPayments = errors.New("invoice has payments, refund them first")
+	ErrOverpayment        = errors.New("payment exceeds outstanding amount")
+	ErrRefundExceedsPaid  = errors.New("refund exceeds paid amount")
+)
+
+type InvoiceRepository interface {
+	// CreateInvoice выставляет счет, применяя промокод и оплачивая его подарочным сертификатом из codes
+	CreateInvoice(invoice *Invoice, codes InvoiceCodes) error
+	GetInvoice(id uint) (*Invoice, error)
+	ListInvoices(filter InvoiceFilter) ([]Invoice, int64, error)
+	// CancelInvoice отменяет неоплаченный счет, освобождая промокод и возвращая проданные товары на склад
+	CancelInvoice(id uint) (*Invoice, error)
+	AddPayment(invoiceID uint, payment *Payment) (*Invoice, error)
+	// AddRefund проводит возврат тем способом, которым счет был оплачен, и возвращает записи журнала баллов:
+	// возврат баллов клиенту или отмену доли баллов, начисленных за счет. Возврат сертификатом
+	// восстанавливает его остаток.
+	AddRefund(invoiceID uint, refund *Payment) (*Invoice, []LoyaltyEntry, error)
+	// GetClientBalance считает счета, которые оплачивает клиент: свои без стороннего плательщика
+	// и чужие, где он указан плательщиком
+	GetClientBalance(clientID uint) (*ClientBalance, error)
+	GetOrganizationBalance(organizationID uint) (*ClientBalance, error)
+	GetCashReport(from, to time.Time, branchID uint) ([]CashReportRow, error)
+}
+
+// CreateInvoice сохраняет счет со строками и списывает проданные товары со склада. Визит или абонемент
+// нельзя выставить повторно, пока предыдущий счет на них не отменен; отмененный или пропущенный визит
+// не выставляется.
+func (r *PostgresRepository) CreateInvoice(invoice *Invoice, codes InvoiceCodes) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, line := range invoice.Lines {
+			if line.AppointmentID == nil && line.PackageID == nil {
+				continue
+			}
+
+			// Блокировка визита или абонемента до конца транзакции не дает двум счетам одновременно
+			// пройти проверку ниже
+			query := tx.Model(&InvoiceLine{}).
+				Joins("JOIN invoices ON invoices.id = invoice_lines.invoice_id").
+				Where("invoices.status <> ? AND invoices.deleted_at IS NULL", InvoiceStatusCancelled)
+			if line.AppointmentID != nil {
+				var appointment Appointment
+				err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&appointment, *line.AppointmentID).Error
+				if err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return ErrInvalidReference
+					}
+					return err
+				}
+				if !appointment.IsBillable() {
+					return fmt.Errorf("%w: %s appointment cannot be invoiced", ErrAppointmentStatus, appointment.Status)
+				}
+				query = query.Where("invoice_lines.appointment_id = ?", *line.AppointmentID)
+			} else {
+				if err := tx.Exec("SELECT 1 FROM packages WHERE id = ? FOR UPDATE", *line.PackageID).Error; err != nil {
+					return err
+				}
+				query = query.Where("invoice_lines.package_id = ?", *line.PackageID)
+			}
+
+			var count int64
+			if err := query.Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrAlreadyInvoiced
+			}
+		}
+
+		invoice.Recalculate()
+		if invoice.IssuedAt.IsZero() {
+			invoice.IssuedAt = time.Now()
+		}
+		if codes.PromoCode != "" {
+			if err := applyPromoCode(tx, invoice, codes.PromoCode); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+
+		// Номер строится из id, поэтому назначается после вставки
+		invoice.Number = fmt.Sprintf("INV-%s-%06d", invoice.IssuedAt.Format("2006"), invoice.ID)
+		if err := tx.Model(invoice).Update("number", invoice.Number).Error; err != nil {
+			return err
+		}
+
+		if err := sellInvoiceProducts(tx, invoice); err != nil {
+			return err
+		}
+
+		if codes.GiftCertificateCode != "" {
+			return applyGiftCertificate(tx, invoice, codes.GiftCertificateCode)
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyInvoiced), errors.Is(err, ErrUnknownCode),
+			errors.Is(err, ErrPromoNotApplicable), errors.Is(err, ErrPromoExhausted),
+			errors.Is(err, ErrCertificateNotApplicable), errors.Is(err, ErrInsufficientStock),
+			errors.Is(err, ErrAppointmentStatus), errors.Is(err, ErrInvalidReference):
+			return err
+		}
+		return fmt.Errorf("failed to create invoice: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetInvoice(id uint) (*Invoice, error) {
+	var invoice Invoice
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("paid_at, id") }).
+		First(&invoice, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// invoiceInBranch - условие принадлежности счета филиалу: по филиалу строк с товаром или визитом,
+// а для счетов без таких строк (абонементы, сертификаты) - по домашнему филиалу клиента
+const invoiceInBranch = `(EXISTS (SELECT 1 FROM invoice_lines
+		LEFT JOIN appointments ON appointments.id = invoice_lines.appointment_id
+		WHERE invoice_lines.invoice_id = invoices.id AND COALESCE(invoice_lines.branch_id, appointments.branch_id) = ?)
+	OR (NOT EXISTS (SELECT 1 FROM invoice_lines
+			LEFT JOIN appointments ON appointments.id = invoice_lines.appointment_id
+			WHERE invoice_lines.invoice_id = invoices.id AND COALESCE(invoice_lines.branch_id, appointments.branch_id) IS NOT NULL)
+		AND EXISTS (SELECT 1 FROM clients WHERE clients.id = invoices.client_id AND clients.branch_id = ?)))`
+
+func (r *PostgresRepository) ListInvoices(filter InvoiceFilter) ([]Invoice, int64, error) {
+	query := r.db.Model(&Invoice{})
+
+	if filter.ClientID != 0 {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.PayerClientID != 0 {
+		query = query.Where("payer_client_id = ?", filter.PayerClientID)
+	}
+	if filter.PayerOrganizationID != 0 {
+		query = query.Where("payer_organization_id = ?", filter.PayerOrganizationID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where(invoiceInBranch, filter.BranchID, filter.BranchID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("issued_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("issued_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+	}
+
+	var invoices []Invoice
+	if err := query.Order("issued_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&invoices).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	return invoices, total, nil
+}
+
+func (r *PostgresRepository) CancelInvoice(id uint) (*Invoice, error) {
+	var invoice Invoice
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, id).Error; err != nil {
+			return err
+		}
+		if invoice.Status == InvoiceStatusCancelled {
+			return ErrInvoiceCancelled
+		}
+		if invoice.PaidTotal != 0 {
+			return ErrInvoiceHasPayments
+		}
+
+		invoice.Status = InvoiceStatusCancelled
+		if err := tx.Model(&invoice).Update("status", invoice.Status).Error; err != nil {
+			return err
+		}
+		if err := returnInvoiceStock(tx, invoice.ID); err != nil {
+			return err
+		}
+		return releasePromoCode(tx, &invoice)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, ErrInvoiceCancelled), errors.Is(err, ErrInvoiceHasPayments):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to cancel invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// AddPayment проводит оплату по счету. Счет блокируется на время транзакции,
+// чтобы параллельные оплаты не превысили сумму счета.
+func (r *PostgresRepository) AddPayment(invoiceID uint, payment *Payment) (*Invoice, error) {
+	var invoice Invoice
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoiceID).Error; err != nil {
+			return err
+		}
+		return applyPayment(tx, &invoice, payment)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, ErrInvoiceCancelled), errors.Is(err, ErrOverpayment):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to add payment: %w", err)
+	}
+	return &invoice, nil
+}
+
+func (r *PostgresRepository) AddRefund(invoiceID uint, refund *Payment) (*Invoice, []LoyaltyEntry, error) {
+	var (
+		invoice Invoice
+		entries []LoyaltyEntry
+	)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoiceID).Error; err != nil {
+			return err
+		}
+		var err error
+		entries, err = applyRefund(tx, &invoice, refund)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, nil, ErrNotFound
+		case errors.Is(err, ErrInvoiceCancelled), errors.Is(err, ErrRefundExceedsPaid),
+			errors.Is(err, ErrFractionalPoints), errors.Is(err, ErrInsufficientPoints):
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to add refund: %w", err)
+	}
+	return &invoice, entries, nil
+}
+
+// applyPayment проводит оплату по счету, заблокированному в транзакции tx
+func applyPayment(tx *gorm.DB, invoice *Invoice, payment *Payment) error {
+	if invoice.Status == InvoiceStatusCancelled {
+		return ErrInvoiceCancelled
+	}
+	if payment.Amount > invoice.Outstanding() {
+		return ErrOverpayment
+	}
+
+	payment.Kind = PaymentKindPayment
+	invoice.PaidTotal += payment.Amount
+	return recordPayment(tx, invoice, payment)
+}
+
+// applyRefund проводит возврат по счету, заблокированному в транзакции tx. Деньгами возвращается не больше,
+// чем оплачено деньгами, баллами и сертификатом - не больше оплаченного ими; баллы зачисляются обратно клиенту,
+// сумма или сессии - обратно на сертификат. Возврат денег отменяет такую же долю баллов, начисленных за счет.
+func applyRefund(tx *gorm.DB, invoice *Invoice, refund *Payment) ([]LoyaltyEntry, error) {
+	if invoice.Status == InvoiceStatusCancelled {
+		return nil, ErrInvoiceCancelled
+	}
+
+	paid, refunded, err := paidByMethod(tx, invoice.ID, refund.Method)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Amount > paid-refunded {
+		return nil, ErrRefundExceedsPaid
+	}
+
+	refund.Kind = PaymentKindRefund
+	invoice.PaidTotal -= refund.Amount
+	if err := recordPayment(tx, invoice, refund); err != nil {
+		return nil, err
+	}
+
+	var entry *LoyaltyEntry
+	switch {
+	case refund.Method == PaymentMethodLoyalty:
+		entry, err = returnLoyaltyPoints(tx, invoice, refund)
+	case refund.Method == PaymentMethodGiftCertificate:
+		err = creditGiftCertificate(tx, invoice, refund, refunded)
+	case !isNonCashMethod(refund.Method):
+		entry, err = clawBackLoyaltyPoints(tx, invoice, paid, refunded+refund.Amount, refund.PaidAt)
+	}
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return []LoyaltyEntry{*entry}, nil
+}
+
+// paidByMethod возвращает оплаты и возвраты по счету тем же способом, что method. Денежные способы
+// (наличные, карта, перевод) взаимозаменяемы; баллы и сертификат учитываются каждый отдельно.
+func paidByMethod(tx *gorm.DB, invoiceID uint, method string) (paid, refunded Money, err error) {
+	var totals []struct {
+		Method string
+		Kind   string
+		Amount Money
+	}
+	err = tx.Model(&Payment{}).
+		Select("method, kind, COALESCE(SUM(amount), 0) AS amount").
+		Where("invoice_id = ?", invoiceID).
+		Group("method, kind").
+		Scan(&totals).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, total := range totals {
+		if total.Method != method && (isNonCashMethod(method) || isNonCashMethod(total.Method)) {
+			continue
+		}
+		if total.Kind == PaymentKindRefund {
+			refunded += total.Amount
+		} else {
+			paid += total.Amount
+		}
+	}
+	return paid, refunded, nil
+}
+
+// recordPayment сохраняет оплату или возврат и новые оплаченную сумму и статус счета
+func recordPayment(tx *gorm.DB, invoice *Invoice, payment *Payment) error {
+	invoice.refreshStatus()
+
+	payment.InvoiceID = invoice.ID
+	payment.ClientID = invoice.ClientID
+	if payment.PaidAt.IsZero() {
+		payment.PaidAt = time.Now()
+	}
+	if err := tx.Create(payment).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(invoice).Updates(map[string]interface{}{
+		"paid_total": invoice.PaidTotal,
+		"status":     invoice.Status,
+	}).Error
+}
+
+func (r *PostgresRepository) GetClientBalance(clientID uint) (*ClientBalance, error) {
+	balance, err := r.invoiceBalance(r.db.Where(
+		"(client_id = ? AND payer_client_id IS NULL AND payer_organization_id IS NULL) OR payer_client_id = ?", clientID, clientID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client balance: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *PostgresRepository) GetOrganizationBalance(organizationID uint) (*ClientBalance, error) {
+	balance, err := r.invoiceBalance(r.db.Where("payer_organization_id = ?", organizationID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization balance: %w", err)
+	}
+	return balance, nil
+}
+
+// invoiceBalance суммирует неотмененные счета, отобранные условием payer
+func (r *PostgresRepository) invoiceBalance(payer *gorm.DB) (*ClientBalance, error) {
+	var balance ClientBalance
+	err := r.db.Model(&Invoice{}).
+		Select("COALESCE(SUM(total), 0) AS invoiced, COALESCE(SUM(paid_total), 0) AS paid").
+		Where(payer).
+		Where("status <> ?", InvoiceStatusCancelled).
+		Scan(&balance).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Outstanding = balance.Invoiced - balance.Paid
+	return &balance, nil
+}
+
+// GetCashReport группирует оплаты и возвраты за период [from, to) по способу оплаты; branchID 0 - по всему центру.
+// Оплаты баллами и сертификатами в кассу не поступают и в отчет не входят.
+func (r *PostgresRepository) GetCashReport(from, to time.Time, branchID uint) ([]CashReportRow, error) {
+	query := r.db.Model(&Payment{}).
+		Select("method, kind, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("paid_at >= ? AND paid_at < ?", from, to).
+		Where("method NOT IN ?", nonCashPaymentMethods)
+	if branchID != 0 {
+		query = query.Where("invoice_id IN (SELECT id FROM invoices WHERE "+invoiceInBranch+")", branchID, branchID)
+	}
+
+	var rows []CashReportRow
+	err := query.Group("method, kind").
+		Order("method, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to build cash report: %w", err)
+	}
+	return rows, nil
+}
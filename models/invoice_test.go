@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		ok   bool
+	}{
+		{"1500", 150000, true},
+		{"1500.5", 150050, true},
+		{"0.07", 7, true},
+		{"-12.34", -1234, true},
+		{"1.234", 0, false},
+		{"abc", 0, false},
+		{".5", 0, false},
+		{"--5", 0, false},
+		{"-+5", 0, false},
+		{"+5", 0, false},
+		{"1.-5", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMoneyScanAndJSON(t *testing.T) {
+	var m Money
+	if err := m.Scan([]byte("1250.500")); err != nil || m != 125050 {
+		t.Errorf("Scan(numeric) = %v, %v; want 125050", m, err)
+	}
+
+	data, _ := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Money(-5)})
+	if string(data) != `{"amount":-0.05}` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
+
+func TestInvoiceRecalculate(t *testing.T) {
+	invoice := Invoice{
+		Discount: 10000,
+		Lines: []InvoiceLine{
+			{Quantity: 2, UnitPrice: 250000},
+			{Quantity: 1, UnitPrice: 99999, DiscountPercent: 15},
+		},
+	}
+	invoice.Recalculate()
+
+	// 2 * 2500.00 + (999.99 - 15%) = 5000.00 + 849.99
+	if invoice.Lines[1].Total != 84999 {
+		t.Errorf("line total = %v, want 849.99", invoice.Lines[1].Total)
+	}
+	if invoice.Subtotal != 584999 || invoice.Total != 574999 {
+		t.Errorf("subtotal/total = %v/%v, want 5849.99/5749.99", invoice.Subtotal, invoice.Total)
+	}
+	if invoice.Status != InvoiceStatusIssued {
+		t.Errorf("status = %q, want issued", invoice.Status)
+	}
+
+	invoice.PaidTotal = 100000
+	invoice.refreshStatus()
+	if invoice.Status != InvoiceStatusPartiallyPaid || invoice.Outstanding() != 474999 {
+		t.Errorf("after partial payment: status %q, outstanding %v", invoice.Status, invoice.Outstanding())
+	}
+}
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды записей журнала баллов. Начисления, возвраты баллов при возврате оплаты и положительные
+// корректировки увеличивают баланс; списания в оплату счета, отмены начислений при возврате денег,
+// сгорания и отрицательные корректировки уменьшают его.
+const (
+	LoyaltyEntryAccrual    = "accrual"
+	LoyaltyEntryRedemption = "redemption"
+	LoyaltyEntryRefund     = "refund"
+	LoyaltyEntryReversal   = "reversal"
+	LoyaltyEntryExpiry     = "expiry"
+	LoyaltyEntryAdjustment = "adjustment"
+)
+
+// К каким строкам счета применяется правило начисления
+const (
+	LoyaltyAppliesToAll          = "all"
+	LoyaltyAppliesToAppointments = "appointments"
+	LoyaltyAppliesToPackages     = "packages"
+	LoyaltyAppliesToProducts     = "products"
+)
+
+// LoyaltyPointValue - стоимость одного балла при оплате счета
+const LoyaltyPointValue Money = 100
+
+// LoyaltyRule - правило начисления баллов за оплаченный счет: Percent процентов от оплаченной деньгами
+// суммы подходящих строк, если итог счета не меньше MinInvoiceTotal. Баллы сгорают через ExpiryDays
+// дней, 0 - бессрочно. Если строке подходит несколько правил, применяется правило с большим процентом.
+type LoyaltyRule struct {
+	ID              uint   `gorm:"primarykey"`
+	Name            string `gorm:"not null"`
+	AppliesTo       string `gorm:"not null;default:all"`
+	Percent         int    `gorm:"not null"`
+	MinInvoiceTotal Money  `gorm:"type:numeric(12,2);not null;default:0"`
+	ExpiryDays      int    `gorm:"not null;default:0"`
+	Active          bool   `gorm:"not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// LoyaltyEntry - неизменяемая запись журнала баллов клиента; Points со знаком.
+// Начисление ссылается на счет и правило, списание, возврат и отмена начисления - на счет, сгорание - на начисление
+// (SourceEntryID), остаток которого сгорел. Баланс клиента - сумма Points его записей.
+type LoyaltyEntry struct {
+	ID            uint          `gorm:"primarykey"`
+	ClientID      uint          `gorm:"not null;index"`
+	Client        *Client       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Kind          string        `gorm:"not null"`
+	Points        int64         `gorm:"not null"`
+	InvoiceID     *uint         `gorm:"index;uniqueIndex:idx_loyalty_accrual,where:kind = 'accrual'"`
+	Invoice       *Invoice      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	RuleID        *uint         `gorm:"uniqueIndex:idx_loyalty_accrual,where:kind = 'accrual'"`
+	Rule          *LoyaltyRule  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SourceEntryID *uint         `gorm:"uniqueIndex"`
+	SourceEntry   *LoyaltyEntry `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ExpiresAt     *time.Time    `gorm:"index"`
+	Comment       string
+	CreatedAt     time.Time `gorm:"not null;index"`
+}
+
+// LoyaltyLot - непотраченный остаток начисления или положительной корректировки
+type LoyaltyLot struct {
+	EntryID   uint
+	Remaining int64
+	ExpiresAt *time.Time
+}
+
+// LoyaltyBalance - доступные клиенту баллы и их остатки по срокам сгорания
+type LoyaltyBalance struct {
+	Points int64
+	Lots   []LoyaltyLot
+}
+
+// Points переводит сумму в баллы по проценту правила, отбрасывая неполный балл
+func (r *LoyaltyRule) Points(amount Money) int64 {
+	return int64(amount.Percent(r.Percent) / LoyaltyPointValue)
+}
+
+func (r *LoyaltyRule) applies(line *InvoiceLine) bool {
+	switch r.AppliesTo {
+	case LoyaltyAppliesToAppointments:
+		return line.AppointmentID != nil
+	case LoyaltyAppliesToPackages:
+		return line.PackageID != nil
+	case LoyaltyAppliesToProducts:
+		return line.ProductID != nil
+	default:
+		return true
+	}
+}
+
+// LoyaltyAccruals рассчитывает начисления за оплаченный счет: по одной записи на примененное правило.
+// Скидка на счет и оплата баллами или сертификатом (redeemed) уменьшают сумму строк пропорционально.
+func LoyaltyAccruals(invoice *Invoice, rules []LoyaltyRule, redeemed Money, at time.Time) []LoyaltyEntry {
+	if invoice.Status != InvoiceStatusPaid || invoice.Subtotal <= 0 || redeemed >= invoice.Total {
+		return nil
+	}
+
+	amounts := map[int]Money{}
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		best := -1
+		for j := range rules {
+			rule := &rules[j]
+			if !rule.Active || invoice.Total < rule.MinInvoiceTotal || !rule.applies(line) {
+				continue
+			}
+			if best < 0 || rule.Percent > rules[best].Percent {
+				best = j
+			}
+		}
+		if best >= 0 {
+			amounts[best] += Money(int64(line.Total) * int64(invoice.Total-redeemed) / int64(invoice.Subtotal))
+		}
+	}
+
+	var entries []LoyaltyEntry
+	for j := range rules {
+		points := rules[j].Points(amounts[j])
+		if points <= 0 {
+			continue
+		}
+		entry := LoyaltyEntry{
+			ClientID:  invoice.ClientID,
+			Kind:      LoyaltyEntryAccrual,
+			Points:    points,
+			InvoiceID: &invoice.ID,
+			RuleID:    &rules[j].ID,
+			CreatedAt: at,
+		}
+		if rules[j].ExpiryDays > 0 {
+			expiresAt := at.AddDate(0, 0, rules[j].ExpiryDays)
+			entry.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// LoyaltyReversal возвращает, сколько баллов из начисленных за счет accrued нужно отменить, когда из оплаченных
+// деньгами paid возвращено refunded, а reversed баллов уже отменено. Отменяется доля начисления,
+// равная доле возврата, с округлением вверх, чтобы после полного возврата не осталось ни балла.
+func LoyaltyReversal(accrued, reversed int64, paid, refunded Money) int64 {
+	if accrued <= 0 || paid <= 0 {
+		return 0
+	}
+	refunded = min(refunded, paid)
+	target := (accrued*int64(refunded) + int64(paid) - 1) / int64(paid)
+	return max(target-reversed, 0)
+}
+
+// LoyaltyLots восстанавливает остатки начислений по журналу, упорядоченному по времени.
+// Списания расходуют сначала баллы, которые сгорят раньше, и не трогают уже сгоревшие;
+// сгорание уменьшает остаток своего начисления.
+func LoyaltyLots(entries []LoyaltyEntry) []LoyaltyLot {
+	var lots []LoyaltyLot
+	index := map[uint]int{}
+	for _, entry := range entries {
+		switch {
+		case entry.Points > 0:
+			index[entry.ID] = len(lots)
+			lots = append(lots, LoyaltyLot{EntryID: entry.ID, Remaining: entry.Points, ExpiresAt: entry.ExpiresAt})
+		case entry.Kind == LoyaltyEntryExpiry && entry.SourceEntryID != nil:
+			if i, ok := index[*entry.SourceEntryID]; ok {
+				lots[i].Remaining += entry.Points
+			}
+		default:
+			consumeLoyaltyLots(lots, -entry.Points, entry.CreatedAt)
+		}
+	}
+	return lots
+}
+
+func consumeLoyaltyLots(lots []LoyaltyLot, points int64, at time.Time) {
+	order := make([]int, 0, len(lots))
+	for i := range lots {
+		if lots[i].Remaining > 0 && !lots[i].expired(at) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		x, y := lots[order[a]].ExpiresAt, lots[order[b]].ExpiresAt
+		return x != nil && (y == nil || x.Before(*y))
+	})
+
+	for _, i := range order {
+		if points <= 0 {
+			return
+		}
+		spent := min(points, lots[i].Remaining)
+		lots[i].Remaining -= spent
+		points -= spent
+	}
+}
+
+func (l LoyaltyLot) expired(at time.Time) bool {
+	return l.ExpiresAt != nil && !at.Before(*l.ExpiresAt)
+}
+
+// NewLoyaltyBalance оставляет остатки, не сгоревшие к моменту now
+func NewLoyaltyBalance(lots []LoyaltyLot, now time.Time) *LoyaltyBalance {
+	balance := &LoyaltyBalance{Lots: []LoyaltyLot{}}
+	for _, lot := range lots {
+		if lot.Remaining > 0 && !lot.expired(now) {
+			balance.Points += lot.Remaining
+			balance.Lots = append(balance.Lots, lot)
+		}
+	}
+	return balance
+}
+
+// protectLoyaltyLedger запрещает изменять и удалять записи журнала баллов на уровне БД.
+// Разрешена только смена клиента при слиянии дубликатов.
+func protectLoyaltyLedger(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION loyalty_entries_immutable() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' OR to_jsonb(NEW) - 'client_id' IS DISTINCT FROM to_jsonb(OLD) - 'client_id' THEN
+				RAISE EXCEPTION 'loyalty ledger entries are immutable';
+			END IF;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS loyalty_entries_immutable ON loyalty_entries",
+		`CREATE TRIGGER loyalty_entries_immutable BEFORE UPDATE OR DELETE ON loyalty_entries
+			FOR EACH ROW EXECUTE FUNCTION loyalty_entries_immutable()`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientPoints возвращается, если у клиента не хватает баллов для списания
+var ErrInsufficientPoints = errors.New("not enough loyalty points")
+
+// ErrFractionalPoints возвращается, если сумма возврата баллами не равна целому числу баллов
+var ErrFractionalPoints = errors.New("amount is not a whole number of loyalty points")
+
+type LoyaltyRepository interface {
+	CreateLoyaltyRule(rule *LoyaltyRule) error
+	GetLoyaltyRule(id uint) (*LoyaltyRule, error)
+	ListLoyaltyRules() ([]LoyaltyRule, error)
+	UpdateLoyaltyRule(rule *LoyaltyRule) error
+	// AccrueLoyaltyPoints начисляет баллы за оплаченный счет по активным правилам и возвращает
+	// созданные записи. За один счет баллы начисляются только один раз.
+	AccrueLoyaltyPoints(invoiceID uint, at time.Time) ([]LoyaltyEntry, error)
+	// RedeemLoyaltyPoints оплачивает счет баллами его клиента
+	RedeemLoyaltyPoints(invoiceID uint, points int64, at time.Time) (*LoyaltyEntry, *Invoice, error)
+	// AdjustLoyaltyPoints записывает ручную корректировку; списать больше доступного нельзя
+	AdjustLoyaltyPoints(entry *LoyaltyEntry) error
+	// ExpireLoyaltyPoints записывает сгорание остатков, срок которых истек к now.
+	// clientID 0 - по всем клиентам.
+	ExpireLoyaltyPoints(clientID uint, now time.Time) ([]LoyaltyEntry, error)
+	GetLoyaltyBalance(clientID uint, now time.Time) (*LoyaltyBalance, error)
+	ListLoyaltyEntries(clientID uint, limit, offset int) ([]LoyaltyEntry, int64, error)
+}
+
+func (r *PostgresRepository) CreateLoyaltyRule(rule *LoyaltyRule) error {
+	if err := r.db.Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create loyalty rule: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetLoyaltyRule(id uint) (*LoyaltyRule, error) {
+	var rule LoyaltyRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get loyalty rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (r *PostgresRepository) ListLoyaltyRules() ([]LoyaltyRule, error) {
+	var rules []LoyaltyRule
+	if err := r.db.Order("active DESC, name, id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list loyalty rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *PostgresRepository) UpdateLoyaltyRule(rule *LoyaltyRule) error {
+	result := r.db.Save(rule)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update loyalty rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) AccrueLoyaltyPoints(invoiceID uint, at time.Time) ([]LoyaltyEntry, error) {
+	var entries []LoyaltyEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var invoice Invoice
+		if err := tx.Preload("Lines").First(&invoice, invoiceID).Error; err != nil {
+			return err
+		}
+		if err := lockLoyalty(tx, invoice.ClientID); err != nil {
+			return err
+		}
+
+		var accrued int64
+		err := tx.Model(&LoyaltyEntry{}).
+			Where("invoice_id = ? AND kind = ?", invoiceID, LoyaltyEntryAccrual).
+			Count(&accrued).Error
+		if err != nil || accrued > 0 {
+			return err
+		}
+
+		var redeemed Money
+		err = tx.Model(&Payment{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("invoice_id = ? AND kind = ? AND method IN ?", invoiceID, PaymentKindPayment, nonCashPaymentMethods).
+			Scan(&redeemed).Error
+		if err != nil {
+			return err
+		}
+
+		var rules []LoyaltyRule
+		if err := tx.Where("active").Order("id").Find(&rules).Error; err != nil {
+			return err
+		}
+
+		entries = LoyaltyAccruals(&invoice, rules, redeemed, at)
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(&entries).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to accrue loyalty points: %w", translateError(err))
+	}
+	return entries, nil
+}
+
+func (r *PostgresRepository) RedeemLoyaltyPoints(invoiceID uint, points int64, at time.Time) (*LoyaltyEntry, *Invoice, error) {
+	var invoice Invoice
+	entry := &LoyaltyEntry{Kind: LoyaltyEntryRedemption, Points: -points, InvoiceID: &invoiceID, CreatedAt: at}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoiceID).Error; err != nil {
+			return err
+		}
+		if err := lockLoyalty(tx, invoice.ClientID); err != nil {
+			return err
+		}
+
+		balance, err := loyaltyBalance(tx, invoice.ClientID, at)
+		if err != nil {
+			return err
+		}
+		if balance.Points < points {
+			return ErrInsufficientPoints
+		}
+
+		payment := &Payment{
+			Kind:   PaymentKindPayment,
+			Method: PaymentMethodLoyalty,
+			Amount: Money(points) * LoyaltyPointValue,
+			PaidAt: at,
+		}
+		if err := applyPayment(tx, &invoice, payment); err != nil {
+			return err
+		}
+
+		entry.ClientID = invoice.ClientID
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, nil, ErrNotFound
+		case errors.Is(err, ErrInsufficientPoints), errors.Is(err, ErrInvoiceCancelled), errors.Is(err, ErrOverpayment):
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to redeem loyalty points: %w", err)
+	}
+	return entry, &invoice, nil
+}
+
+func (r *PostgresRepository) AdjustLoyaltyPoints(entry *LoyaltyEntry) error {
+	entry.Kind = LoyaltyEntryAdjustment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockLoyalty(tx, entry.ClientID); err != nil {
+			return err
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+
+		if entry.Points < 0 {
+			balance, err := loyaltyBalance(tx, entry.ClientID, entry.CreatedAt)
+			if err != nil {
+				return err
+			}
+			if balance.Points < -entry.Points {
+				return ErrInsufficientPoints
+			}
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientPoints) {
+			return err
+		}
+		return fmt.Errorf("failed to adjust loyalty points: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ExpireLoyaltyPoints(clientID uint, now time.Time) ([]LoyaltyEntry, error) {
+	query := r.db.Model(&LoyaltyEntry{}).
+		Distinct("client_id").
+		Where("points > 0 AND expires_at <= ?", now).
+		Where("NOT EXISTS (SELECT 1 FROM loyalty_entries x WHERE x.source_entry_id = loyalty_entries.id)")
+	if clientID != 0 {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var clientIDs []uint
+	if err := query.Pluck("client_id", &clientIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find expiring loyalty points: %w", err)
+	}
+
+	var expired []LoyaltyEntry
+	for _, id := range clientIDs {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := lockLoyalty(tx, id); err != nil {
+				return err
+			}
+			lots, err := loyaltyLots(tx, id)
+			if err != nil {
+				return err
+			}
+
+			var entries []LoyaltyEntry
+			for _, lot := range lots {
+				if lot.Remaining > 0 && lot.expired(now) {
+					sourceID := lot.EntryID
+					entries = append(entries, LoyaltyEntry{
+						ClientID:      id,
+						Kind:          LoyaltyEntryExpiry,
+						Points:        -lot.Remaining,
+						SourceEntryID: &sourceID,
+						CreatedAt:     now,
+					})
+				}
+			}
+			if len(entries) == 0 {
+				return nil
+			}
+			if err := tx.Create(&entries).Error; err != nil {
+				return err
+			}
+			expired = append(expired, entries...)
+			return nil
+		})
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire loyalty points of client %d: %w", id, err)
+		}
+	}
+	return expired, nil
+}
+
+func (r *PostgresRepository) GetLoyaltyBalance(clientID uint, now time.Time) (*LoyaltyBalance, error) {
+	balance, err := loyaltyBalance(r.db, clientID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loyalty balance: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *PostgresRepository) ListLoyaltyEntries(clientID uint, limit, offset int) ([]LoyaltyEntry, int64, error) {
+	query := r.db.Model(&LoyaltyEntry{}).Where("client_id = ?", clientID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count loyalty entries: %w", err)
+	}
+
+	var entries []LoyaltyEntry
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list loyalty entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// returnLoyaltyPoints зачисляет клиенту баллы, возвращенные из оплаты счета invoice
+func returnLoyaltyPoints(tx *gorm.DB, invoice *Invoice, refund *Payment) (*LoyaltyEntry, error) {
+	if refund.Amount%LoyaltyPointValue != 0 {
+		return nil, ErrFractionalPoints
+	}
+	if err := lockLoyalty(tx, invoice.ClientID); err != nil {
+		return nil, err
+	}
+
+	entry := &LoyaltyEntry{
+		ClientID:  invoice.ClientID,
+		Kind:      LoyaltyEntryRefund,
+		Points:    int64(refund.Amount / LoyaltyPointValue),
+		InvoiceID: &invoice.ID,
+		CreatedAt: refund.PaidAt,
+	}
+	return entry, tx.Create(entry).Error
+}
+
+// clawBackLoyaltyPoints отменяет долю баллов, начисленных за счет invoice, после возврата денег: из оплаченных
+// деньгами paid возвращено refunded. Если клиент уже потратил эти баллы, возврат отклоняется.
+func clawBackLoyaltyPoints(tx *gorm.DB, invoice *Invoice, paid, refunded Money, at time.Time) (*LoyaltyEntry, error) {
+	if err := lockLoyalty(tx, invoice.ClientID); err != nil {
+		return nil, err
+	}
+
+	var totals struct {
+		Accrued  int64
+		Reversed int64
+	}
+	err := tx.Model(&LoyaltyEntry{}).
+		Select("COALESCE(SUM(points) FILTER (WHERE kind = ?), 0) AS accrued, COALESCE(-SUM(points) FILTER (WHERE kind = ?), 0) AS reversed",
+			LoyaltyEntryAccrual, LoyaltyEntryReversal).
+		Where("invoice_id = ?", invoice.ID).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := LoyaltyReversal(totals.Accrued, totals.Reversed, paid, refunded)
+	if points == 0 {
+		return nil, nil
+	}
+	balance, err := loyaltyBalance(tx, invoice.ClientID, at)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Points < points {
+		return nil, ErrInsufficientPoints
+	}
+
+	entry := &LoyaltyEntry{
+		ClientID:  invoice.ClientID,
+		Kind:      LoyaltyEntryReversal,
+		Points:    -points,
+		InvoiceID: &invoice.ID,
+		CreatedAt: at,
+	}
+	return entry, tx.Create(entry).Error
+}
+
+// lockLoyalty сериализует изменения журнала баллов клиента до конца транзакции
+func lockLoyalty(tx *gorm.DB, clientID uint) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("loyalty:%d", clientID)).Error
+}
+
+func loyaltyLots(db *gorm.DB, clientID uint) ([]LoyaltyLot, error) {
+	var entries []LoyaltyEntry
+	if err := db.Where("client_id = ?", clientID).Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return LoyaltyLots(entries), nil
+}
+
+func loyaltyBalance(db *gorm.DB, clientID uint, now time.Time) (*LoyaltyBalance, error) {
+	lots, err := loyaltyLots(db, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoyaltyBalance(lots, now), nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoyaltyAccruals(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	id := func(v uint) *uint { return &v }
+	invoice := &Invoice{
+		ClientID: 7,
+		Status:   InvoiceStatusPaid,
+		Lines: []InvoiceLine{
+			{Total: 300000, AppointmentID: id(1)},
+			{Total: 700000, PackageID: id(2)},
+		},
+		Discount: 100000,
+	}
+	invoice.ID = 5
+	invoice.Subtotal = 1000000
+	invoice.Total = 900000
+
+	rules := []LoyaltyRule{
+		{ID: 1, AppliesTo: LoyaltyAppliesToAll, Percent: 5, Active: true},
+		{ID: 2, AppliesTo: LoyaltyAppliesToPackages, Percent: 10, ExpiryDays: 30, Active: true},
+		{ID: 3, AppliesTo: LoyaltyAppliesToAll, Percent: 50, Active: false},
+		{ID: 4, AppliesTo: LoyaltyAppliesToAll, Percent: 20, MinInvoiceTotal: 2000000, Active: true},
+	}
+
+	entries := LoyaltyAccruals(invoice, rules, 0, at)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	// Визит: 3000 * 0.9 = 2700, 5% = 135 баллов; абонемент: 7000 * 0.9 = 6300, 10% = 630 баллов
+	if *entries[0].RuleID != 1 || entries[0].Points != 135 || entries[0].ExpiresAt != nil {
+		t.Errorf("appointment accrual = %+v", entries[0])
+	}
+	if *entries[1].RuleID != 2 || entries[1].Points != 630 || !entries[1].ExpiresAt.Equal(at.AddDate(0, 0, 30)) {
+		t.Errorf("package accrual = %+v", entries[1])
+	}
+
+	// Оплаченная баллами часть счета баллы не приносит
+	entries = LoyaltyAccruals(invoice, rules, 450000, at)
+	if entries[0].Points != 67 || entries[1].Points != 315 {
+		t.Errorf("accruals with redemption = %d, %d, want 67, 315", entries[0].Points, entries[1].Points)
+	}
+
+	invoice.Status = InvoiceStatusPartiallyPaid
+	if entries := LoyaltyAccruals(invoice, rules, 0, at); entries != nil {
+		t.Errorf("unpaid invoice accrued %+v", entries)
+	}
+}
+
+func TestLoyaltyLots(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	ptr := func(v time.Time) *time.Time { return &v }
+	id := func(v uint) *uint { return &v }
+
+	entries := []LoyaltyEntry{
+		{ID: 1, Kind: LoyaltyEntryAdjustment, Points: 100, CreatedAt: day(1)},
+		{ID: 2, Kind: LoyaltyEntryAccrual, Points: 50, ExpiresAt: ptr(day(20)), CreatedAt: day(2)},
+		{ID: 3, Kind: LoyaltyEntryAccrual, Points: 40, ExpiresAt: ptr(day(10)), CreatedAt: day(3)},
+		// Списание сначала расходует баллы, сгорающие раньше
+		{ID: 4, Kind: LoyaltyEntryRedemption, Points: -60, CreatedAt: day(5)},
+		// Начисление 3 уже сгорело и не расходуется
+		{ID: 5, Kind: LoyaltyEntryAdjustment, Points: -10, CreatedAt: day(15)},
+	}
+
+	lots := LoyaltyLots(entries)
+	want := map[uint]int64{1: 100, 2: 20, 3: 0}
+	for _, lot := range lots {
+		if lot.Remaining != want[lot.EntryID] {
+			t.Errorf("lot %d remaining = %d, want %d", lot.EntryID, lot.Remaining, want[lot.EntryID])
+		}
+	}
+
+	balance := NewLoyaltyBalance(lots, day(25))
+	if balance.Points != 100 || len(balance.Lots) != 1 {
+		t.Errorf("balance after expiry = %+v, want 100 points in one lot", balance)
+	}
+
+	entries = append(entries, LoyaltyEntry{ID: 6, Kind: LoyaltyEntryExpiry, Points: -20, SourceEntryID: id(2), CreatedAt: day(21)})
+	for _, lot := range LoyaltyLots(entries) {
+		if lot.EntryID == 2 && lot.Remaining != 0 {
+			t.Errorf("expired lot remaining = %d, want 0", lot.Remaining)
+		}
+	}
+}
+
+func TestLoyaltyReversal(t *testing.T) {
+	tests := []struct {
+		name     string
+		accrued  int64
+		reversed int64
+		paid     Money
+		refunded Money
+		want     int64
+	}{
+		{"nothing accrued", 0, 0, 100000, 100000, 0},
+		{"full refund", 45, 0, 900000, 900000, 45},
+		{"partial refund rounds up", 45, 0, 900000, 100000, 5},
+		{"third of refund", 10, 0, 300000, 100000, 4},
+		{"second refund", 10, 4, 300000, 200000, 3},
+		{"refund over paid", 10, 7, 300000, 400000, 3},
+		{"already reversed", 10, 10, 300000, 300000, 0},
+	}
+	for _, tt := range tests {
+		if got := LoyaltyReversal(tt.accrued, tt.reversed, tt.paid, tt.refunded); got != tt.want {
+			t.Errorf("%s: LoyaltyReversal() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money - денежная сумма в копейках. В PostgreSQL хранится в колонке numeric(12,2),
+// в JSON передается десятичным числом с двумя знаками после точки, без потерь на float.
+type Money int64
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney разбирает сумму вида "1234", "1234.5" или "-1234.56"
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	// Знак допускается только один и только перед числом: ParseInt сам принял бы "-" и "+" в частях
+	if !isDigits(whole) || (hasFraction && (!isDigits(fraction) || len(fraction) > 2)) {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, value)
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	rubles, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, value)
+	}
+	kopecks, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil || kopecks < 0 {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, value)
+	}
+
+	amount := Money(rubles*100 + kopecks)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// isDigits сообщает, что строка непустая и состоит только из десятичных цифр
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// Percent возвращает долю суммы в процентах с округлением до копейки (половина - вверх)
+func (m Money) Percent(percent int) Money {
+	value := int64(m) * int64(percent)
+	if value < 0 {
+		return -Money((-value + 50) / 100)
+	}
+	return Money((value + 50) / 100)
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanString(value string) error {
+	// numeric может прийти с лишними нулями в дробной части, например "10.500"
+	if whole, fraction, ok := strings.Cut(value, "."); ok && len(fraction) > 2 {
+		if strings.Trim(fraction[2:], "0") != "" {
+			return fmt.Errorf("%w: %q has more than two decimal places", errInvalidMoney, value)
+		}
+		value = whole + "." + fraction[:2]
+	}
+
+	parsed, err := ParseMoney(value)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает как число, так и строку с числом
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	parsed, err := ParseMoney(value)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
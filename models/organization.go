@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды связей клиента
+const (
+	RelationshipGuardian         = "guardian"
+	RelationshipFamilyMember     = "family_member"
+	RelationshipCorporateSponsor = "corporate_sponsor"
+)
+
+// Organization - компания, оплачивающая услуги своих сотрудников
+type Organization struct {
+	gorm.Model
+	Name        string `gorm:"not null"`
+	TaxID       string `gorm:"index"`
+	ContactName string
+	Phone       string
+	Email       string
+	Address     string
+}
+
+// ClientRelationship - связь клиента ClientID. Для guardian RelatedClientID - опекун (родитель),
+// который записывает клиента и может оплачивать его счета; family_member - симметричная связь
+// родственников; для corporate_sponsor OrganizationID - работодатель, оплачивающий услуги.
+type ClientRelationship struct {
+	ID              uint          `gorm:"primarykey"`
+	ClientID        uint          `gorm:"not null;index;uniqueIndex:idx_client_relationship_client,where:related_client_id IS NOT NULL;uniqueIndex:idx_client_relationship_organization,where:organization_id IS NOT NULL"`
+	Client          *Client       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Kind            string        `gorm:"not null;uniqueIndex:idx_client_relationship_client,where:related_client_id IS NOT NULL;uniqueIndex:idx_client_relationship_organization,where:organization_id IS NOT NULL"`
+	RelatedClientID *uint         `gorm:"index;uniqueIndex:idx_client_relationship_client,where:related_client_id IS NOT NULL"`
+	RelatedClient   *Client       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	OrganizationID  *uint         `gorm:"index;uniqueIndex:idx_client_relationship_organization,where:organization_id IS NOT NULL"`
+	Organization    *Organization `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	CreatedAt       time.Time
+}
+
+// CanPayFor сообщает, дает ли связь право оплачивать счета клиента clientID:
+// опекун платит за подопечного, родственники - друг за друга, организация - за сотрудника
+func (r *ClientRelationship) CanPayFor(clientID uint, payerClientID, payerOrganizationID *uint) bool {
+	switch {
+	case payerOrganizationID != nil:
+		return r.Kind == RelationshipCorporateSponsor && r.ClientID == clientID &&
+			r.OrganizationID != nil && *r.OrganizationID == *payerOrganizationID
+	case payerClientID != nil && r.RelatedClientID != nil:
+		if r.Kind == RelationshipGuardian {
+			return r.ClientID == clientID && *r.RelatedClientID == *payerClientID
+		}
+		if r.Kind == RelationshipFamilyMember {
+			return (r.ClientID == clientID && *r.RelatedClientID == *payerClientID) ||
+				(r.ClientID == *payerClientID && *r.RelatedClientID == clientID)
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(organization *Organization) error
+	GetOrganization(id uint) (*Organization, error)
+	ListOrganizations(limit, offset int) ([]Organization, int64, error)
+	UpdateOrganization(organization *Organization) error
+	// ListOrganizationClients возвращает сотрудников, за которых платит организация
+	ListOrganizationClients(organizationID uint) ([]Client, error)
+	CreateRelationship(relationship *ClientRelationship) error
+	// ListRelationships возвращает связи, в которых клиент участвует с любой стороны
+	ListRelationships(clientID uint) ([]ClientRelationship, error)
+	DeleteRelationship(clientID, relationshipID uint) error
+	// ListDependents возвращает клиентов, опекуном которых указан guardianID
+	ListDependents(guardianID uint) ([]Client, error)
+	// CanBill проверяет, может ли плательщик (клиент или организация) оплачивать счета клиента
+	CanBill(clientID uint, payerClientID, payerOrganizationID *uint) (bool, error)
+}
+
+func (r *PostgresRepository) CreateOrganization(organization *Organization) error {
+	if err := r.db.Create(organization).Error; err != nil {
+		return fmt.Errorf("failed to create organization: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetOrganization(id uint) (*Organization, error) {
+	var organization Organization
+	if err := r.db.First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &organization, nil
+}
+
+func (r *PostgresRepository) ListOrganizations(limit, offset int) ([]Organization, int64, error) {
+	query := r.db.Model(&Organization{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count organizations: %w", err)
+	}
+
+	var organizations []Organization
+	if err := query.Order("name, id").Limit(limit).Offset(offset).Find(&organizations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return organizations, total, nil
+}
+
+func (r *PostgresRepository) UpdateOrganization(organization *Organization) error {
+	result := r.db.Save(organization)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update organization: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListOrganizationClients(organizationID uint) ([]Client, error) {
+	var clients []Client
+	err := r.db.Preload("Tags", orderTags).
+		Where("id IN (?)", r.db.Model(&ClientRelationship{}).
+			Select("client_id").
+			Where("kind = ? AND organization_id = ?", RelationshipCorporateSponsor, organizationID)).
+		Order("full_name, id").
+		Find(&clients).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization clients: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *PostgresRepository) CreateRelationship(relationship *ClientRelationship) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Родственная связь симметрична: обратная запись считается той же связью
+		if relationship.Kind == RelationshipFamilyMember {
+			var count int64
+			err := tx.Model(&ClientRelationship{}).
+				Where("kind = ? AND client_id = ? AND related_client_id = ?", RelationshipFamilyMember, *relationship.RelatedClientID, relationship.ClientID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrDuplicate
+			}
+		}
+		if err := tx.Create(relationship).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Client").Preload("RelatedClient").Preload("Organization").First(relationship, relationship.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return err
+		}
+		return fmt.Errorf("failed to create relationship: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListRelationships(clientID uint) ([]ClientRelationship, error) {
+	var relationships []ClientRelationship
+	err := r.db.Preload("Client").Preload("RelatedClient").Preload("Organization").
+		Where("client_id = ? OR related_client_id = ?", clientID, clientID).
+		Order("kind, id").
+		Find(&relationships).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+func (r *PostgresRepository) DeleteRelationship(clientID, relationshipID uint) error {
+	result := r.db.Where("client_id = ? OR related_client_id = ?", clientID, clientID).Delete(&ClientRelationship{}, relationshipID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete relationship: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListDependents(guardianID uint) ([]Client, error) {
+	var clients []Client
+	err := r.db.Preload("Tags", orderTags).
+		Where("id IN (?)", r.db.Model(&ClientRelationship{}).
+			Select("client_id").
+			Where("kind = ? AND related_client_id = ?", RelationshipGuardian, guardianID)).
+		Order("full_name, id").
+		Find(&clients).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dependents: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *PostgresRepository) CanBill(clientID uint, payerClientID, payerOrganizationID *uint) (bool, error) {
+	var relationships []ClientRelationship
+	if err := r.db.Where("client_id = ? OR related_client_id = ?", clientID, clientID).Find(&relationships).Error; err != nil {
+		return false, fmt.Errorf("failed to check payer: %w", err)
+	}
+	for i := range relationships {
+		if relationships[i].CanPayFor(clientID, payerClientID, payerOrganizationID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestClientRelationshipCanPayFor(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	guardian := ClientRelationship{ClientID: 1, Kind: RelationshipGuardian, RelatedClientID: id(2)}
+	family := ClientRelationship{ClientID: 3, Kind: RelationshipFamilyMember, RelatedClientID: id(4)}
+	sponsor := ClientRelationship{ClientID: 5, Kind: RelationshipCorporateSponsor, OrganizationID: id(7)}
+
+	tests := []struct {
+		name         string
+		relationship ClientRelationship
+		clientID     uint
+		payerClient  *uint
+		payerOrg     *uint
+		want         bool
+	}{
+		{"guardian pays for dependent", guardian, 1, id(2), nil, true},
+		{"dependent does not pay for guardian", guardian, 2, id(1), nil, false},
+		{"family member pays", family, 3, id(4), nil, true},
+		{"family link is symmetric", family, 4, id(3), nil, true},
+		{"sponsor pays for employee", sponsor, 5, nil, id(7), true},
+		{"other organization", sponsor, 5, nil, id(8), false},
+		{"client link is not an organization", guardian, 1, nil, id(2), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.relationship.CanPayFor(tt.clientID, tt.payerClient, tt.payerOrg); got != tt.want {
+			t.Errorf("%s: CanPayFor() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды абонементов: пакет из фиксированного числа сессий или членство на период
+const (
+	PackageKindSessions   = "sessions"
+	PackageKindMembership = "membership"
+)
+
+// Статусы абонемента. Exhausted и expired не хранятся, а вычисляются в EffectiveStatus.
+const (
+	PackageStatusActive    = "active"
+	PackageStatusFrozen    = "frozen"
+	PackageStatusCancelled = "cancelled"
+	PackageStatusExhausted = "exhausted"
+	PackageStatusExpired   = "expired"
+)
+
+// Package - абонемент клиента. Для членства без лимита TotalSessions и RemainingSessions пустые.
+// Service ограничивает абонемент специализацией специалиста (например, "massage"); пустое значение - любая.
+type Package struct {
+	gorm.Model
+	ClientID          uint    `gorm:"not null;index"`
+	Client            *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Kind              string  `gorm:"not null"`
+	Title             string  `gorm:"not null"`
+	Service           string
+	TotalSessions     *int
+	RemainingSessions *int
+	ValidFrom         time.Time `gorm:"type:date;not null"`
+	ValidUntil        time.Time `gorm:"type:date;not null"`
+	Status            string    `gorm:"not null;default:active"`
+	FrozenAt          *time.Time
+	FrozenDays        int `gorm:"not null;default:0"`
+	Debits            []PackageDebit
+}
+
+// PackageDebit - списание сессии за завершенный визит или посещение группового занятия.
+// Заполнено ровно одно из AppointmentID и ClassEnrollmentID; уникальность по ним
+// защищает от повторного списания за одно и то же посещение.
+type PackageDebit struct {
+	ID                uint             `gorm:"primarykey"`
+	PackageID         uint             `gorm:"not null;index"`
+	Package           *Package         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	AppointmentID     *uint            `gorm:"uniqueIndex"`
+	Appointment       *Appointment     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ClassEnrollmentID *uint            `gorm:"uniqueIndex"`
+	ClassEnrollment   *ClassEnrollment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Sessions          int              `gorm:"not null"`
+	CreatedAt         time.Time
+}
+
+// EffectiveStatus возвращает статус абонемента с учетом срока действия и остатка на момент now
+func (p *Package) EffectiveStatus(now time.Time) string {
+	if p.Status != PackageStatusActive {
+		return p.Status
+	}
+	if now.Format(dateLayout) > p.ValidUntil.Format(dateLayout) {
+		return PackageStatusExpired
+	}
+	if p.RemainingSessions != nil && *p.RemainingSessions <= 0 {
+		return PackageStatusExhausted
+	}
+	return PackageStatusActive
+}
+
+// CanCover сообщает, можно ли оплатить абонементом визит к специалисту указанной специализации в момент at
+func (p *Package) CanCover(specialization string, at time.Time) bool {
+	if p.EffectiveStatus(at) != PackageStatusActive {
+		return false
+	}
+	if at.Format(dateLayout) < p.ValidFrom.Format(dateLayout) {
+		return false
+	}
+	return p.Service == "" || strings.EqualFold(p.Service, specialization)
+}
+
+// Unfreeze снимает заморозку и продлевает срок действия на число замороженных дней (неполный день считается целым)
+func (p *Package) Unfreeze(now time.Time) int {
+	if p.FrozenAt == nil {
+		return 0
+	}
+
+	days := int(now.Sub(*p.FrozenAt).Hours()/24) + 1
+	p.ValidUntil = p.ValidUntil.AddDate(0, 0, days)
+	p.FrozenDays += days
+	p.FrozenAt = nil
+	p.Status = PackageStatusActive
+	return days
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoPackage возвращается, если у клиента нет абонемента, которым можно оплатить визит
+var ErrNoPackage = errors.New("no package can cover the appointment")
+
+// ErrPackageChanged возвращается, если статус абонемента изменился после того, как его загрузили
+var ErrPackageChanged = errors.New("package status has changed")
+
+type PackageRepository interface {
+	CreatePackage(pkg *Package) error
+	GetPackage(clientID, packageID uint) (*Package, error)
+	ListPackages(clientID uint) ([]Package, error)
+	// UpdatePackage сохраняет статус, заморозку и срок действия абонемента, если в базе он все еще
+	// в статусе from; иначе возвращается ErrPackageChanged
+	UpdatePackage(pkg *Package, from string) error
+	CompleteAppointment(appointment *Appointment, packageID *uint) (*Package, *PackageDebit, error)
+}
+
+func (r *PostgresRepository) CreatePackage(pkg *Package) error {
+	if err := r.db.Create(pkg).Error; err != nil {
+		return fmt.Errorf("failed to create package: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetPackage(clientID, packageID uint) (*Package, error) {
+	var pkg Package
+	err := r.db.Preload("Debits", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Where("client_id = ?", clientID).
+		First(&pkg, packageID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get package: %w", err)
+	}
+	return &pkg, nil
+}
+
+func (r *PostgresRepository) ListPackages(clientID uint) ([]Package, error) {
+	var packages []Package
+	if err := r.db.Where("client_id = ?", clientID).Order("valid_until DESC, id DESC").Find(&packages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	return packages, nil
+}
+
+// UpdatePackage сохраняет только состояние абонемента: статус, заморозку и срок действия.
+// Остаток сессий меняют списания под блокировкой строки, и полная перезапись затерла бы
+// параллельное списание устаревшим значением. Условие на статус не дает двум переходам
+// (например, заморозке и отмене) выполниться по одному и тому же исходному состоянию.
+func (r *PostgresRepository) UpdatePackage(pkg *Package, from string) error {
+	result := r.db.Model(pkg).Where("status = ?", from).Updates(map[string]interface{}{
+		"status":      pkg.Status,
+		"frozen_at":   pkg.FrozenAt,
+		"frozen_days": pkg.FrozenDays,
+		"valid_until": pkg.ValidUntil,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update package: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrPackageChanged
+	}
+	return nil
+}
+
+// CompleteAppointment переводит запись в статус completed под блокировкой строки и в той же транзакции
+// списывает сессию с абонемента клиента. Если packageID не указан, выбирается подходящий абонемент
+// с ближайшим окончанием срока. Отсутствие абонемента не ошибка: визит завершается, списание возвращается пустым.
+func (r *PostgresRepository) CompleteAppointment(appointment *Appointment, packageID *uint) (*Package, *PackageDebit, error) {
+	var (
+		debited *Package
+		debit   *PackageDebit
+	)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := transitionAppointment(tx, appointment, AppointmentStatusCompleted); err != nil {
+			return err
+		}
+
+		var specialist Specialist
+		if err := tx.First(&specialist, appointment.SpecialistID).Error; err != nil {
+			return err
+		}
+
+		appointmentID := appointment.ID
+		var err error
+		debited, debit, err = debitPackage(tx, appointment.ClientID, specialist.Specialization, appointment.StartsAt, packageID,
+			PackageDebit{AppointmentID: &appointmentID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNoPackage) || errors.Is(err, ErrAppointmentStatus) {
+			return nil, nil, err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// Визит уже был оплачен абонементом ранее
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to complete appointment: %w", translateError(err))
+	}
+	return debited, debit, nil
+}
+
+// debitPackage списывает одну сессию с абонемента клиента, покрывающего услугу service в момент at.
+// Если packageID не указан, выбирается подходящий абонемент с ближайшим окончанием срока; если
+// подходящего нет, списание не выполняется. Указанный, но неподходящий абонемент - ErrNoPackage.
+// debit задает, за что списывается сессия.
+func debitPackage(tx *gorm.DB, clientID uint, service string, at time.Time, packageID *uint, debit PackageDebit) (*Package, *PackageDebit, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("client_id = ? AND status = ?", clientID, PackageStatusActive).
+		Order("valid_until, id")
+	if packageID != nil {
+		query = query.Where("id = ?", *packageID)
+	}
+
+	var candidates []Package
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var debited *Package
+	for i := range candidates {
+		if candidates[i].CanCover(service, at) {
+			debited = &candidates[i]
+			break
+		}
+	}
+	if debited == nil {
+		if packageID != nil {
+			return nil, nil, ErrNoPackage
+		}
+		return nil, nil, nil
+	}
+
+	debit.PackageID = debited.ID
+	debit.Sessions = 1
+	if err := tx.Create(&debit).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if debited.RemainingSessions != nil {
+		remaining := *debited.RemainingSessions - debit.Sessions
+		debited.RemainingSessions = &remaining
+		if err := tx.Model(debited).Update("remaining_sessions", remaining).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return debited, &debit, nil
+}
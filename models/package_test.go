@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPackageEffectiveStatus(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	remaining := func(n int) *int { return &n }
+
+	tests := []struct {
+		name string
+		pkg  Package
+		want string
+	}{
+		{"active", Package{Status: PackageStatusActive, ValidUntil: now, RemainingSessions: remaining(3)}, PackageStatusActive},
+		{"expired", Package{Status: PackageStatusActive, ValidUntil: now.AddDate(0, 0, -1), RemainingSessions: remaining(3)}, PackageStatusExpired},
+		{"exhausted", Package{Status: PackageStatusActive, ValidUntil: now, RemainingSessions: remaining(0)}, PackageStatusExhausted},
+		{"unlimited membership", Package{Status: PackageStatusActive, ValidUntil: now}, PackageStatusActive},
+		{"frozen", Package{Status: PackageStatusFrozen, ValidUntil: now}, PackageStatusFrozen},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pkg.EffectiveStatus(now); got != tt.want {
+			t.Errorf("%s: EffectiveStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackageCanCover(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	pkg := Package{
+		Status:     PackageStatusActive,
+		Service:    "Massage",
+		ValidFrom:  now.AddDate(0, 0, -5),
+		ValidUntil: now.AddDate(0, 1, 0),
+	}
+
+	if !pkg.CanCover("massage", now) {
+		t.Error("expected package to cover massage")
+	}
+	if pkg.CanCover("yoga", now) {
+		t.Error("expected package not to cover yoga")
+	}
+	if pkg.CanCover("massage", now.AddDate(0, 0, -10)) {
+		t.Error("expected package not to cover visits before valid_from")
+	}
+}
+
+func TestPackageUnfreeze(t *testing.T) {
+	frozenAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	pkg := Package{
+		Status:     PackageStatusFrozen,
+		FrozenAt:   &frozenAt,
+		ValidUntil: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	days := pkg.Unfreeze(frozenAt.Add(9*24*time.Hour + time.Hour))
+	if days != 10 {
+		t.Errorf("Unfreeze() = %d days, want 10", days)
+	}
+	if got := pkg.ValidUntil.Format(dateLayout); got != "2025-04-10" {
+		t.Errorf("ValidUntil = %s, want 2025-04-10", got)
+	}
+	if pkg.Status != PackageStatusActive || pkg.FrozenAt != nil || pkg.FrozenDays != 10 {
+		t.Errorf("unexpected package state after unfreeze: %+v", pkg)
+	}
+}
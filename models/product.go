@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Категории товаров
+const (
+	ProductCategorySupplements = "supplements"
+	ProductCategoryCosmetics   = "cosmetics"
+	ProductCategoryOther       = "other"
+)
+
+// Виды движений товара. Sale проводится строкой счета, return - отменой такого счета.
+const (
+	StockMovementReceipt  = "receipt"
+	StockMovementSale     = "sale"
+	StockMovementWriteOff = "write_off"
+	StockMovementReturn   = "return"
+)
+
+// ErrInsufficientStock возвращается, если остатка товара в филиале не хватает для расхода
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// Product - товар каталога (добавки, косметика). Товар не удаляется, а снимается с продажи.
+// Когда остаток в филиале опускается до LowStockThreshold, отправляется оповещение.
+type Product struct {
+	ID                uint   `gorm:"primarykey"`
+	SKU               string `gorm:"not null;uniqueIndex"`
+	Name              string `gorm:"not null"`
+	Category          string `gorm:"not null;index"`
+	Description       string
+	Price             Money `gorm:"type:numeric(12,2);not null"`
+	LowStockThreshold int   `gorm:"not null;default:0"`
+	Active            bool  `gorm:"not null"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// ProductStock - остаток товара в филиале; строка появляется при первом движении
+type ProductStock struct {
+	ID        uint     `gorm:"primarykey"`
+	ProductID uint     `gorm:"not null;uniqueIndex:idx_product_stock_branch"`
+	Product   *Product `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	BranchID  uint     `gorm:"not null;uniqueIndex:idx_product_stock_branch;index"`
+	Branch    *Branch  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Quantity  int      `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// StockMovement - движение товара в филиале. Quantity всегда положительное, направление задает Kind;
+// QuantityAfter - остаток после движения. Продажи и возвраты ссылаются на счет.
+type StockMovement struct {
+	ID            uint     `gorm:"primarykey"`
+	ProductID     uint     `gorm:"not null;index"`
+	Product       *Product `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	BranchID      uint     `gorm:"not null;index"`
+	Branch        *Branch  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Kind          string   `gorm:"not null"`
+	Quantity      int      `gorm:"not null"`
+	QuantityAfter int      `gorm:"not null"`
+	InvoiceID     *uint    `gorm:"index"`
+	Invoice       *Invoice `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Comment       string
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+// ProductFilter - параметры выборки каталога; Query ищет по названию и артикулу
+type ProductFilter struct {
+	Category   string
+	Query      string
+	ActiveOnly bool
+	Limit      int
+	Offset     int
+}
+
+// StockFilter - параметры выборки остатков; LowOnly оставляет остатки не выше порога товара
+type StockFilter struct {
+	ProductID uint
+	BranchID  uint
+	LowOnly   bool
+	Limit     int
+	Offset    int
+}
+
+// StockMovementFilter - параметры выборки движений товара
+type StockMovementFilter struct {
+	ProductID uint
+	BranchID  uint
+	Kind      string
+	InvoiceID uint
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// Delta возвращает изменение остатка: приход и возврат увеличивают его, продажа и списание - уменьшают
+func (m *StockMovement) Delta() int {
+	switch m.Kind {
+	case StockMovementSale, StockMovementWriteOff:
+		return -m.Quantity
+	default:
+		return m.Quantity
+	}
+}
+
+// ReachedLowStock сообщает, что движение опустило остаток до порога threshold или ниже.
+// Движения, после которых остаток и так был низким, повторно не сообщаются.
+func (m *StockMovement) ReachedLowStock(threshold int) bool {
+	before := m.QuantityAfter - m.Delta()
+	return m.QuantityAfter <= threshold && before > threshold
+}
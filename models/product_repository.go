@@ -0,0 +1,237 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductRepository interface {
+	CreateProduct(product *Product) error
+	GetProduct(id uint) (*Product, error)
+	ListProducts(filter ProductFilter) ([]Product, int64, error)
+	UpdateProduct(product *Product) error
+
+	// ListStock возвращает остатки товаров по филиалам вместе с товаром
+	ListStock(filter StockFilter) ([]ProductStock, int64, error)
+	// RecordStockMovement проводит приход или списание; нехватка остатка - ErrInsufficientStock
+	RecordStockMovement(movement *StockMovement) error
+	ListStockMovements(filter StockMovementFilter) ([]StockMovement, int64, error)
+}
+
+func (r *PostgresRepository) CreateProduct(product *Product) error {
+	if err := r.db.Create(product).Error; err != nil {
+		return fmt.Errorf("failed to create product: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetProduct(id uint) (*Product, error) {
+	var product Product
+	if err := r.db.First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return &product, nil
+}
+
+func (r *PostgresRepository) ListProducts(filter ProductFilter) ([]Product, int64, error) {
+	query := r.db.Model(&Product{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		query = query.Where("(name ILIKE ? OR sku ILIKE ?)", pattern, pattern)
+	}
+	if filter.ActiveOnly {
+		query = query.Where("active")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	var products []Product
+	if err := query.Order("name, id").Limit(filter.Limit).Offset(filter.Offset).Find(&products).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
+	}
+	return products, total, nil
+}
+
+func (r *PostgresRepository) UpdateProduct(product *Product) error {
+	result := r.db.Save(product)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update product: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListStock(filter StockFilter) ([]ProductStock, int64, error) {
+	query := r.db.Model(&ProductStock{}).Joins("JOIN products ON products.id = product_stocks.product_id")
+	if filter.ProductID != 0 {
+		query = query.Where("product_stocks.product_id = ?", filter.ProductID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("product_stocks.branch_id = ?", filter.BranchID)
+	}
+	if filter.LowOnly {
+		query = query.Where("products.active AND product_stocks.quantity <= products.low_stock_threshold")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count stock: %w", err)
+	}
+
+	var stock []ProductStock
+	err := query.Preload("Product").
+		Order("products.name, product_stocks.product_id, product_stocks.branch_id").
+		Limit(filter.Limit).Offset(filter.Offset).
+		Find(&stock).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list stock: %w", err)
+	}
+	return stock, total, nil
+}
+
+func (r *PostgresRepository) RecordStockMovement(movement *StockMovement) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return moveStock(tx, movement)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientStock) {
+			return err
+		}
+		return fmt.Errorf("failed to record stock movement: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListStockMovements(filter StockMovementFilter) ([]StockMovement, int64, error) {
+	query := r.db.Model(&StockMovement{})
+	if filter.ProductID != 0 {
+		query = query.Where("product_id = ?", filter.ProductID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.InvoiceID != 0 {
+		query = query.Where("invoice_id = ?", filter.InvoiceID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+
+	var movements []StockMovement
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&movements).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+	return movements, total, nil
+}
+
+// moveStock проводит движение в транзакции tx: блокирует остаток товара в филиале, создавая его
+// при первом движении, проверяет, что расход его не превышает, и записывает движение с новым остатком
+func moveStock(tx *gorm.DB, movement *StockMovement) error {
+	stock := ProductStock{ProductID: movement.ProductID, BranchID: movement.BranchID}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "branch_id"}},
+		DoNothing: true,
+	}).Create(&stock).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND branch_id = ?", movement.ProductID, movement.BranchID).
+		First(&stock).Error
+	if err != nil {
+		return err
+	}
+
+	quantity := stock.Quantity + movement.Delta()
+	if quantity < 0 {
+		return fmt.Errorf("%w: product %d has %d left", ErrInsufficientStock, movement.ProductID, stock.Quantity)
+	}
+	if err := tx.Model(&stock).Update("quantity", quantity).Error; err != nil {
+		return err
+	}
+
+	movement.QuantityAfter = quantity
+	return tx.Create(movement).Error
+}
+
+// sellInvoiceProducts списывает со склада товары из строк счета. Остатки блокируются в порядке товара
+// и филиала, чтобы параллельные продажи не ждали друг друга по кругу.
+func sellInvoiceProducts(tx *gorm.DB, invoice *Invoice) error {
+	var lines []InvoiceLine
+	for _, line := range invoice.Lines {
+		if line.ProductID != nil && line.BranchID != nil {
+			lines = append(lines, line)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if *lines[i].ProductID != *lines[j].ProductID {
+			return *lines[i].ProductID < *lines[j].ProductID
+		}
+		return *lines[i].BranchID < *lines[j].BranchID
+	})
+
+	for _, line := range lines {
+		err := moveStock(tx, &StockMovement{
+			ProductID: *line.ProductID,
+			BranchID:  *line.BranchID,
+			Kind:      StockMovementSale,
+			Quantity:  line.Quantity,
+			InvoiceID: &invoice.ID,
+			Comment:   invoice.Number,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returnInvoiceStock возвращает на склад товары, проданные по счету invoiceID
+func returnInvoiceStock(tx *gorm.DB, invoiceID uint) error {
+	var sales []StockMovement
+	if err := tx.Where("invoice_id = ? AND kind = ?", invoiceID, StockMovementSale).Order("id").Find(&sales).Error; err != nil {
+		return err
+	}
+
+	for _, sale := range sales {
+		err := moveStock(tx, &StockMovement{
+			ProductID: sale.ProductID,
+			BranchID:  sale.BranchID,
+			Kind:      StockMovementReturn,
+			Quantity:  sale.Quantity,
+			InvoiceID: sale.InvoiceID,
+			Comment:   "invoice cancelled",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestStockMovementReachedLowStock(t *testing.T) {
+	tests := []struct {
+		name     string
+		movement StockMovement
+		want     bool
+	}{
+		{"sale crosses threshold", StockMovement{Kind: StockMovementSale, Quantity: 2, QuantityAfter: 4}, true},
+		{"sale above threshold", StockMovement{Kind: StockMovementSale, Quantity: 2, QuantityAfter: 6}, false},
+		{"already low", StockMovement{Kind: StockMovementWriteOff, Quantity: 1, QuantityAfter: 3}, false},
+		{"receipt", StockMovement{Kind: StockMovementReceipt, Quantity: 10, QuantityAfter: 12}, false},
+		{"return stays low", StockMovement{Kind: StockMovementReturn, Quantity: 1, QuantityAfter: 2}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.movement.ReachedLowStock(4); got != tt.want {
+			t.Errorf("%s: ReachedLowStock(4) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoyaltyRuleAppliesToProducts(t *testing.T) {
+	id := uint(1)
+	rule := &LoyaltyRule{AppliesTo: LoyaltyAppliesToProducts}
+	if !rule.applies(&InvoiceLine{ProductID: &id}) {
+		t.Error("products rule does not apply to a product line")
+	}
+	if rule.applies(&InvoiceLine{AppointmentID: &id}) {
+		t.Error("products rule applies to an appointment line")
+	}
+}
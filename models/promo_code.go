@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Виды скидки по промокоду
+const (
+	PromoKindPercent = "percent"
+	PromoKindFixed   = "fixed"
+)
+
+var (
+	ErrPromoNotApplicable = errors.New("promo code is not applicable")
+	ErrPromoExhausted     = errors.New("promo code usage limit is reached")
+)
+
+// PromoCode - промокод на скидку при выставлении счета: Percent процентов или фиксированная Amount.
+// FirstVisitOnly ограничивает код клиентами без завершенных визитов, кроме визитов самого счета,
+// Channels - клиентами из указанных рекламных каналов. UsedCount - число неотмененных счетов с кодом;
+// UsageLimit ограничивает его в целом, PerClientLimit - для одного клиента.
+type PromoCode struct {
+	ID             uint       `gorm:"primarykey"`
+	Code           string     `gorm:"not null;uniqueIndex"`
+	Kind           string     `gorm:"not null"`
+	Percent        int        `gorm:"not null;default:0"`
+	Amount         Money      `gorm:"type:numeric(12,2);not null;default:0"`
+	FirstVisitOnly bool       `gorm:"not null;default:false"`
+	Channels       StringList `gorm:"type:jsonb;not null;default:'[]'"`
+	UsageLimit     *int
+	PerClientLimit *int
+	UsedCount      int        `gorm:"not null;default:0"`
+	ValidFrom      *time.Time `gorm:"type:date"`
+	ValidUntil     *time.Time `gorm:"type:date"`
+	Active         bool       `gorm:"not null"`
+	Comment        string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NormalizeCode приводит код промокода или сертификата к виду, в котором он хранится
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Check проверяет ограничения кода, не требующие обращения к БД: активность, срок действия и канал клиента
+func (p *PromoCode) Check(client *Client, at time.Time) error {
+	day := at.Format(dateLayout)
+	switch {
+	case !p.Active:
+		return fmt.Errorf("%w: code is inactive", ErrPromoNotApplicable)
+	case p.ValidFrom != nil && day < p.ValidFrom.Format(dateLayout):
+		return fmt.Errorf("%w: code is not valid yet", ErrPromoNotApplicable)
+	case p.ValidUntil != nil && day > p.ValidUntil.Format(dateLayout):
+		return fmt.Errorf("%w: code has expired", ErrPromoNotApplicable)
+	case len(p.Channels) > 0 && !slices.Contains(p.Channels, client.AdvertisingChannel):
+		return fmt.Errorf("%w: code is not available for the client's advertising channel", ErrPromoNotApplicable)
+	}
+	return nil
+}
+
+// CheckFirstVisit проверяет ограничение FirstVisitOnly: completed - завершенные визиты клиента.
+// Визиты, за которые выставляется счет invoice, первыми остаются.
+func (p *PromoCode) CheckFirstVisit(invoice *Invoice, completed []uint) error {
+	if !p.FirstVisitOnly {
+		return nil
+	}
+	for _, id := range completed {
+		if !slices.ContainsFunc(invoice.Lines, func(line InvoiceLine) bool {
+			return line.AppointmentID != nil && *line.AppointmentID == id
+		}) {
+			return fmt.Errorf("%w: code is for the first visit only", ErrPromoNotApplicable)
+		}
+	}
+	return nil
+}
+
+// Discount возвращает скидку по коду с суммы base; фиксированная скидка не превышает base
+func (p *PromoCode) Discount(base Money) Money {
+	if p.Kind == PromoKindPercent {
+		return base.Percent(p.Percent)
+	}
+	return min(p.Amount, base)
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownCode возвращается, если промокод или сертификат с указанным кодом не найден
+var ErrUnknownCode = errors.New("promo code or gift certificate not found")
+
+type PromoCodeRepository interface {
+	CreatePromoCode(promo *PromoCode) error
+	GetPromoCode(id uint) (*PromoCode, error)
+	ListPromoCodes(activeOnly bool, limit, offset int) ([]PromoCode, int64, error)
+	// UpdatePromoCode сохраняет условия кода; счетчик использований не меняется
+	UpdatePromoCode(promo *PromoCode) error
+}
+
+func (r *PostgresRepository) CreatePromoCode(promo *PromoCode) error {
+	if err := r.db.Create(promo).Error; err != nil {
+		return fmt.Errorf("failed to create promo code: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetPromoCode(id uint) (*PromoCode, error) {
+	var promo PromoCode
+	if err := r.db.First(&promo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+	return &promo, nil
+}
+
+func (r *PostgresRepository) ListPromoCodes(activeOnly bool, limit, offset int) ([]PromoCode, int64, error) {
+	query := r.db.Model(&PromoCode{})
+	if activeOnly {
+		query = query.Where("active")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count promo codes: %w", err)
+	}
+
+	var promos []PromoCode
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&promos).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list promo codes: %w", err)
+	}
+	return promos, total, nil
+}
+
+func (r *PostgresRepository) UpdatePromoCode(promo *PromoCode) error {
+	result := r.db.Omit("UsedCount").Save(promo)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update promo code: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// applyPromoCode применяет промокод к новому счету в транзакции tx. Счетчик использований
+// увеличивается условным UPDATE, который блокирует строку кода до конца транзакции: параллельные
+// счета с тем же кодом проверяют лимиты по очереди. Если код не подошел, откат транзакции
+// возвращает и счетчик.
+func applyPromoCode(tx *gorm.DB, invoice *Invoice, code string) error {
+	var promo PromoCode
+	result := tx.Model(&promo).
+		Clauses(clause.Returning{}).
+		Where("code = ?", code).
+		Where("usage_limit IS NULL OR used_count < usage_limit").
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&PromoCode{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUnknownCode
+		}
+		return ErrPromoExhausted
+	}
+
+	var client Client
+	if err := tx.First(&client, invoice.ClientID).Error; err != nil {
+		return err
+	}
+	if err := promo.Check(&client, invoice.IssuedAt); err != nil {
+		return err
+	}
+
+	if promo.FirstVisitOnly {
+		var completed []uint
+		err := tx.Model(&Appointment{}).
+			Where("client_id = ? AND status = ?", invoice.ClientID, AppointmentStatusCompleted).
+			Pluck("id", &completed).Error
+		if err != nil {
+			return err
+		}
+		if err := promo.CheckFirstVisit(invoice, completed); err != nil {
+			return err
+		}
+	}
+
+	if promo.PerClientLimit != nil {
+		var used int64
+		err := tx.Model(&Invoice{}).
+			Where("promo_code_id = ? AND client_id = ? AND status <> ?", promo.ID, invoice.ClientID, InvoiceStatusCancelled).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(*promo.PerClientLimit) {
+			return fmt.Errorf("%w for this client", ErrPromoExhausted)
+		}
+	}
+
+	invoice.PromoCodeID = &promo.ID
+	invoice.PromoDiscount = promo.Discount(invoice.Subtotal - invoice.Discount)
+	invoice.Discount += invoice.PromoDiscount
+	invoice.Recalculate()
+	return nil
+}
+
+// releasePromoCode возвращает использование промокода при отмене счета
+func releasePromoCode(tx *gorm.DB, invoice *Invoice) error {
+	if invoice.PromoCodeID == nil {
+		return nil
+	}
+	return tx.Model(&PromoCode{}).
+		Where("id = ? AND used_count > 0", *invoice.PromoCodeID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
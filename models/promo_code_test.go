@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPromoCodeCheck(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return &d
+	}
+	at := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	client := &Client{AdvertisingChannel: "instagram"}
+
+	tests := []struct {
+		name  string
+		promo PromoCode
+		ok    bool
+	}{
+		{"active", PromoCode{Active: true}, true},
+		{"inactive", PromoCode{}, false},
+		{"last day", PromoCode{Active: true, ValidFrom: day("2024-03-01"), ValidUntil: day("2024-03-10")}, true},
+		{"not started", PromoCode{Active: true, ValidFrom: day("2024-03-11")}, false},
+		{"expired", PromoCode{Active: true, ValidUntil: day("2024-03-09")}, false},
+		{"matching channel", PromoCode{Active: true, Channels: StringList{"vk", "instagram"}}, true},
+		{"other channel", PromoCode{Active: true, Channels: StringList{"vk"}}, false},
+	}
+	for _, tt := range tests {
+		err := tt.promo.Check(client, at)
+		if (err == nil) != tt.ok || (err != nil && !errors.Is(err, ErrPromoNotApplicable)) {
+			t.Errorf("%s: Check() = %v", tt.name, err)
+		}
+	}
+
+	percent := PromoCode{Kind: PromoKindPercent, Percent: 15}
+	if got := percent.Discount(200000); got != 30000 {
+		t.Errorf("percent discount = %d, want 30000", got)
+	}
+	fixed := PromoCode{Kind: PromoKindFixed, Amount: 50000}
+	if got := fixed.Discount(30000); got != 30000 {
+		t.Errorf("fixed discount = %d, want 30000", got)
+	}
+}
+
+func TestPromoCodeCheckFirstVisit(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	promo := PromoCode{FirstVisitOnly: true}
+	invoice := &Invoice{Lines: []InvoiceLine{{AppointmentID: id(3)}, {ProductID: id(1)}}}
+
+	tests := []struct {
+		name      string
+		completed []uint
+		ok        bool
+	}{
+		{"no visits", nil, true},
+		{"only the invoiced visit", []uint{3}, true},
+		{"earlier visit", []uint{2, 3}, false},
+	}
+	for _, tt := range tests {
+		err := promo.CheckFirstVisit(invoice, tt.completed)
+		if (err == nil) != tt.ok || (err != nil && !errors.Is(err, ErrPromoNotApplicable)) {
+			t.Errorf("%s: CheckFirstVisit() = %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Типы вопросов анкеты
+const (
+	QuestionTypeText   = "text"
+	QuestionTypeChoice = "choice"
+	QuestionTypeScale  = "scale"
+	QuestionTypeDate   = "date"
+)
+
+const maxTextAnswerLength = 5000
+
+// QuestionnaireTemplate - шаблон анкеты. Вопросы хранятся в версиях: изменение вопросов
+// создает новую версию, а ответы клиентов ссылаются на ту версию, по которой они заполнялись.
+type QuestionnaireTemplate struct {
+	gorm.Model
+	Code           string `gorm:"not null;uniqueIndex"`
+	Title          string `gorm:"not null"`
+	Description    string
+	CurrentVersion int `gorm:"not null;default:1"`
+	// Latest - текущая версия вопросов, заполняется репозиторием
+	Latest *QuestionnaireVersion `gorm:"-"`
+}
+
+// QuestionnaireVersion - неизменяемый набор вопросов шаблона
+type QuestionnaireVersion struct {
+	ID         uint                   `gorm:"primarykey"`
+	TemplateID uint                   `gorm:"not null;uniqueIndex:idx_questionnaire_version"`
+	Template   *QuestionnaireTemplate `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Version    int                    `gorm:"not null;uniqueIndex:idx_questionnaire_version"`
+	Questions  Questions              `gorm:"type:jsonb;not null"`
+	CreatedAt  time.Time
+}
+
+// Question - вопрос анкеты. Options задаются для choice (Multiple - несколько ответов),
+// Min и Max - для scale.
+type Question struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+	Multiple bool     `json:"multiple,omitempty"`
+	Min      int      `json:"min,omitempty"`
+	Max      int      `json:"max,omitempty"`
+}
+
+// Questions хранится в jsonb-колонке
+type Questions []Question
+
+// QuestionnaireResponse - заполненная клиентом анкета по конкретной версии шаблона
+type QuestionnaireResponse struct {
+	gorm.Model
+	ClientID   uint                   `gorm:"not null;index"`
+	Client     *Client                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TemplateID uint                   `gorm:"not null;index"`
+	Template   *QuestionnaireTemplate `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	VersionID  uint                   `gorm:"not null"`
+	Version    *QuestionnaireVersion  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Answers    Answers                `gorm:"type:jsonb;not null"`
+}
+
+// Answers - ответы по ключам вопросов, хранятся в jsonb-колонке
+type Answers map[string]interface{}
+
+func (q Questions) Value() (driver.Value, error) {
+	return jsonValue(q)
+}
+
+func (q *Questions) Scan(value interface{}) error {
+	return jsonScan(value, q)
+}
+
+func (a Answers) Value() (driver.Value, error) {
+	return jsonValue(a)
+}
+
+func (a *Answers) Scan(value interface{}) error {
+	return jsonScan(value, a)
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func jsonScan(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case nil:
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into %T", value, dest)
+}
+
+// Validate проверяет набор вопросов: уникальные ключи, известные типы и параметры типов
+func (q Questions) Validate() error {
+	if len(q) == 0 {
+		return errors.New("questionnaire must contain at least one question")
+	}
+
+	seen := make(map[string]bool, len(q))
+	for _, question := range q {
+		if question.Key == "" || question.Label == "" {
+			return errors.New("question key and label are required")
+		}
+		if seen[question.Key] {
+			return fmt.Errorf("duplicate question key %q", question.Key)
+		}
+		seen[question.Key] = true
+
+		switch question.Type {
+		case QuestionTypeText, QuestionTypeDate:
+		case QuestionTypeChoice:
+			if len(question.Options) == 0 {
+				return fmt.Errorf("question %q: choice requires options", question.Key)
+			}
+		case QuestionTypeScale:
+			if question.Min >= question.Max {
+				return fmt.Errorf("question %q: scale min must be less than max", question.Key)
+			}
+		default:
+			return fmt.Errorf("question %q: unknown type %q", question.Key, question.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateAnswers проверяет ответы по вопросам версии и возвращает их в нормализованном виде:
+// пустые ответы на необязательные вопросы отбрасываются, шкала приводится к целому числу.
+func (v *QuestionnaireVersion) ValidateAnswers(answers map[string]interface{}) (Answers, error) {
+	known := make(map[string]bool, len(v.Questions))
+	for _, question := range v.Questions {
+		known[question.Key] = true
+	}
+	for key := range answers {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown question %q", key)
+		}
+	}
+
+	result := make(Answers, len(answers))
+	for _, question := range v.Questions {
+		value, ok := answers[question.Key]
+		if !ok || value == nil || value == "" {
+			if question.Required {
+				return nil, fmt.Errorf("question %q: answer is required", question.Key)
+			}
+			continue
+		}
+
+		normalized, err := question.normalizeAnswer(value)
+		if err != nil {
+			return nil, fmt.Errorf("question %q: %w", question.Key, err)
+		}
+		result[question.Key] = normalized
+	}
+	return result, nil
+}
+
+func (q *Question) normalizeAnswer(value interface{}) (interface{}, error) {
+	switch q.Type {
+	case QuestionTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected text")
+		}
+		if utf8.RuneCountInString(s) > maxTextAnswerLength {
+			return nil, fmt.Errorf("answer is longer than %d characters", maxTextAnswerLength)
+		}
+		return s, nil
+
+	case QuestionTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected date")
+		}
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return nil, errors.New("expected date in format YYYY-MM-DD")
+		}
+		return s, nil
+
+	case QuestionTypeScale:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, errors.New("expected integer")
+		}
+		if int(n) < q.Min || int(n) > q.Max {
+			return nil, fmt.Errorf("expected value from %d to %d", q.Min, q.Max)
+		}
+		return int(n), nil
+
+	case QuestionTypeChoice:
+		if !q.Multiple {
+			s, ok := value.(string)
+			if !ok || !q.hasOption(s) {
+				return nil, errors.New("expected one of the options")
+			}
+			return s, nil
+		}
+
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, errors.New("expected list of options")
+		}
+		if len(items) == 0 && q.Required {
+			return nil, errors.New("answer is required")
+		}
+		chosen := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok || !q.hasOption(s) {
+				return nil, errors.New("expected list of options")
+			}
+			chosen = append(chosen, s)
+		}
+		return chosen, nil
+	}
+	return nil, fmt.Errorf("unknown question type %q", q.Type)
+}
+
+func (q *Question) hasOption(value string) bool {
+	for _, option := range q.Options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
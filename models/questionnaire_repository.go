@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QuestionnaireRepository interface {
+	CreateQuestionnaireTemplate(template *QuestionnaireTemplate, questions Questions) error
+	// UpdateQuestionnaireTemplate сохраняет название и описание шаблона; если questions отличаются
+	// от текущей версии, добавляется новая версия вопросов
+	UpdateQuestionnaireTemplate(template *QuestionnaireTemplate, questions Questions) error
+	GetQuestionnaireTemplate(id uint) (*QuestionnaireTemplate, error)
+	ListQuestionnaireTemplates(limit, offset int) ([]QuestionnaireTemplate, int64, error)
+	GetQuestionnaireVersion(templateID uint, version int) (*QuestionnaireVersion, error)
+	CreateQuestionnaireResponse(response *QuestionnaireResponse) error
+	GetQuestionnaireResponse(clientID, responseID uint) (*QuestionnaireResponse, error)
+	ListQuestionnaireResponses(clientID, templateID uint, limit, offset int) ([]QuestionnaireResponse, int64, error)
+}
+
+func (r *PostgresRepository) CreateQuestionnaireTemplate(template *QuestionnaireTemplate, questions Questions) error {
+	version := &QuestionnaireVersion{Version: 1, Questions: questions}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		template.CurrentVersion = 1
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+		version.TemplateID = template.ID
+		return tx.Create(version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create questionnaire template: %w", translateError(err))
+	}
+
+	template.Latest = version
+	return nil
+}
+
+func (r *PostgresRepository) UpdateQuestionnaireTemplate(template *QuestionnaireTemplate, questions Questions) error {
+	var latest QuestionnaireVersion
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current QuestionnaireTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, template.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ? AND version = ?", current.ID, current.CurrentVersion).First(&latest).Error; err != nil {
+			return err
+		}
+
+		template.CurrentVersion = current.CurrentVersion
+		if questions != nil && !sameQuestions(latest.Questions, questions) {
+			latest = QuestionnaireVersion{
+				TemplateID: current.ID,
+				Version:    current.CurrentVersion + 1,
+				Questions:  questions,
+			}
+			if err := tx.Create(&latest).Error; err != nil {
+				return err
+			}
+			template.CurrentVersion = latest.Version
+		}
+
+		return tx.Model(template).Select("title", "description", "current_version").Updates(template).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update questionnaire template: %w", translateError(err))
+	}
+
+	template.Latest = &latest
+	return nil
+}
+
+func (r *PostgresRepository) GetQuestionnaireTemplate(id uint) (*QuestionnaireTemplate, error) {
+	var template QuestionnaireTemplate
+	if err := r.db.First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get questionnaire template: %w", err)
+	}
+
+	var latest QuestionnaireVersion
+	if err := r.db.Where("template_id = ? AND version = ?", template.ID, template.CurrentVersion).First(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to get questionnaire questions: %w", err)
+	}
+	template.Latest = &latest
+	return &template, nil
+}
+
+func (r *PostgresRepository) ListQuestionnaireTemplates(limit, offset int) ([]QuestionnaireTemplate, int64, error) {
+	query := r.db.Model(&QuestionnaireTemplate{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count questionnaire templates: %w", err)
+	}
+
+	var templates []QuestionnaireTemplate
+	if err := query.Order("title, id").Limit(limit).Offset(offset).Find(&templates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list questionnaire templates: %w", err)
+	}
+	return templates, total, nil
+}
+
+func (r *PostgresRepository) GetQuestionnaireVersion(templateID uint, version int) (*QuestionnaireVersion, error) {
+	var content QuestionnaireVersion
+	if err := r.db.Where("template_id = ? AND version = ?", templateID, version).First(&content).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get questionnaire version: %w", err)
+	}
+	return &content, nil
+}
+
+func (r *PostgresRepository) CreateQuestionnaireResponse(response *QuestionnaireResponse) error {
+	if err := r.db.Omit(clause.Associations).Create(response).Error; err != nil {
+		return fmt.Errorf("failed to save questionnaire response: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetQuestionnaireResponse(clientID, responseID uint) (*QuestionnaireResponse, error) {
+	var response QuestionnaireResponse
+	err := r.db.Preload("Template").Preload("Version").
+		Where("client_id = ?", clientID).
+		First(&response, responseID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get questionnaire response: %w", err)
+	}
+	return &response, nil
+}
+
+func (r *PostgresRepository) ListQuestionnaireResponses(clientID, templateID uint, limit, offset int) ([]QuestionnaireResponse, int64, error) {
+	query := r.db.Model(&QuestionnaireResponse{}).Where("client_id = ?", clientID)
+	if templateID != 0 {
+		query = query.Where("template_id = ?", templateID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count questionnaire responses: %w", err)
+	}
+
+	var responses []QuestionnaireResponse
+	err := query.Preload("Template").Preload("Version").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&responses).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list questionnaire responses: %w", err)
+	}
+	return responses, total, nil
+}
+
+// sameQuestions сравнивает наборы вопросов по их JSON-представлению, в котором они и хранятся
+func sameQuestions(a, b Questions) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
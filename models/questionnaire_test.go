@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQuestionnaireValidateAnswers(t *testing.T) {
+	version := QuestionnaireVersion{Questions: Questions{
+		{Key: "goal", Label: "Goal", Type: QuestionTypeText, Required: true},
+		{Key: "pain", Label: "Pain level", Type: QuestionTypeScale, Min: 0, Max: 10},
+		{Key: "areas", Label: "Areas", Type: QuestionTypeChoice, Options: []string{"back", "neck"}, Multiple: true},
+		{Key: "last_visit", Label: "Last visit", Type: QuestionTypeDate},
+	}}
+
+	parse := func(body string) map[string]interface{} {
+		var answers map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &answers); err != nil {
+			t.Fatal(err)
+		}
+		return answers
+	}
+
+	answers, err := version.ValidateAnswers(parse(`{"goal": "relax", "pain": 4, "areas": ["neck"], "last_visit": ""}`))
+	if err != nil {
+		t.Fatalf("ValidateAnswers() error = %v", err)
+	}
+	if answers["pain"] != 4 {
+		t.Errorf("pain = %#v, want 4", answers["pain"])
+	}
+	if _, ok := answers["last_visit"]; ok {
+		t.Error("empty optional answer should be dropped")
+	}
+
+	invalid := []string{
+		`{"pain": 4}`,
+		`{"goal": "relax", "pain": 11}`,
+		`{"goal": "relax", "pain": 2.5}`,
+		`{"goal": "relax", "areas": ["knee"]}`,
+		`{"goal": "relax", "last_visit": "01.02.2025"}`,
+		`{"goal": "relax", "unknown": "x"}`,
+	}
+	for _, body := range invalid {
+		if _, err := version.ValidateAnswers(parse(body)); err == nil {
+			t.Errorf("ValidateAnswers(%s) expected error", body)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Статусы реферальной награды: pending - приглашенный еще не оплатил визит, qualified - оплатил
+// и награда причитается пригласившему, rewarded - награда выдана, cancelled - отменена
+const (
+	ReferralStatusPending   = "pending"
+	ReferralStatusQualified = "qualified"
+	ReferralStatusRewarded  = "rewarded"
+	ReferralStatusCancelled = "cancelled"
+)
+
+// Referral - клиент ReferrerID привел клиента ReferredID. У клиента может быть только один пригласивший.
+// Награда RewardAmount причитается после первого полностью оплаченного счета приглашенного.
+type Referral struct {
+	ID                  uint    `gorm:"primarykey"`
+	ReferrerID          uint    `gorm:"not null;index"`
+	Referrer            *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	ReferredID          uint    `gorm:"not null;uniqueIndex"`
+	Referred            *Client `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Status              string  `gorm:"not null;default:pending;index"`
+	RewardAmount        Money   `gorm:"type:numeric(12,2);not null;default:0"`
+	QualifyingInvoiceID *uint
+	QualifyingInvoice   *Invoice `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	QualifiedAt         *time.Time
+	RewardedAt          *time.Time
+	Comment             string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// ReferralFilter - параметры выборки рефералов; BranchID - домашний филиал приведенного клиента
+type ReferralFilter struct {
+	ReferrerID uint
+	Status     string
+	BranchID   uint
+	Limit      int
+	Offset     int
+}
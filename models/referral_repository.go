@@ -0,0 +1,165 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReferralCycle        = errors.New("clients cannot refer each other")
+	ErrReferralNotQualified = errors.New("referral reward is not due yet")
+	ErrReferralClosed       = errors.New("referral is already rewarded or cancelled")
+)
+
+type ReferralRepository interface {
+	CreateReferral(referral *Referral) error
+	GetReferral(id uint) (*Referral, error)
+	ListReferrals(filter ReferralFilter) ([]Referral, int64, error)
+	// QualifyReferral отмечает, что приглашенный клиент оплатил первый счет; возвращает nil,
+	// если ожидающего реферала у клиента нет
+	QualifyReferral(referredID, invoiceID uint, at time.Time) (*Referral, error)
+	RewardReferral(id uint, at time.Time) (*Referral, error)
+	CancelReferral(id uint) (*Referral, error)
+}
+
+func (r *PostgresRepository) CreateReferral(referral *Referral) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&Referral{}).
+			Where("referrer_id = ? AND referred_id = ?", referral.ReferredID, referral.ReferrerID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrReferralCycle
+		}
+
+		referral.Status = ReferralStatusPending
+		if err := tx.Create(referral).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Referrer").Preload("Referred").First(referral, referral.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrReferralCycle) {
+			return err
+		}
+		return fmt.Errorf("failed to create referral: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetReferral(id uint) (*Referral, error) {
+	var referral Referral
+	if err := r.db.Preload("Referrer").Preload("Referred").First(&referral, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get referral: %w", err)
+	}
+	return &referral, nil
+}
+
+func (r *PostgresRepository) ListReferrals(filter ReferralFilter) ([]Referral, int64, error) {
+	query := r.db.Model(&Referral{})
+
+	if filter.ReferrerID != 0 {
+		query = query.Where("referrer_id = ?", filter.ReferrerID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("referred_id IN (SELECT id FROM clients WHERE branch_id = ?)", filter.BranchID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count referrals: %w", err)
+	}
+
+	var referrals []Referral
+	err := query.Preload("Referrer").Preload("Referred").
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).Offset(filter.Offset).
+		Find(&referrals).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, total, nil
+}
+
+func (r *PostgresRepository) QualifyReferral(referredID, invoiceID uint, at time.Time) (*Referral, error) {
+	var referral Referral
+	result := r.db.Model(&referral).
+		Clauses(clause.Returning{}).
+		Where("referred_id = ? AND status = ?", referredID, ReferralStatusPending).
+		Updates(map[string]interface{}{
+			"status":                ReferralStatusQualified,
+			"qualifying_invoice_id": invoiceID,
+			"qualified_at":          at,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to qualify referral: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &referral, nil
+}
+
+func (r *PostgresRepository) RewardReferral(id uint, at time.Time) (*Referral, error) {
+	return r.changeReferral(id, func(referral *Referral) error {
+		if referral.Status != ReferralStatusQualified {
+			if referral.Status == ReferralStatusPending {
+				return ErrReferralNotQualified
+			}
+			return ErrReferralClosed
+		}
+		referral.Status = ReferralStatusRewarded
+		referral.RewardedAt = &at
+		return nil
+	})
+}
+
+func (r *PostgresRepository) CancelReferral(id uint) (*Referral, error) {
+	return r.changeReferral(id, func(referral *Referral) error {
+		if referral.Status == ReferralStatusRewarded || referral.Status == ReferralStatusCancelled {
+			return ErrReferralClosed
+		}
+		referral.Status = ReferralStatusCancelled
+		return nil
+	})
+}
+
+// changeReferral меняет реферал под блокировкой строки
+func (r *PostgresRepository) changeReferral(id uint, change func(referral *Referral) error) (*Referral, error) {
+	var referral Referral
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&referral, id).Error; err != nil {
+			return err
+		}
+		if err := change(&referral); err != nil {
+			return err
+		}
+		if err := tx.Save(&referral).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Referrer").Preload("Referred").First(&referral, id).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, ErrReferralNotQualified), errors.Is(err, ErrReferralClosed):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update referral: %w", err)
+	}
+	return &referral, nil
+}
@@ -8,18 +8,25 @@ import (
 	"os"
 )
 
-var ErrNotFound = errors.New("record not found")
+var (
+	ErrNotFound         = errors.New("record not found")
+	ErrDuplicate        = errors.New("record already exists")
+	ErrInvalidReference = errors.New("referenced record does not exist")
+)
 
 type Repository interface {
 	CreateClient(client *Client) error
 	GetClientByID(id uint) (*Client, error) // Изменили тип id на uint
 	UpdateClient(client *Client) error
 	DeleteClient(id uint) error // Изменили тип id на uint
+	ListClients(filter ClientFilter) ([]Client, int64, error)
 	Close() error
 }
 
 type PostgresRepository struct {
 	db *gorm.DB
+	// backfilledClients - клиенты, отнесенные к основному филиалу при миграции на этом запуске
+	backfilledClients []uint
 }
 
 func NewPostgresRepository() (*PostgresRepository, error) {
@@ -32,28 +39,97 @@ func NewPostgresRepository() (*PostgresRepository, error) {
 		os.Getenv("DB_PORT"),
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	if err := db.AutoMigrate(&Client{}); err != nil {
+	backfilled, err := migrate(db)
+	if err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
 	}
 
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, backfilledClients: backfilled}, nil
+}
+
+// BackfilledClientIDs возвращает клиентов, которым миграция при запуске назначила основной филиал
+func (r *PostgresRepository) BackfilledClientIDs() []uint {
+	return r.backfilledClients
+}
+
+// migrate приводит схему к текущим моделям и возвращает клиентов, получивших филиал по умолчанию
+func migrate(db *gorm.DB) ([]uint, error) {
+	if err := db.AutoMigrate(&Branch{}); err != nil {
+		return nil, err
+	}
+	backfilled, err := assignDefaultBranch(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.AutoMigrate(&Specialist{}, &AdvertisingChannel{}); err != nil {
+		return nil, err
+	}
+
+	// Канал клиента стал ссылкой на справочник: до создания внешнего ключа в справочнике должны быть все коды
+	if err := seedAdvertisingChannels(db); err != nil {
+		return nil, err
+	}
+
+	// Раньше specialist_id ни на что не ссылался: обнуляем висячие значения до создания внешнего ключа
+	if db.Migrator().HasColumn(&Client{}, "specialist_id") {
+		if err := db.Exec("UPDATE clients SET specialist_id = NULL WHERE specialist_id NOT IN (SELECT id FROM specialists)").Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.AutoMigrate(&Tag{}, &Client{}, &Organization{}, &ClientRelationship{}, &Resource{}, &Appointment{}, &WorkingHours{}, &ScheduleException{},
+		&SessionNote{}, &SessionNoteVersion{},
+		&QuestionnaireTemplate{}, &QuestionnaireVersion{}, &QuestionnaireResponse{},
+		&ConsentTemplate{}, &ConsentSignature{},
+		&Attachment{}, &Segment{},
+		&TreatmentPlan{}, &TreatmentGoal{}, &TreatmentMilestone{}, &ProgressMeasurement{},
+		&GroupClass{}, &ClassEnrollment{},
+		&Package{}, &PackageDebit{},
+		&Product{}, &ProductStock{}, &StockMovement{},
+		&PromoCode{}, &Invoice{}, &InvoiceLine{}, &Payment{}, &GiftCertificate{}, &GiftCertificateUsage{},
+		&WaitlistEntry{}, &WaitlistWindow{},
+		&Referral{},
+		&LoyaltyRule{}, &LoyaltyEntry{},
+		&Survey{}, &FeedbackResponse{},
+		&Employee{}, &Shift{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := protectLoyaltyLedger(db); err != nil {
+		return nil, err
+	}
+	return backfilled, nil
+}
+
+// translateError приводит ошибки ограничений БД к ошибкам репозитория
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrInvalidReference
+	default:
+		return err
+	}
 }
 
 func (r *PostgresRepository) CreateClient(client *Client) error {
 	if err := r.db.Create(client).Error; err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return fmt.Errorf("failed to create client: %w", translateError(err))
 	}
 	return nil
 }
 
 func (r *PostgresRepository) GetClientByID(id uint) (*Client, error) {
 	var client Client
-	if err := r.db.First(&client, id).Error; err != nil {
+	if err := r.db.Preload("Tags", orderTags).First(&client, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
 		}
@@ -73,10 +149,11 @@ func (r *PostgresRepository) DeleteClient(id uint) error {
 	return nil
 }
 
+// UpdateClient сохраняет анкету клиента; метки меняются только через SetClientTags
 func (r *PostgresRepository) UpdateClient(client *Client) error {
-	result := r.db.Save(client)
+	result := r.db.Omit("Tags").Save(client)
 	if result.Error != nil {
-		return fmt.Errorf("failed to update client: %w", result.Error)
+		return fmt.Errorf("failed to update client: %w", translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
 		return ErrNotFound
@@ -84,6 +161,63 @@ func (r *PostgresRepository) UpdateClient(client *Client) error {
 	return nil
 }
 
+func (r *PostgresRepository) ListClients(filter ClientFilter) ([]Client, int64, error) {
+	query := r.db.Model(&Client{})
+
+	if filter.SpecialistID != 0 {
+		query = query.Where("specialist_id = ?", filter.SpecialistID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Gender != "" {
+		query = query.Where("gender = ?", filter.Gender)
+	}
+	if filter.AdvertisingChannel != "" {
+		query = query.Where("advertising_channel = ?", filter.AdvertisingChannel)
+	}
+	if filter.AgeMin > 0 {
+		query = query.Where("age >= ?", filter.AgeMin)
+	}
+	if filter.AgeMax > 0 {
+		query = query.Where("age <= ?", filter.AgeMax)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.TagID != 0 {
+		query = query.Where("id IN (?)", r.db.Table("client_tags").Select("client_id").Where("tag_id = ?", filter.TagID))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count clients: %w", err)
+	}
+
+	column, ok := clientSortColumns[filter.SortBy]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+	// Вторичная сортировка по id делает порядок страниц стабильным
+	query = query.Order(column + " " + direction)
+	if column != "id" {
+		query = query.Order("id " + direction)
+	}
+
+	var clients []Client
+	if err := query.Preload("Tags", orderTags).Limit(filter.Limit).Offset(filter.Offset).Find(&clients).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list clients: %w", err)
+	}
+	return clients, total, nil
+}
+
 func (r *PostgresRepository) Close() error {
 	sqlDB, err := r.db.DB()
 	if err != nil {
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды ресурсов
+const (
+	ResourceKindRoom      = "room"
+	ResourceKindEquipment = "equipment"
+)
+
+// Статусы ресурса: выведенный из работы ресурс нельзя бронировать
+const (
+	ResourceStatusActive   = "active"
+	ResourceStatusInactive = "inactive"
+)
+
+// Resource - помещение или оборудование (массажный кабинет, сауна, тренажер).
+// Capacity - сколько записей могут одновременно пользоваться ресурсом.
+type Resource struct {
+	gorm.Model
+	Name        string `gorm:"not null;index"`
+	Kind        string `gorm:"not null;index"`
+	Capacity    int    `gorm:"not null;default:1"`
+	Status      string `gorm:"not null;default:active"`
+	Description string
+	BranchID    uint    `gorm:"not null;index"`
+	Branch      *Branch `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// ResourceFilter - параметры выборки списка ресурсов
+type ResourceFilter struct {
+	Kind     string
+	Status   string
+	BranchID uint
+	Limit    int
+	Offset   int
+}
+
+// ResourceBooking - занятость ресурса одной записью
+type ResourceBooking struct {
+	ResourceID    uint
+	AppointmentID uint
+	ClientID      uint
+	SpecialistID  uint
+	StartsAt      time.Time
+	EndsAt        time.Time
+}
+
+// PeakOccupancy возвращает максимальное число одновременно пересекающихся интервалов
+func PeakOccupancy(slots []TimeSlot) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	edges := make([]edge, 0, len(slots)*2)
+	for _, s := range slots {
+		edges = append(edges, edge{s.StartsAt, 1}, edge{s.EndsAt, -1})
+	}
+	// Конец интервала обрабатывается раньше начала в ту же минуту: записи встык не пересекаются
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+
+	peak, current := 0, 0
+	for _, e := range edges {
+		current += e.delta
+		if current > peak {
+			peak = current
+		}
+	}
+	return peak
+}
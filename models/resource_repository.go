@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ResourceRepository interface {
+	CreateResource(resource *Resource) error
+	GetResourceByID(id uint) (*Resource, error)
+	GetResourcesByIDs(ids []uint) ([]Resource, error)
+	UpdateResource(resource *Resource) error
+	DeleteResource(id uint) error
+	ListResources(filter ResourceFilter) ([]Resource, int64, error)
+	CountUpcomingResourceBookings(id uint, since time.Time) (int64, error)
+	ListResourceBookings(from, to time.Time) ([]ResourceBooking, error)
+}
+
+func (r *PostgresRepository) CreateResource(resource *Resource) error {
+	if err := r.db.Create(resource).Error; err != nil {
+		return fmt.Errorf("failed to create resource: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetResourceByID(id uint) (*Resource, error) {
+	var resource Resource
+	if err := r.db.First(&resource, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get resource: %w", err)
+	}
+	return &resource, nil
+}
+
+func (r *PostgresRepository) GetResourcesByIDs(ids []uint) ([]Resource, error) {
+	var resources []Resource
+	if len(ids) == 0 {
+		return resources, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Order("id").Find(&resources).Error; err != nil {
+		return nil, fmt.Errorf("failed to get resources: %w", err)
+	}
+	return resources, nil
+}
+
+func (r *PostgresRepository) UpdateResource(resource *Resource) error {
+	result := r.db.Save(resource)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update resource: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteResource(id uint) error {
+	result := r.db.Delete(&Resource{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete resource: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListResources(filter ResourceFilter) ([]Resource, int64, error) {
+	query := r.db.Model(&Resource{})
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count resources: %w", err)
+	}
+
+	var resources []Resource
+	if err := query.Order("kind, name, id").Limit(filter.Limit).Offset(filter.Offset).Find(&resources).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list resources: %w", err)
+	}
+	return resources, total, nil
+}
+
+// CountUpcomingResourceBookings считает активные записи, которые используют ресурс после since
+func (r *PostgresRepository) CountUpcomingResourceBookings(id uint, since time.Time) (int64, error) {
+	var count int64
+	err := resourceBookingsQuery(r.db).
+		Where("appointment_resources.resource_id = ?", id).
+		Where("appointments.ends_at > ?", since).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count resource bookings: %w", err)
+	}
+	return count, nil
+}
+
+// ListResourceBookings возвращает занятость всех ресурсов активными записями в интервале [from, to)
+func (r *PostgresRepository) ListResourceBookings(from, to time.Time) ([]ResourceBooking, error) {
+	var bookings []ResourceBooking
+	err := resourceBookingsQuery(r.db).
+		Select("appointment_resources.resource_id, appointments.id AS appointment_id, appointments.client_id, "+
+			"appointments.specialist_id, appointments.starts_at, appointments.ends_at").
+		Where("appointments.starts_at < ? AND appointments.ends_at > ?", to, from).
+		Order("appointments.starts_at, appointments.id").
+		Scan(&bookings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource bookings: %w", err)
+	}
+	return bookings, nil
+}
+
+// resourceBookingsQuery - связи записей с ресурсами, ограниченные активными записями
+func resourceBookingsQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("appointment_resources").
+		Joins("JOIN appointments ON appointments.id = appointment_resources.appointment_id").
+		Where("appointments.deleted_at IS NULL").
+		Where("appointments.status IN ?", activeAppointmentStatuses)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeakOccupancy(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2025, 3, 10, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		slots []TimeSlot
+		want  int
+	}{
+		{"empty", nil, 0},
+		{"back to back", []TimeSlot{{at(10, 0), at(11, 0)}, {at(11, 0), at(12, 0)}}, 1},
+		{"nested", []TimeSlot{{at(10, 0), at(13, 0)}, {at(10, 30), at(11, 0)}, {at(12, 0), at(12, 30)}}, 2},
+		{"all overlap", []TimeSlot{{at(10, 0), at(12, 0)}, {at(11, 0), at(13, 0)}, {at(11, 30), at(11, 45)}}, 3},
+	}
+
+	for _, tt := range tests {
+		if got := PeakOccupancy(tt.slots); got != tt.want {
+			t.Errorf("%s: PeakOccupancy() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
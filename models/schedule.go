@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды исключений из рабочего графика
+const (
+	ExceptionVacation  = "vacation"
+	ExceptionSickLeave = "sick_leave"
+	ExceptionDayOff    = "day_off"
+)
+
+const dateLayout = "2006-01-02"
+
+// WorkingHours - недельный шаблон рабочего времени специалиста: одна строка на день недели.
+// День недели хранится в формате ISO (1 - понедельник, 7 - воскресенье), время - "ЧЧ:ММ".
+type WorkingHours struct {
+	gorm.Model
+	SpecialistID uint        `gorm:"not null;uniqueIndex:idx_working_hours_weekday"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Weekday      int         `gorm:"not null;uniqueIndex:idx_working_hours_weekday"`
+	StartTime    string      `gorm:"type:varchar(5);not null"`
+	EndTime      string      `gorm:"type:varchar(5);not null"`
+	BreakStart   string      `gorm:"type:varchar(5)"`
+	BreakEnd     string      `gorm:"type:varchar(5)"`
+}
+
+// ScheduleException - период, когда специалист не принимает (отпуск, больничный, отгул).
+// Границы периода включительные.
+type ScheduleException struct {
+	gorm.Model
+	SpecialistID uint        `gorm:"not null;index"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Kind         string      `gorm:"not null"`
+	StartsOn     time.Time   `gorm:"type:date;not null"`
+	EndsOn       time.Time   `gorm:"type:date;not null"`
+	Reason       string
+}
+
+// TimeSlot - свободный интервал для записи
+type TimeSlot struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// ISOWeekday возвращает день недели в формате ISO
+func ISOWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// ParseClock переводит время "ЧЧ:ММ" в минуты от начала суток
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate проверяет согласованность шаблона: начало раньше конца, перерыв внутри рабочего дня
+func (w *WorkingHours) Validate() error {
+	start, err := ParseClock(w.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(w.EndTime)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return fmt.Errorf("weekday %d: start time must be before end time", w.Weekday)
+	}
+
+	if w.BreakStart == "" && w.BreakEnd == "" {
+		return nil
+	}
+	breakStart, err := ParseClock(w.BreakStart)
+	if err != nil {
+		return err
+	}
+	breakEnd, err := ParseClock(w.BreakEnd)
+	if err != nil {
+		return err
+	}
+	if breakStart >= breakEnd || breakStart < start || breakEnd > end {
+		return fmt.Errorf("weekday %d: break must be inside working hours", w.Weekday)
+	}
+	return nil
+}
+
+// intervals возвращает рабочие интервалы дня за вычетом перерыва
+func (w *WorkingHours) intervals(day time.Time) []TimeSlot {
+	at := func(clock string) time.Time {
+		minutes, _ := ParseClock(clock)
+		return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+	}
+
+	if w.BreakStart == "" {
+		return []TimeSlot{{StartsAt: at(w.StartTime), EndsAt: at(w.EndTime)}}
+	}
+	return []TimeSlot{
+		{StartsAt: at(w.StartTime), EndsAt: at(w.BreakStart)},
+		{StartsAt: at(w.BreakEnd), EndsAt: at(w.EndTime)},
+	}
+}
+
+// Covers сообщает, попадает ли день в период исключения
+func (e *ScheduleException) Covers(day time.Time) bool {
+	d := day.Format(dateLayout)
+	return d >= e.StartsOn.Format(dateLayout) && d <= e.EndsOn.Format(dateLayout)
+}
+
+// BusyIntervals собирает время, занятое записями и групповыми занятиями специалиста
+func BusyIntervals(appointments []Appointment, classes []GroupClass) []TimeSlot {
+	busy := make([]TimeSlot, 0, len(appointments)+len(classes))
+	for _, a := range appointments {
+		busy = append(busy, TimeSlot{StartsAt: a.StartsAt, EndsAt: a.EndsAt})
+	}
+	for _, class := range classes {
+		busy = append(busy, TimeSlot{StartsAt: class.StartsAt, EndsAt: class.EndsAt})
+	}
+	return busy
+}
+
+// ComputeFreeSlots нарезает рабочее время специалиста с from по to (дни включительно, в часовом поясе from)
+// на слоты длиной slot. Слоты, выпадающие на исключения, перерывы, занятое время busy или уже прошедшие к now,
+// отбрасываются.
+func ComputeFreeSlots(hours []WorkingHours, exceptions []ScheduleException, busy []TimeSlot, from, to time.Time, slot time.Duration, now time.Time) []TimeSlot {
+	byWeekday := make(map[int]WorkingHours, len(hours))
+	for _, h := range hours {
+		byWeekday[h.Weekday] = h
+	}
+
+	slots := []TimeSlot{}
+	for day := from; day.Format(dateLayout) <= to.Format(dateLayout); day = day.AddDate(0, 0, 1) {
+		template, ok := byWeekday[ISOWeekday(day)]
+		if !ok || isExceptionDay(exceptions, day) {
+			continue
+		}
+
+		for _, interval := range template.intervals(day) {
+			for start := interval.StartsAt; !start.Add(slot).After(interval.EndsAt); start = start.Add(slot) {
+				candidate := TimeSlot{StartsAt: start, EndsAt: start.Add(slot)}
+				if candidate.StartsAt.Before(now) || overlapsBusy(busy, candidate) {
+					continue
+				}
+				slots = append(slots, candidate)
+			}
+		}
+	}
+	return slots
+}
+
+func isExceptionDay(exceptions []ScheduleException, day time.Time) bool {
+	for i := range exceptions {
+		if exceptions[i].Covers(day) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsBusy(busy []TimeSlot, slot TimeSlot) bool {
+	for _, b := range busy {
+		if b.StartsAt.Before(slot.EndsAt) && b.EndsAt.After(slot.StartsAt) {
+			return true
+		}
+	}
+	return false
+}
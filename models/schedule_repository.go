@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleRepository interface {
+	GetWorkingHours(specialistID uint) ([]WorkingHours, error)
+	ReplaceWorkingHours(specialistID uint, hours []WorkingHours) error
+	CreateScheduleException(exception *ScheduleException) error
+	ListScheduleExceptions(specialistID uint, from, to time.Time) ([]ScheduleException, error)
+	DeleteScheduleException(specialistID, id uint) error
+	ListBusyAppointments(specialistID uint, from, to time.Time) ([]Appointment, error)
+	ListBusyGroupClasses(specialistID uint, from, to time.Time) ([]GroupClass, error)
+}
+
+func (r *PostgresRepository) GetWorkingHours(specialistID uint) ([]WorkingHours, error) {
+	var hours []WorkingHours
+	if err := r.db.Where("specialist_id = ?", specialistID).Order("weekday").Find(&hours).Error; err != nil {
+		return nil, fmt.Errorf("failed to get working hours: %w", err)
+	}
+	return hours, nil
+}
+
+// ReplaceWorkingHours полностью заменяет недельный шаблон специалиста
+func (r *PostgresRepository) ReplaceWorkingHours(specialistID uint, hours []WorkingHours) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Шаблон не нуждается в истории, а мягкое удаление мешало бы уникальному индексу по дню недели
+		if err := tx.Unscoped().Where("specialist_id = ?", specialistID).Delete(&WorkingHours{}).Error; err != nil {
+			return err
+		}
+		for i := range hours {
+			hours[i].SpecialistID = specialistID
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace working hours: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreateScheduleException(exception *ScheduleException) error {
+	if err := r.db.Create(exception).Error; err != nil {
+		return fmt.Errorf("failed to create schedule exception: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListScheduleExceptions(specialistID uint, from, to time.Time) ([]ScheduleException, error) {
+	query := r.db.Where("specialist_id = ?", specialistID)
+	if !from.IsZero() {
+		query = query.Where("ends_on >= ?", from.Format(dateLayout))
+	}
+	if !to.IsZero() {
+		query = query.Where("starts_on <= ?", to.Format(dateLayout))
+	}
+
+	var exceptions []ScheduleException
+	if err := query.Order("starts_on").Find(&exceptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schedule exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+func (r *PostgresRepository) DeleteScheduleException(specialistID, id uint) error {
+	result := r.db.Where("specialist_id = ?", specialistID).Delete(&ScheduleException{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete schedule exception: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListBusyAppointments возвращает активные записи специалиста, пересекающиеся с интервалом [from, to)
+func (r *PostgresRepository) ListBusyAppointments(specialistID uint, from, to time.Time) ([]Appointment, error) {
+	var appointments []Appointment
+	err := r.db.Where("specialist_id = ?", specialistID).
+		Where("status IN ?", activeAppointmentStatuses).
+		Where("starts_at < ? AND ends_at > ?", to, from).
+		Order("starts_at").
+		Find(&appointments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list busy appointments: %w", err)
+	}
+	return appointments, nil
+}
+
+// ListBusyGroupClasses возвращает назначенные групповые занятия специалиста, пересекающиеся с интервалом [from, to).
+// Записи на это время отклоняются так же, как при пересечении с другой записью.
+func (r *PostgresRepository) ListBusyGroupClasses(specialistID uint, from, to time.Time) ([]GroupClass, error) {
+	var classes []GroupClass
+	err := r.db.Where("specialist_id = ?", specialistID).
+		Where("status = ?", GroupClassStatusScheduled).
+		Where("starts_at < ? AND ends_at > ?", to, from).
+		Order("starts_at").
+		Find(&classes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list busy group classes: %w", err)
+	}
+	return classes, nil
+}
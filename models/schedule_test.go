@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeFreeSlots(t *testing.T) {
+	loc := time.UTC
+	// 2025-03-03 - понедельник
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, loc)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	hours := []WorkingHours{
+		{Weekday: 1, StartTime: "09:00", EndTime: "13:00", BreakStart: "11:00", BreakEnd: "12:00"},
+		{Weekday: 2, StartTime: "10:00", EndTime: "12:00"},
+	}
+	appointments := []Appointment{
+		{StartsAt: monday.Add(9*time.Hour + 30*time.Minute), EndsAt: monday.Add(10*time.Hour + 30*time.Minute)},
+	}
+	// Групповое занятие специалиста занимает время так же, как запись
+	classes := []GroupClass{
+		{StartsAt: tuesday.Add(11 * time.Hour), EndsAt: tuesday.Add(11*time.Hour + 45*time.Minute)},
+	}
+
+	slots := ComputeFreeSlots(hours, nil, BusyIntervals(appointments, classes), monday, tuesday, time.Hour, monday)
+
+	want := []time.Time{
+		monday.Add(12 * time.Hour),
+		tuesday.Add(10 * time.Hour),
+	}
+	if len(slots) != len(want) {
+		t.Fatalf("got %d slots, want %d: %v", len(slots), len(want), slots)
+	}
+	for i, slot := range slots {
+		if !slot.StartsAt.Equal(want[i]) || slot.EndsAt.Sub(slot.StartsAt) != time.Hour {
+			t.Errorf("slot %d = %v-%v, want start %v", i, slot.StartsAt, slot.EndsAt, want[i])
+		}
+	}
+}
+
+func TestComputeFreeSlotsSkipsExceptionsAndPast(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	hours := []WorkingHours{
+		{Weekday: 1, StartTime: "09:00", EndTime: "11:00"},
+		{Weekday: 2, StartTime: "09:00", EndTime: "11:00"},
+	}
+	exceptions := []ScheduleException{{Kind: ExceptionSickLeave, StartsOn: tuesday, EndsOn: tuesday}}
+	now := monday.Add(9*time.Hour + 15*time.Minute)
+
+	slots := ComputeFreeSlots(hours, exceptions, nil, monday, tuesday, time.Hour, now)
+	if len(slots) != 1 || !slots[0].StartsAt.Equal(monday.Add(10*time.Hour)) {
+		t.Errorf("got %v, want single slot at 10:00 on monday", slots)
+	}
+}
+
+func TestWorkingHoursValidate(t *testing.T) {
+	valid := WorkingHours{Weekday: 1, StartTime: "09:00", EndTime: "18:00", BreakStart: "13:00", BreakEnd: "14:00"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	invalid := []WorkingHours{
+		{Weekday: 1, StartTime: "18:00", EndTime: "09:00"},
+		{Weekday: 1, StartTime: "09:00", EndTime: "18:00", BreakStart: "08:00", BreakEnd: "10:00"},
+		{Weekday: 1, StartTime: "9am", EndTime: "18:00"},
+	}
+	for _, wh := range invalid {
+		if err := wh.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", wh)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Segment - сохраненное определение выборки клиентов, например
+// "женщины 30-45 из Instagram без визитов 60 дней". Состав вычисляется при каждом запросе.
+type Segment struct {
+	ID          uint            `gorm:"primarykey"`
+	Name        string          `gorm:"not null;uniqueIndex"`
+	Description string          `gorm:"type:text"`
+	Criteria    SegmentCriteria `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SegmentCriteria - условия сегмента, объединяемые по И. TagIDs требует наличия всех меток,
+// ExcludeTagIDs - отсутствия любой из них. NoVisitDays отбирает клиентов без визитов
+// и посещений групповых занятий за последние N дней (в том числе никогда не приходивших).
+type SegmentCriteria struct {
+	Gender              string   `json:"gender,omitempty"`
+	AgeMin              int      `json:"age_min,omitempty"`
+	AgeMax              int      `json:"age_max,omitempty"`
+	AdvertisingChannels []string `json:"advertising_channels,omitempty"`
+	SpecialistID        *uint    `json:"specialist_id,omitempty"`
+	BranchID            *uint    `json:"branch_id,omitempty"`
+	TagIDs              []uint   `json:"tag_ids,omitempty"`
+	ExcludeTagIDs       []uint   `json:"exclude_tag_ids,omitempty"`
+	NoVisitDays         int      `json:"no_visit_days,omitempty"`
+}
+
+func (c SegmentCriteria) Value() (driver.Value, error) {
+	return jsonValue(c)
+}
+
+func (c *SegmentCriteria) Scan(value interface{}) error {
+	return jsonScan(value, c)
+}
+
+// Validate проверяет согласованность условий сегмента
+func (c SegmentCriteria) Validate() error {
+	if c.Gender != "" && c.Gender != GenderMale && c.Gender != GenderFemale {
+		return errors.New("gender must be male or female")
+	}
+	if c.AgeMin < 0 || c.AgeMax < 0 || c.AgeMin > 120 || c.AgeMax > 120 {
+		return errors.New("age bounds must be between 0 and 120")
+	}
+	if c.AgeMax > 0 && c.AgeMin > c.AgeMax {
+		return errors.New("age_min must not exceed age_max")
+	}
+	if c.NoVisitDays < 0 || c.NoVisitDays > 3650 {
+		return errors.New("no_visit_days must be between 0 and 3650")
+	}
+	for _, include := range c.TagIDs {
+		for _, exclude := range c.ExcludeTagIDs {
+			if include == exclude {
+				return errors.New("a tag cannot be both required and excluded")
+			}
+		}
+	}
+	return nil
+}
+
+// NeedsVisitHistory сообщает, требуют ли условия данных о визитах, которых нет в поисковом индексе
+func (c SegmentCriteria) NeedsVisitHistory() bool {
+	return c.NoVisitDays > 0
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SegmentRepository interface {
+	CreateSegment(segment *Segment) error
+	GetSegment(id uint) (*Segment, error)
+	ListSegments(limit, offset int) ([]Segment, int64, error)
+	UpdateSegment(segment *Segment) error
+	DeleteSegment(id uint) error
+	// ListSegmentClients вычисляет состав сегмента на момент now; limit 0 возвращает только количество
+	ListSegmentClients(criteria SegmentCriteria, now time.Time, limit, offset int) ([]Client, int64, error)
+}
+
+func (r *PostgresRepository) CreateSegment(segment *Segment) error {
+	if err := r.db.Create(segment).Error; err != nil {
+		return fmt.Errorf("failed to create segment: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetSegment(id uint) (*Segment, error) {
+	var segment Segment
+	if err := r.db.First(&segment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get segment: %w", err)
+	}
+	return &segment, nil
+}
+
+func (r *PostgresRepository) ListSegments(limit, offset int) ([]Segment, int64, error) {
+	query := r.db.Model(&Segment{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count segments: %w", err)
+	}
+
+	var segments []Segment
+	if err := query.Order("name, id").Limit(limit).Offset(offset).Find(&segments).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list segments: %w", err)
+	}
+	return segments, total, nil
+}
+
+func (r *PostgresRepository) UpdateSegment(segment *Segment) error {
+	result := r.db.Save(segment)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update segment: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteSegment(id uint) error {
+	result := r.db.Delete(&Segment{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete segment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListSegmentClients(criteria SegmentCriteria, now time.Time, limit, offset int) ([]Client, int64, error) {
+	query := r.db.Model(&Client{})
+
+	if criteria.Gender != "" {
+		query = query.Where("gender = ?", criteria.Gender)
+	}
+	if criteria.AgeMin > 0 {
+		query = query.Where("age >= ?", criteria.AgeMin)
+	}
+	if criteria.AgeMax > 0 {
+		query = query.Where("age <= ?", criteria.AgeMax)
+	}
+	if len(criteria.AdvertisingChannels) > 0 {
+		query = query.Where("advertising_channel IN ?", criteria.AdvertisingChannels)
+	}
+	if criteria.SpecialistID != nil {
+		query = query.Where("specialist_id = ?", *criteria.SpecialistID)
+	}
+	if criteria.BranchID != nil {
+		query = query.Where("branch_id = ?", *criteria.BranchID)
+	}
+	if len(criteria.TagIDs) > 0 {
+		query = query.Where("id IN (?)", r.db.Table("client_tags").
+			Select("client_id").
+			Where("tag_id IN ?", criteria.TagIDs).
+			Group("client_id").
+			Having("COUNT(DISTINCT tag_id) = ?", len(uniqueIDs(criteria.TagIDs))))
+	}
+	if len(criteria.ExcludeTagIDs) > 0 {
+		query = query.Where("id NOT IN (?)", r.db.Table("client_tags").
+			Select("client_id").
+			Where("tag_id IN ?", criteria.ExcludeTagIDs))
+	}
+	if criteria.NoVisitDays > 0 {
+		since := now.AddDate(0, 0, -criteria.NoVisitDays)
+		query = query.
+			Where("id NOT IN (?)", r.db.Model(&Appointment{}).
+				Select("client_id").
+				Where("status = ? AND starts_at >= ? AND starts_at <= ?", AppointmentStatusCompleted, since, now)).
+			Where("id NOT IN (?)", r.db.Table("class_enrollments").
+				Select("class_enrollments.client_id").
+				Joins("JOIN group_classes ON group_classes.id = class_enrollments.group_class_id").
+				Where("class_enrollments.status = ?", EnrollmentStatusAttended).
+				Where("group_classes.starts_at >= ? AND group_classes.starts_at <= ?", since, now))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count segment clients: %w", err)
+	}
+	if limit == 0 {
+		return nil, total, nil
+	}
+
+	var clients []Client
+	if err := query.Preload("Tags", orderTags).Order("id").Limit(limit).Offset(offset).Find(&clients).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list segment clients: %w", err)
+	}
+	return clients, total, nil
+}
+
+func orderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name")
+}
+
+// uniqueIDs возвращает идентификаторы без повторов в исходном порядке
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
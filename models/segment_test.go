@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestSegmentCriteriaValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria SegmentCriteria
+		wantErr  bool
+	}{
+		{"empty", SegmentCriteria{}, false},
+		{"women 30-45 from instagram", SegmentCriteria{Gender: GenderFemale, AgeMin: 30, AgeMax: 45, AdvertisingChannels: []string{ChannelInstagram}, NoVisitDays: 60}, false},
+		{"unknown gender", SegmentCriteria{Gender: "other"}, true},
+		{"inverted ages", SegmentCriteria{AgeMin: 45, AgeMax: 30}, true},
+		{"negative days", SegmentCriteria{NoVisitDays: -1}, true},
+		{"tag required and excluded", SegmentCriteria{TagIDs: []uint{1, 2}, ExcludeTagIDs: []uint{2}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.criteria.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionNote - заметка специалиста о визите клиента. Содержимое хранится в версиях:
+// каждое редактирование добавляет новую версию, прежние версии не изменяются и не удаляются.
+type SessionNote struct {
+	gorm.Model
+	ClientID       uint         `gorm:"not null;index"`
+	Client         *Client      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID   uint         `gorm:"not null;index"`
+	Specialist     *Specialist  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	AppointmentID  *uint        `gorm:"index"`
+	Appointment    *Appointment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	VisitDate      time.Time    `gorm:"type:date;not null"`
+	CurrentVersion int          `gorm:"not null;default:1"`
+	// Latest - содержимое текущей версии, заполняется репозиторием
+	Latest *SessionNoteVersion `gorm:"-"`
+}
+
+// SessionNoteVersion - неизменяемый снимок содержимого заметки
+type SessionNoteVersion struct {
+	ID              uint         `gorm:"primarykey"`
+	SessionNoteID   uint         `gorm:"not null;uniqueIndex:idx_session_note_version"`
+	SessionNote     *SessionNote `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Version         int          `gorm:"not null;uniqueIndex:idx_session_note_version"`
+	AuthorID        uint         `gorm:"not null"`
+	Author          *Specialist  `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Complaints      string
+	Assessment      string
+	Procedures      string
+	Recommendations string
+	Text            string
+	CreatedAt       time.Time
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleVersion возвращается, если заметку успели изменить после того, как ее прочитал автор правки
+var ErrStaleVersion = errors.New("note was modified by someone else")
+
+type SessionNoteRepository interface {
+	CreateSessionNote(note *SessionNote, content *SessionNoteVersion) error
+	AddSessionNoteVersion(clientID, noteID uint, baseVersion int, content *SessionNoteVersion) (*SessionNote, error)
+	GetSessionNote(clientID, noteID uint) (*SessionNote, error)
+	ListSessionNotes(clientID uint, limit, offset int) ([]SessionNote, int64, error)
+	ListSessionNoteVersions(clientID, noteID uint) ([]SessionNoteVersion, error)
+	GetSessionNoteVersion(clientID, noteID uint, version int) (*SessionNoteVersion, error)
+}
+
+func (r *PostgresRepository) CreateSessionNote(note *SessionNote, content *SessionNoteVersion) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		note.CurrentVersion = 1
+		if err := tx.Create(note).Error; err != nil {
+			return err
+		}
+
+		content.SessionNoteID = note.ID
+		content.Version = 1
+		if content.AuthorID == 0 {
+			content.AuthorID = note.SpecialistID
+		}
+		return tx.Create(content).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session note: %w", translateError(err))
+	}
+
+	note.Latest = content
+	return nil
+}
+
+// AddSessionNoteVersion добавляет новую версию заметки. Если baseVersion больше нуля,
+// она должна совпадать с текущей версией, иначе возвращается ErrStaleVersion.
+func (r *PostgresRepository) AddSessionNoteVersion(clientID, noteID uint, baseVersion int, content *SessionNoteVersion) (*SessionNote, error) {
+	var note SessionNote
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("client_id = ?", clientID).
+			First(&note, noteID).Error; err != nil {
+			return err
+		}
+
+		if baseVersion > 0 && baseVersion != note.CurrentVersion {
+			return ErrStaleVersion
+		}
+
+		content.SessionNoteID = note.ID
+		content.Version = note.CurrentVersion + 1
+		if err := tx.Create(content).Error; err != nil {
+			return err
+		}
+
+		note.CurrentVersion = content.Version
+		return tx.Model(&note).Update("current_version", note.CurrentVersion).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, ErrStaleVersion):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to add session note version: %w", translateError(err))
+	}
+
+	note.Latest = content
+	return &note, nil
+}
+
+func (r *PostgresRepository) GetSessionNote(clientID, noteID uint) (*SessionNote, error) {
+	note, err := r.findSessionNote(clientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest SessionNoteVersion
+	if err := r.db.Where("session_note_id = ? AND version = ?", note.ID, note.CurrentVersion).First(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to get session note content: %w", err)
+	}
+	note.Latest = &latest
+	return note, nil
+}
+
+func (r *PostgresRepository) ListSessionNotes(clientID uint, limit, offset int) ([]SessionNote, int64, error) {
+	query := r.db.Model(&SessionNote{}).Where("client_id = ?", clientID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count session notes: %w", err)
+	}
+
+	var notes []SessionNote
+	if err := query.Order("visit_date DESC, id DESC").Limit(limit).Offset(offset).Find(&notes).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list session notes: %w", err)
+	}
+	if len(notes) == 0 {
+		return notes, total, nil
+	}
+
+	ids := make([]uint, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+
+	var versions []SessionNoteVersion
+	err := r.db.Where("(session_note_id, version) IN (SELECT id, current_version FROM session_notes WHERE id IN ?)", ids).
+		Find(&versions).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get session note contents: %w", err)
+	}
+
+	latest := make(map[uint]*SessionNoteVersion, len(versions))
+	for i := range versions {
+		latest[versions[i].SessionNoteID] = &versions[i]
+	}
+	for i := range notes {
+		notes[i].Latest = latest[notes[i].ID]
+	}
+	return notes, total, nil
+}
+
+func (r *PostgresRepository) ListSessionNoteVersions(clientID, noteID uint) ([]SessionNoteVersion, error) {
+	if _, err := r.findSessionNote(clientID, noteID); err != nil {
+		return nil, err
+	}
+
+	var versions []SessionNoteVersion
+	if err := r.db.Where("session_note_id = ?", noteID).Order("version").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list session note versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (r *PostgresRepository) GetSessionNoteVersion(clientID, noteID uint, version int) (*SessionNoteVersion, error) {
+	if _, err := r.findSessionNote(clientID, noteID); err != nil {
+		return nil, err
+	}
+
+	var content SessionNoteVersion
+	if err := r.db.Where("session_note_id = ? AND version = ?", noteID, version).First(&content).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get session note version: %w", err)
+	}
+	return &content, nil
+}
+
+// findSessionNote проверяет, что заметка принадлежит клиенту
+func (r *PostgresRepository) findSessionNote(clientID, noteID uint) (*SessionNote, error) {
+	var note SessionNote
+	if err := r.db.Where("client_id = ?", clientID).First(&note, noteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get session note: %w", err)
+	}
+	return &note, nil
+}
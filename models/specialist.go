@@ -0,0 +1,30 @@
+package models
+
+import "gorm.io/gorm"
+
+// Рабочие статусы специалиста
+const (
+	SpecialistStatusActive    = "active"
+	SpecialistStatusOnLeave   = "on_leave"
+	SpecialistStatusDismissed = "dismissed"
+)
+
+type Specialist struct {
+	gorm.Model
+	FullName       string `gorm:"not null"`
+	Specialization string `gorm:"not null"`
+	Phone          string `gorm:"not null"`
+	Email          string
+	Status         string  `gorm:"not null;default:active"`
+	BranchID       uint    `gorm:"not null;index"`
+	Branch         *Branch `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// SpecialistFilter - параметры выборки списка специалистов
+type SpecialistFilter struct {
+	Status         string
+	Specialization string
+	BranchID       uint
+	Limit          int
+	Offset         int
+}
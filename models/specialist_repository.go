@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type SpecialistRepository interface {
+	CreateSpecialist(specialist *Specialist) error
+	GetSpecialistByID(id uint) (*Specialist, error)
+	UpdateSpecialist(specialist *Specialist) error
+	DeleteSpecialist(id uint) error
+	ListSpecialists(filter SpecialistFilter) ([]Specialist, int64, error)
+	CountSpecialistClients(id uint) (int64, error)
+}
+
+func (r *PostgresRepository) CreateSpecialist(specialist *Specialist) error {
+	if err := r.db.Create(specialist).Error; err != nil {
+		return fmt.Errorf("failed to create specialist: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetSpecialistByID(id uint) (*Specialist, error) {
+	var specialist Specialist
+	if err := r.db.First(&specialist, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get specialist: %w", err)
+	}
+	return &specialist, nil
+}
+
+func (r *PostgresRepository) UpdateSpecialist(specialist *Specialist) error {
+	result := r.db.Save(specialist)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update specialist: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteSpecialist(id uint) error {
+	result := r.db.Delete(&Specialist{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete specialist: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListSpecialists(filter SpecialistFilter) ([]Specialist, int64, error) {
+	query := r.db.Model(&Specialist{})
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Specialization != "" {
+		query = query.Where("specialization ILIKE ?", "%"+filter.Specialization+"%")
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count specialists: %w", err)
+	}
+
+	var specialists []Specialist
+	if err := query.Order("full_name, id").Limit(filter.Limit).Offset(filter.Offset).Find(&specialists).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list specialists: %w", err)
+	}
+	return specialists, total, nil
+}
+
+func (r *PostgresRepository) CountSpecialistClients(id uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&Client{}).Where("specialist_id = ?", id).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count specialist clients: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Должности сотрудников центра
+const (
+	EmployeeRoleReception  = "reception"
+	EmployeeRoleCleaner    = "cleaner"
+	EmployeeRoleSpecialist = "specialist"
+	EmployeeRoleManager    = "manager"
+	EmployeeRoleOther      = "other"
+)
+
+// Статусы сотрудника
+const (
+	EmployeeStatusActive    = "active"
+	EmployeeStatusDismissed = "dismissed"
+)
+
+// Статусы смены. Absent не хранится, а вычисляется в EffectiveStatus.
+const (
+	ShiftStatusPlanned   = "planned"
+	ShiftStatusStarted   = "started"
+	ShiftStatusCompleted = "completed"
+	ShiftStatusCancelled = "cancelled"
+	ShiftStatusAbsent    = "absent"
+)
+
+// MaxShiftDuration - наибольшая длительность смены по плану и по факту
+const MaxShiftDuration = 24 * time.Hour
+
+// ClockInWindow - насколько раньше планового начала можно отметить приход
+const ClockInWindow = 2 * time.Hour
+
+// ErrShiftOverlap возвращается, если смена пересекается с другой сменой сотрудника
+var ErrShiftOverlap = errors.New("shift overlaps with another shift of the employee")
+
+// ErrShiftState возвращается, если действие недопустимо в текущем статусе смены
+var ErrShiftState = errors.New("action is not allowed in the current shift status")
+
+// ErrInvalidClock возвращается, если отметка прихода или ухода не согласуется с временем смены
+var ErrInvalidClock = errors.New("clock time does not match the shift")
+
+// Employee - сотрудник центра, которому планируются смены: администраторы, уборщики, управляющие.
+// Специалист, ведущий прием, тоже заводится сотрудником со ссылкой SpecialistID; его часы приема
+// по-прежнему задаются рабочим графиком, а смены нужны для учета отработанного времени.
+type Employee struct {
+	ID           uint        `gorm:"primarykey"`
+	FullName     string      `gorm:"not null"`
+	Role         string      `gorm:"not null;index"`
+	Phone        string      `gorm:"not null"`
+	SpecialistID *uint       `gorm:"uniqueIndex"`
+	Specialist   *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	BranchID     uint        `gorm:"not null;index"`
+	Branch       *Branch     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Status       string      `gorm:"not null;default:active"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Shift - смена сотрудника: плановое время, перерыв и фактические отметки прихода и ухода.
+// Смена относится к филиалу, где сотрудник работает в этот день, и к месяцу своего планового начала.
+type Shift struct {
+	ID           uint      `gorm:"primarykey"`
+	EmployeeID   uint      `gorm:"not null;index"`
+	Employee     *Employee `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	BranchID     uint      `gorm:"not null;index"`
+	Branch       *Branch   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	PlannedStart time.Time `gorm:"not null;index"`
+	PlannedEnd   time.Time `gorm:"not null"`
+	BreakMinutes int       `gorm:"not null;default:0"`
+	ClockIn      *time.Time
+	ClockOut     *time.Time
+	Status       string `gorm:"not null;default:planned"`
+	Comment      string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// EmployeeFilter - параметры выборки списка сотрудников
+type EmployeeFilter struct {
+	Role     string
+	Status   string
+	BranchID uint
+	Limit    int
+	Offset   int
+}
+
+// ShiftFilter - параметры выборки смен; From и To ограничивают плановое начало
+type ShiftFilter struct {
+	EmployeeID uint
+	BranchID   uint
+	Status     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// TimesheetRow - строка табеля: итоги смен сотрудника за месяц, время в минутах
+type TimesheetRow struct {
+	EmployeeID      uint
+	FullName        string
+	Role            string
+	Shifts          int
+	CompletedShifts int
+	AbsentShifts    int
+	// OpenShifts - смены с приходом, но без ухода: их время не учитывается, пока отметку не исправят
+	OpenShifts      int
+	PlannedMinutes  int
+	WorkedMinutes   int
+	OvertimeMinutes int
+	LateMinutes     int
+}
+
+// EffectiveStatus возвращает статус смены на момент now: плановая смена, закончившаяся без прихода, - неявка
+func (s *Shift) EffectiveStatus(now time.Time) string {
+	if s.Status == ShiftStatusPlanned && !now.Before(s.PlannedEnd) {
+		return ShiftStatusAbsent
+	}
+	return s.Status
+}
+
+// PlannedMinutes возвращает плановое рабочее время смены без перерыва
+func (s *Shift) PlannedMinutes() int {
+	return max(0, int(s.PlannedEnd.Sub(s.PlannedStart)/time.Minute)-s.BreakMinutes)
+}
+
+// WorkedMinutes возвращает фактически отработанное время без перерыва; для незакрытой смены - 0
+func (s *Shift) WorkedMinutes() int {
+	if s.ClockIn == nil || s.ClockOut == nil {
+		return 0
+	}
+	return max(0, int(s.ClockOut.Sub(*s.ClockIn)/time.Minute)-s.BreakMinutes)
+}
+
+// OvertimeMinutes возвращает переработку - отработанное сверх плана время
+func (s *Shift) OvertimeMinutes() int {
+	return max(0, s.WorkedMinutes()-s.PlannedMinutes())
+}
+
+// LateMinutes возвращает опоздание - насколько приход позже планового начала
+func (s *Shift) LateMinutes() int {
+	if s.ClockIn == nil {
+		return 0
+	}
+	return max(0, int(s.ClockIn.Sub(s.PlannedStart)/time.Minute))
+}
+
+// StartShift отмечает приход. Приход допускается не раньше ClockInWindow до начала и до конца смены.
+func (s *Shift) StartShift(at time.Time) error {
+	if s.Status != ShiftStatusPlanned {
+		return ErrShiftState
+	}
+	if at.Before(s.PlannedStart.Add(-ClockInWindow)) || !at.Before(s.PlannedEnd) {
+		return ErrInvalidClock
+	}
+	s.ClockIn = &at
+	s.Status = ShiftStatusStarted
+	return nil
+}
+
+// FinishShift отмечает уход; смена длиннее MaxShiftDuration считается ошибочной отметкой
+func (s *Shift) FinishShift(at time.Time) error {
+	if s.Status != ShiftStatusStarted || s.ClockIn == nil {
+		return ErrShiftState
+	}
+	if !at.After(*s.ClockIn) || at.Sub(*s.ClockIn) > MaxShiftDuration {
+		return ErrInvalidClock
+	}
+	s.ClockOut = &at
+	s.Status = ShiftStatusCompleted
+	return nil
+}
+
+// CorrectClock исправляет отметки прихода и ухода (nil - оставить как есть) и пересчитывает статус.
+// Уход без прихода, раньше прихода или позже MaxShiftDuration после него - ErrInvalidClock.
+func (s *Shift) CorrectClock(clockIn, clockOut *time.Time) error {
+	if clockIn != nil {
+		s.ClockIn = clockIn
+	}
+	if clockOut != nil {
+		s.ClockOut = clockOut
+	}
+	if s.ClockOut != nil {
+		if s.ClockIn == nil || !s.ClockOut.After(*s.ClockIn) || s.ClockOut.Sub(*s.ClockIn) > MaxShiftDuration {
+			return ErrInvalidClock
+		}
+		s.Status = ShiftStatusCompleted
+	} else if s.ClockIn != nil {
+		s.Status = ShiftStatusStarted
+	}
+	return nil
+}
+
+// BuildTimesheet сводит смены в табель по сотрудникам, упорядоченный по имени.
+// Отмененные смены не учитываются; сотрудник смены должен быть загружен.
+func BuildTimesheet(shifts []Shift, now time.Time) []TimesheetRow {
+	rows := make(map[uint]*TimesheetRow)
+	for i := range shifts {
+		shift := &shifts[i]
+		if shift.Status == ShiftStatusCancelled {
+			continue
+		}
+
+		row, ok := rows[shift.EmployeeID]
+		if !ok {
+			row = &TimesheetRow{EmployeeID: shift.EmployeeID}
+			if shift.Employee != nil {
+				row.FullName = shift.Employee.FullName
+				row.Role = shift.Employee.Role
+			}
+			rows[shift.EmployeeID] = row
+		}
+
+		row.Shifts++
+		row.PlannedMinutes += shift.PlannedMinutes()
+		switch shift.EffectiveStatus(now) {
+		case ShiftStatusCompleted:
+			row.CompletedShifts++
+			row.WorkedMinutes += shift.WorkedMinutes()
+			row.OvertimeMinutes += shift.OvertimeMinutes()
+		case ShiftStatusAbsent:
+			row.AbsentShifts++
+		case ShiftStatusStarted:
+			row.OpenShifts++
+		}
+		row.LateMinutes += shift.LateMinutes()
+	}
+
+	timesheet := make([]TimesheetRow, 0, len(rows))
+	for _, row := range rows {
+		timesheet = append(timesheet, *row)
+	}
+	sort.Slice(timesheet, func(i, j int) bool {
+		if timesheet[i].FullName != timesheet[j].FullName {
+			return timesheet[i].FullName < timesheet[j].FullName
+		}
+		return timesheet[i].EmployeeID < timesheet[j].EmployeeID
+	})
+	return timesheet
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StaffRepository interface {
+	CreateEmployee(employee *Employee) error
+	GetEmployee(id uint) (*Employee, error)
+	ListEmployees(filter EmployeeFilter) ([]Employee, int64, error)
+	UpdateEmployee(employee *Employee) error
+
+	// CreateShift сохраняет смену; пересечение с другой неотмененной сменой сотрудника - ErrShiftOverlap
+	CreateShift(shift *Shift) error
+	GetShift(id uint) (*Shift, error)
+	ListShifts(filter ShiftFilter) ([]Shift, int64, error)
+	// ChangeShift применяет change к смене под блокировкой строки и сохраняет результат.
+	// Если change перенес смену, она проверяется на пересечение с другими сменами сотрудника.
+	ChangeShift(id uint, change func(shift *Shift) error) (*Shift, error)
+	// Timesheet строит табель по сменам с плановым началом в [from, to); branchID 0 - по всем филиалам
+	Timesheet(from, to time.Time, branchID uint, now time.Time) ([]TimesheetRow, error)
+}
+
+func (r *PostgresRepository) CreateEmployee(employee *Employee) error {
+	if err := r.db.Create(employee).Error; err != nil {
+		return fmt.Errorf("failed to create employee: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetEmployee(id uint) (*Employee, error) {
+	var employee Employee
+	if err := r.db.First(&employee, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+	return &employee, nil
+}
+
+func (r *PostgresRepository) ListEmployees(filter EmployeeFilter) ([]Employee, int64, error) {
+	query := r.db.Model(&Employee{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count employees: %w", err)
+	}
+
+	var employees []Employee
+	if err := query.Order("full_name, id").Limit(filter.Limit).Offset(filter.Offset).Find(&employees).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list employees: %w", err)
+	}
+	return employees, total, nil
+}
+
+func (r *PostgresRepository) UpdateEmployee(employee *Employee) error {
+	result := r.db.Omit(clause.Associations).Save(employee)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update employee: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreateShift(shift *Shift) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkShiftOverlap(tx, shift); err != nil {
+			return err
+		}
+		return tx.Create(shift).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrShiftOverlap) {
+			return err
+		}
+		return fmt.Errorf("failed to create shift: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetShift(id uint) (*Shift, error) {
+	var shift Shift
+	if err := r.db.First(&shift, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get shift: %w", err)
+	}
+	return &shift, nil
+}
+
+func (r *PostgresRepository) ListShifts(filter ShiftFilter) ([]Shift, int64, error) {
+	query := r.db.Model(&Shift{})
+	if filter.EmployeeID != 0 {
+		query = query.Where("employee_id = ?", filter.EmployeeID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("branch_id = ?", filter.BranchID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("planned_start >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("planned_start < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count shifts: %w", err)
+	}
+
+	var shifts []Shift
+	if err := query.Order("planned_start, id").Limit(filter.Limit).Offset(filter.Offset).Find(&shifts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list shifts: %w", err)
+	}
+	return shifts, total, nil
+}
+
+func (r *PostgresRepository) ChangeShift(id uint, change func(shift *Shift) error) (*Shift, error) {
+	var shift Shift
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shift, id).Error; err != nil {
+			return err
+		}
+		before := shift
+		if err := change(&shift); err != nil {
+			return err
+		}
+		moved := shift.EmployeeID != before.EmployeeID ||
+			!shift.PlannedStart.Equal(before.PlannedStart) || !shift.PlannedEnd.Equal(before.PlannedEnd)
+		if moved && shift.Status != ShiftStatusCancelled {
+			if err := checkShiftOverlap(tx, &shift); err != nil {
+				return err
+			}
+		}
+		return tx.Omit(clause.Associations).Save(&shift).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, ErrShiftState), errors.Is(err, ErrInvalidClock), errors.Is(err, ErrShiftOverlap):
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update shift: %w", translateError(err))
+	}
+	return &shift, nil
+}
+
+func (r *PostgresRepository) Timesheet(from, to time.Time, branchID uint, now time.Time) ([]TimesheetRow, error) {
+	query := r.db.Preload("Employee").
+		Where("planned_start >= ? AND planned_start < ?", from, to).
+		Where("status <> ?", ShiftStatusCancelled)
+	if branchID != 0 {
+		query = query.Where("branch_id = ?", branchID)
+	}
+
+	var shifts []Shift
+	if err := query.Find(&shifts).Error; err != nil {
+		return nil, fmt.Errorf("failed to build timesheet: %w", err)
+	}
+	return BuildTimesheet(shifts, now), nil
+}
+
+// checkShiftOverlap ищет неотмененные смены того же сотрудника, пересекающиеся с плановым временем смены.
+// Advisory-блокировка сериализует параллельное планирование смен одного сотрудника до конца транзакции.
+func checkShiftOverlap(tx *gorm.DB, shift *Shift) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("employee:%d", shift.EmployeeID)).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	err := tx.Model(&Shift{}).
+		Where("employee_id = ? AND id <> ?", shift.EmployeeID, shift.ID).
+		Where("status <> ?", ShiftStatusCancelled).
+		Where("planned_start < ? AND planned_end > ?", shift.PlannedEnd, shift.PlannedStart).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrShiftOverlap
+	}
+	return nil
+}
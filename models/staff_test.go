@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShiftClocking(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	shift := &Shift{PlannedStart: start, PlannedEnd: start.Add(8 * time.Hour), BreakMinutes: 60, Status: ShiftStatusPlanned}
+
+	if err := shift.FinishShift(start.Add(time.Hour)); !errors.Is(err, ErrShiftState) {
+		t.Fatalf("FinishShift before StartShift: err = %v, want ErrShiftState", err)
+	}
+	if err := shift.StartShift(start.Add(-3 * time.Hour)); !errors.Is(err, ErrInvalidClock) {
+		t.Fatalf("StartShift too early: err = %v, want ErrInvalidClock", err)
+	}
+	if err := shift.StartShift(start.Add(10 * time.Minute)); err != nil {
+		t.Fatalf("StartShift: %v", err)
+	}
+	if err := shift.FinishShift(start.Add(9*time.Hour + 40*time.Minute)); err != nil {
+		t.Fatalf("FinishShift: %v", err)
+	}
+
+	if got := shift.PlannedMinutes(); got != 420 {
+		t.Errorf("PlannedMinutes() = %d, want 420", got)
+	}
+	if got := shift.WorkedMinutes(); got != 510 {
+		t.Errorf("WorkedMinutes() = %d, want 510", got)
+	}
+	if got := shift.OvertimeMinutes(); got != 90 {
+		t.Errorf("OvertimeMinutes() = %d, want 90", got)
+	}
+	if got := shift.LateMinutes(); got != 10 {
+		t.Errorf("LateMinutes() = %d, want 10", got)
+	}
+}
+
+func TestBuildTimesheet(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	clock := func(d time.Duration) *time.Time {
+		at := start.Add(d)
+		return &at
+	}
+	anna := &Employee{ID: 2, FullName: "Анна", Role: EmployeeRoleReception}
+	boris := &Employee{ID: 1, FullName: "Борис", Role: EmployeeRoleCleaner}
+	shifts := []Shift{
+		{EmployeeID: 1, Employee: boris, PlannedStart: start, PlannedEnd: start.Add(4 * time.Hour),
+			ClockIn: clock(0), ClockOut: clock(5 * time.Hour), Status: ShiftStatusCompleted},
+		{EmployeeID: 2, Employee: anna, PlannedStart: start, PlannedEnd: start.Add(8 * time.Hour), Status: ShiftStatusPlanned},
+		{EmployeeID: 2, Employee: anna, PlannedStart: start.Add(24 * time.Hour), PlannedEnd: start.Add(32 * time.Hour),
+			ClockIn: clock(24 * time.Hour), Status: ShiftStatusStarted},
+		{EmployeeID: 1, Employee: boris, PlannedStart: start.Add(48 * time.Hour), PlannedEnd: start.Add(52 * time.Hour), Status: ShiftStatusCancelled},
+	}
+
+	rows := BuildTimesheet(shifts, start.Add(26*time.Hour))
+	if len(rows) != 2 || rows[0].EmployeeID != 2 || rows[1].EmployeeID != 1 {
+		t.Fatalf("rows = %+v, want Анна then Борис", rows)
+	}
+	if got := rows[0]; got.Shifts != 2 || got.AbsentShifts != 1 || got.OpenShifts != 1 || got.PlannedMinutes != 960 || got.WorkedMinutes != 0 {
+		t.Errorf("Анна = %+v", got)
+	}
+	if got := rows[1]; got.Shifts != 1 || got.CompletedShifts != 1 || got.WorkedMinutes != 300 || got.OvertimeMinutes != 60 {
+		t.Errorf("Борис = %+v", got)
+	}
+}
+
+func TestShiftCorrectClock(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		clock := start.Add(d)
+		return &clock
+	}
+
+	shift := &Shift{PlannedStart: start, PlannedEnd: start.Add(8 * time.Hour), Status: ShiftStatusPlanned}
+	if err := shift.CorrectClock(nil, at(time.Hour)); !errors.Is(err, ErrInvalidClock) {
+		t.Fatalf("clock out without clock in: err = %v, want ErrInvalidClock", err)
+	}
+
+	shift = &Shift{PlannedStart: start, PlannedEnd: start.Add(8 * time.Hour), ClockIn: at(0), Status: ShiftStatusStarted}
+	if err := shift.CorrectClock(at(5*time.Minute), nil); err != nil || shift.Status != ShiftStatusStarted {
+		t.Fatalf("correct clock in: err = %v, status %s", err, shift.Status)
+	}
+	if err := shift.CorrectClock(nil, at(25*time.Hour)); !errors.Is(err, ErrInvalidClock) {
+		t.Fatalf("clock out after 24 hours: err = %v, want ErrInvalidClock", err)
+	}
+	if err := shift.CorrectClock(nil, at(8*time.Hour)); err != nil || shift.Status != ShiftStatusCompleted {
+		t.Fatalf("clock out: err = %v, status %s", err, shift.Status)
+	}
+}
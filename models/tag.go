@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Tag - метка клиента ("VIP", "после травмы", "корпоративный"). Метки удаляются окончательно
+// вместе с привязками к клиентам, поэтому имя можно сразу использовать повторно.
+type Tag struct {
+	ID        uint   `gorm:"primarykey"`
+	Name      string `gorm:"not null;uniqueIndex"`
+	Color     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type TagRepository interface {
+	CreateTag(tag *Tag) error
+	GetTag(id uint) (*Tag, error)
+	ListTags() ([]Tag, error)
+	UpdateTag(tag *Tag) error
+	DeleteTag(id uint) error
+	// TagClientIDs возвращает клиентов с меткой, чтобы переиндексировать их после изменения метки
+	TagClientIDs(tagID uint) ([]uint, error)
+	// SetClientTags заменяет набор меток клиента; несуществующие метки дают ErrInvalidReference
+	SetClientTags(clientID uint, tagIDs []uint) ([]Tag, error)
+}
+
+func (r *PostgresRepository) CreateTag(tag *Tag) error {
+	if err := r.db.Create(tag).Error; err != nil {
+		return fmt.Errorf("failed to create tag: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetTag(id uint) (*Tag, error) {
+	var tag Tag
+	if err := r.db.First(&tag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	return &tag, nil
+}
+
+func (r *PostgresRepository) ListTags() ([]Tag, error) {
+	var tags []Tag
+	if err := r.db.Order("name").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *PostgresRepository) UpdateTag(tag *Tag) error {
+	result := r.db.Save(tag)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update tag: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteTag(id uint) error {
+	var rowsAffected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM client_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Tag{}, id)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) TagClientIDs(tagID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.Table("client_tags").Where("tag_id = ?", tagID).Order("client_id").Pluck("client_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tag clients: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *PostgresRepository) SetClientTags(clientID uint, tagIDs []uint) ([]Tag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	var tags []Tag
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(tagIDs) > 0 {
+			if err := tx.Where("id IN ?", tagIDs).Order("name").Find(&tags).Error; err != nil {
+				return err
+			}
+			if len(tags) != len(tagIDs) {
+				return ErrInvalidReference
+			}
+		}
+		return tx.Model(&Client{Model: gorm.Model{ID: clientID}}).Association("Tags").Replace(tags)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidReference) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to set client tags: %w", translateError(err))
+	}
+	return tags, nil
+}
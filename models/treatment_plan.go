@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы плана лечения
+const (
+	TreatmentPlanStatusActive    = "active"
+	TreatmentPlanStatusCompleted = "completed"
+	TreatmentPlanStatusCancelled = "cancelled"
+)
+
+// TreatmentPlan - план работы с запросом клиента: цели, число сессий и контрольные точки
+type TreatmentPlan struct {
+	gorm.Model
+	ClientID        uint        `gorm:"not null;index"`
+	Client          *Client     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	SpecialistID    uint        `gorm:"not null;index"`
+	Specialist      *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Title           string      `gorm:"not null"`
+	Description     string
+	PlannedSessions int       `gorm:"not null"`
+	StartsOn        time.Time `gorm:"type:date;not null"`
+	EndsOn          *time.Time
+	Status          string `gorm:"not null;default:active"`
+	Goals           []TreatmentGoal
+	Milestones      []TreatmentMilestone
+}
+
+// TreatmentGoal - цель плана. Если задана метрика, прогресс считается по замерам:
+// от исходного значения BaselineValue к целевому TargetValue.
+type TreatmentGoal struct {
+	ID              uint   `gorm:"primarykey"`
+	TreatmentPlanID uint   `gorm:"not null;index"`
+	Description     string `gorm:"not null"`
+	Metric          string
+	BaselineValue   *float64
+	TargetValue     *float64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// TreatmentMilestone - контрольная точка плана
+type TreatmentMilestone struct {
+	ID              uint      `gorm:"primarykey"`
+	TreatmentPlanID uint      `gorm:"not null;index"`
+	Title           string    `gorm:"not null"`
+	DueOn           time.Time `gorm:"type:date;not null"`
+	ReachedAt       *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ProgressMeasurement - замер показателя клиента (вес, оценка боли и т.п.), сделанный на визите
+type ProgressMeasurement struct {
+	ID              uint           `gorm:"primarykey"`
+	ClientID        uint           `gorm:"not null;index:idx_progress_client_metric"`
+	Client          *Client        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	TreatmentPlanID *uint          `gorm:"index"`
+	TreatmentPlan   *TreatmentPlan `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	AppointmentID   *uint          `gorm:"index"`
+	Appointment     *Appointment   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Metric          string         `gorm:"not null;index:idx_progress_client_metric"`
+	Value           float64        `gorm:"not null"`
+	Unit            string
+	MeasuredAt      time.Time `gorm:"not null"`
+	Comment         string
+	CreatedAt       time.Time
+}
+
+// Progress возвращает процент движения от исходного значения к целевому по последнему замеру.
+// Результат ограничен диапазоном 0..100; ok=false, если у цели нет метрики или границ.
+func (g *TreatmentGoal) Progress(latest float64) (percent float64, ok bool) {
+	if g.Metric == "" || g.BaselineValue == nil || g.TargetValue == nil {
+		return 0, false
+	}
+
+	baseline, target := *g.BaselineValue, *g.TargetValue
+	if baseline == target {
+		if latest == target {
+			return 100, true
+		}
+		return 0, true
+	}
+
+	percent = (latest - baseline) / (target - baseline) * 100
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return percent, true
+}
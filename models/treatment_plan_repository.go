@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TreatmentRepository interface {
+	CreateTreatmentPlan(plan *TreatmentPlan) error
+	GetTreatmentPlan(clientID, planID uint) (*TreatmentPlan, error)
+	ListTreatmentPlans(clientID uint) ([]TreatmentPlan, error)
+	UpdateTreatmentPlan(plan *TreatmentPlan) error
+	SetMilestoneReached(planID, milestoneID uint, reachedAt *time.Time) (*TreatmentMilestone, error)
+	CountCompletedSessions(plan *TreatmentPlan) (int64, error)
+	CreateProgressMeasurement(measurement *ProgressMeasurement) error
+	ListProgressMeasurements(clientID uint, metric string, from, to time.Time) ([]ProgressMeasurement, error)
+}
+
+func (r *PostgresRepository) CreateTreatmentPlan(plan *TreatmentPlan) error {
+	if err := r.db.Create(plan).Error; err != nil {
+		return fmt.Errorf("failed to create treatment plan: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetTreatmentPlan(clientID, planID uint) (*TreatmentPlan, error) {
+	var plan TreatmentPlan
+	err := r.db.Preload("Goals", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Milestones", func(db *gorm.DB) *gorm.DB { return db.Order("due_on, id") }).
+		Where("client_id = ?", clientID).
+		First(&plan, planID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get treatment plan: %w", err)
+	}
+	return &plan, nil
+}
+
+func (r *PostgresRepository) ListTreatmentPlans(clientID uint) ([]TreatmentPlan, error) {
+	var plans []TreatmentPlan
+	err := r.db.Preload("Goals", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Milestones", func(db *gorm.DB) *gorm.DB { return db.Order("due_on, id") }).
+		Where("client_id = ?", clientID).
+		Order("starts_on DESC, id DESC").
+		Find(&plans).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list treatment plans: %w", err)
+	}
+	return plans, nil
+}
+
+// UpdateTreatmentPlan сохраняет поля плана; цели и контрольные точки не затрагиваются
+func (r *PostgresRepository) UpdateTreatmentPlan(plan *TreatmentPlan) error {
+	result := r.db.Omit(clause.Associations).Save(plan)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update treatment plan: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) SetMilestoneReached(planID, milestoneID uint, reachedAt *time.Time) (*TreatmentMilestone, error) {
+	var milestone TreatmentMilestone
+	if err := r.db.Where("treatment_plan_id = ?", planID).First(&milestone, milestoneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get milestone: %w", err)
+	}
+
+	milestone.ReachedAt = reachedAt
+	if err := r.db.Model(&milestone).Update("reached_at", reachedAt).Error; err != nil {
+		return nil, fmt.Errorf("failed to update milestone: %w", err)
+	}
+	return &milestone, nil
+}
+
+// CountCompletedSessions считает завершенные визиты клиента к специалисту плана в период действия плана
+func (r *PostgresRepository) CountCompletedSessions(plan *TreatmentPlan) (int64, error) {
+	query := r.db.Model(&Appointment{}).
+		Where("client_id = ? AND specialist_id = ?", plan.ClientID, plan.SpecialistID).
+		Where("status = ?", AppointmentStatusCompleted).
+		Where("starts_at >= ?", plan.StartsOn)
+	if plan.EndsOn != nil {
+		query = query.Where("starts_at < ?", plan.EndsOn.AddDate(0, 0, 1))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count completed sessions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *PostgresRepository) CreateProgressMeasurement(measurement *ProgressMeasurement) error {
+	if err := r.db.Create(measurement).Error; err != nil {
+		return fmt.Errorf("failed to create progress measurement: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListProgressMeasurements(clientID uint, metric string, from, to time.Time) ([]ProgressMeasurement, error) {
+	query := r.db.Where("client_id = ?", clientID)
+	if metric != "" {
+		query = query.Where("metric = ?", metric)
+	}
+	if !from.IsZero() {
+		query = query.Where("measured_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("measured_at < ?", to)
+	}
+
+	var measurements []ProgressMeasurement
+	if err := query.Order("measured_at, id").Find(&measurements).Error; err != nil {
+		return nil, fmt.Errorf("failed to list progress measurements: %w", err)
+	}
+	return measurements, nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestTreatmentGoalProgress(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		goal   TreatmentGoal
+		latest float64
+		want   float64
+		wantOK bool
+	}{
+		{"weight loss halfway", TreatmentGoal{Metric: "weight", BaselineValue: value(90), TargetValue: value(80)}, 85, 50, true},
+		{"pain score reached", TreatmentGoal{Metric: "pain_score", BaselineValue: value(8), TargetValue: value(2)}, 1, 100, true},
+		{"moved away from target", TreatmentGoal{Metric: "weight", BaselineValue: value(90), TargetValue: value(80)}, 92, 0, true},
+		{"no metric", TreatmentGoal{Description: "feel better"}, 5, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.goal.Progress(tt.latest)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("%s: Progress(%v) = %v, %v; want %v, %v", tt.name, tt.latest, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы записи в листе ожидания
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusFulfilled = "fulfilled"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+// WaitlistEntry - заявка клиента в лист ожидания к специалисту на период дат (границы включительные).
+// Если окна не заданы, клиенту подходит любое время в этот период.
+type WaitlistEntry struct {
+	gorm.Model
+	ClientID       uint        `gorm:"not null;index"`
+	Client         *Client     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SpecialistID   uint        `gorm:"not null;index"`
+	Specialist     *Specialist `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DateFrom       time.Time   `gorm:"type:date;not null"`
+	DateTo         time.Time   `gorm:"type:date;not null"`
+	Status         string      `gorm:"not null;default:waiting;index"`
+	Comment        string
+	LastNotifiedAt *time.Time
+	Windows        []WaitlistWindow `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// WaitlistWindow - предпочтительное время клиента. Weekday в формате ISO, 0 - любой день недели.
+type WaitlistWindow struct {
+	ID              uint   `gorm:"primarykey"`
+	WaitlistEntryID uint   `gorm:"not null;index"`
+	Weekday         int    `gorm:"not null;default:0"`
+	StartTime       string `gorm:"type:varchar(5);not null"`
+	EndTime         string `gorm:"type:varchar(5);not null"`
+}
+
+// WaitlistFilter - параметры выборки листа ожидания
+type WaitlistFilter struct {
+	ClientID     uint
+	SpecialistID uint
+	BranchID     uint
+	Status       string
+	Limit        int
+	Offset       int
+}
+
+// Validate проверяет, что начало окна раньше конца
+func (w *WaitlistWindow) Validate() error {
+	start, err := ParseClock(w.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(w.EndTime)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return fmt.Errorf("window %s-%s: start time must be before end time", w.StartTime, w.EndTime)
+	}
+	return nil
+}
+
+// contains сообщает, помещается ли интервал целиком в окно. Интервалы через полночь окну не подходят.
+func (w *WaitlistWindow) contains(startsAt, endsAt time.Time) bool {
+	if w.Weekday != 0 && w.Weekday != ISOWeekday(startsAt) {
+		return false
+	}
+	if startsAt.Format(dateLayout) != endsAt.Format(dateLayout) {
+		return false
+	}
+	start, err := ParseClock(w.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(w.EndTime)
+	if err != nil {
+		return false
+	}
+	from := startsAt.Hour()*60 + startsAt.Minute()
+	to := endsAt.Hour()*60 + endsAt.Minute()
+	return from >= start && to <= end
+}
+
+// MatchesSlot сообщает, подходит ли освободившийся интервал [startsAt, endsAt) под заявку
+func (e *WaitlistEntry) MatchesSlot(startsAt, endsAt time.Time) bool {
+	if e.Status != WaitlistStatusWaiting {
+		return false
+	}
+	day := startsAt.Format(dateLayout)
+	if day < e.DateFrom.Format(dateLayout) || day > e.DateTo.Format(dateLayout) {
+		return false
+	}
+	if len(e.Windows) == 0 {
+		return true
+	}
+	for i := range e.Windows {
+		if e.Windows[i].contains(startsAt, endsAt) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistRepository interface {
+	CreateWaitlistEntry(entry *WaitlistEntry) error
+	GetWaitlistEntry(id uint) (*WaitlistEntry, error)
+	ListWaitlistEntries(filter WaitlistFilter) ([]WaitlistEntry, int64, error)
+	UpdateWaitlistEntry(entry *WaitlistEntry) error
+	FindWaitlistMatches(appointment *Appointment) ([]WaitlistEntry, error)
+	MarkWaitlistNotified(ids []uint, at time.Time) error
+	FulfillWaitlistEntries(clientID, specialistID uint) error
+}
+
+func (r *PostgresRepository) CreateWaitlistEntry(entry *WaitlistEntry) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create waitlist entry: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetWaitlistEntry(id uint) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	if err := r.db.Preload("Windows").First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func (r *PostgresRepository) ListWaitlistEntries(filter WaitlistFilter) ([]WaitlistEntry, int64, error) {
+	query := r.db.Model(&WaitlistEntry{})
+	if filter.ClientID != 0 {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.SpecialistID != 0 {
+		query = query.Where("specialist_id = ?", filter.SpecialistID)
+	}
+	if filter.BranchID != 0 {
+		query = query.Where("specialist_id IN (SELECT id FROM specialists WHERE branch_id = ?)", filter.BranchID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count waitlist entries: %w", err)
+	}
+
+	var entries []WaitlistEntry
+	err := query.Preload("Windows").
+		Order("created_at, id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list waitlist entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+func (r *PostgresRepository) UpdateWaitlistEntry(entry *WaitlistEntry) error {
+	result := r.db.Omit(clause.Associations).Save(entry)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update waitlist entry: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FindWaitlistMatches возвращает ожидающие заявки к специалисту записи, под которые подходит ее время,
+// в порядке постановки в очередь. Если время специалиста уже занято другой записью или групповым
+// занятием, записаться на него нельзя, и заявки не возвращаются.
+func (r *PostgresRepository) FindWaitlistMatches(appointment *Appointment) ([]WaitlistEntry, error) {
+	busy, err := hasScheduleConflict(r.db, appointment.ID, 0, appointment.SpecialistID, appointment.BranchID, "",
+		appointment.StartsAt, appointment.EndsAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find waitlist matches: %w", err)
+	}
+	if busy {
+		return nil, nil
+	}
+
+	day := appointment.StartsAt.Format(dateLayout)
+
+	var candidates []WaitlistEntry
+	err = r.db.Preload("Windows").
+		Where("specialist_id = ? AND status = ?", appointment.SpecialistID, WaitlistStatusWaiting).
+		Where("date_from <= ? AND date_to >= ?", day, day).
+		Where("client_id <> ?", appointment.ClientID).
+		Order("created_at, id").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find waitlist matches: %w", err)
+	}
+
+	matches := make([]WaitlistEntry, 0, len(candidates))
+	for _, entry := range candidates {
+		if entry.MatchesSlot(appointment.StartsAt, appointment.EndsAt) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+func (r *PostgresRepository) MarkWaitlistNotified(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.db.Model(&WaitlistEntry{}).Where("id IN ?", ids).Update("last_notified_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark waitlist entries notified: %w", err)
+	}
+	return nil
+}
+
+// FulfillWaitlistEntries закрывает ожидающие заявки клиента к специалисту после того, как он записался
+func (r *PostgresRepository) FulfillWaitlistEntries(clientID, specialistID uint) error {
+	err := r.db.Model(&WaitlistEntry{}).
+		Where("client_id = ? AND specialist_id = ? AND status = ?", clientID, specialistID, WaitlistStatusWaiting).
+		Update("status", WaitlistStatusFulfilled).Error
+	if err != nil {
+		return fmt.Errorf("failed to fulfill waitlist entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitlistEntryMatchesSlot(t *testing.T) {
+	// 10.03.2025 - понедельник
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC) }
+	entry := WaitlistEntry{
+		Status:   WaitlistStatusWaiting,
+		DateFrom: at(10, 0, 0),
+		DateTo:   at(14, 0, 0),
+		Windows: []WaitlistWindow{
+			{Weekday: 1, StartTime: "09:00", EndTime: "12:00"},
+			{Weekday: 0, StartTime: "18:00", EndTime: "21:00"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		endsAt   time.Time
+		want     bool
+	}{
+		{"inside monday window", at(10, 10, 0), at(10, 11, 0), true},
+		{"exceeds window end", at(10, 11, 30), at(10, 12, 30), false},
+		{"monday window on tuesday", at(11, 10, 0), at(11, 11, 0), false},
+		{"any weekday evening", at(12, 19, 0), at(12, 20, 0), true},
+		{"outside date range", at(17, 19, 0), at(17, 20, 0), false},
+	}
+
+	for _, tt := range tests {
+		if got := entry.MatchesSlot(tt.startsAt, tt.endsAt); got != tt.want {
+			t.Errorf("%s: MatchesSlot() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	entry.Windows = nil
+	if !entry.MatchesSlot(at(11, 15, 0), at(11, 16, 0)) {
+		t.Error("entry without windows should match any time in the date range")
+	}
+	entry.Status = WaitlistStatusCancelled
+	if entry.MatchesSlot(at(11, 15, 0), at(11, 16, 0)) {
+		t.Error("cancelled entry should not match")
+	}
+}
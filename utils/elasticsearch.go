@@ -13,6 +13,7 @@ import (
 type ElasticsearchClient interface {
 	IndexClient(ctx context.Context, index string, id string, document interface{}) error
 	SearchClients(ctx context.Context, index string, query map[string]interface{}) ([]map[string]interface{}, error)
+	CountClients(ctx context.Context, index string, query map[string]interface{}) (int64, error)
 	DeleteClient(ctx context.Context, index string, id string) error
 	Close() error
 }
@@ -113,6 +114,36 @@ func (e *elasticsearchClient) SearchClients(ctx context.Context, index string, q
 	return results, nil
 }
 
+// CountClients возвращает число документов, подходящих под запрос; query должен содержать только ключ "query"
+func (e *elasticsearchClient) CountClients(ctx context.Context, index string, query map[string]interface{}) (int64, error) {
+	var buf strings.Builder
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return 0, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	res, err := e.client.Count(
+		e.client.Count.WithContext(ctx),
+		e.client.Count.WithIndex(index),
+		e.client.Count.WithBody(strings.NewReader(buf.String())),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("Elasticsearch error: %s", res.String())
+	}
+
+	var r struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return r.Count, nil
+}
+
 func (e *elasticsearchClient) DeleteClient(ctx context.Context, index string, id string) error {
 	req := esapi.DeleteRequest{
 		Index:      index,